@@ -20,44 +20,65 @@ package main
 
 import (
 	"bufio"
+	"compress/gzip"
 	"container/heap"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"os"
-	"sort"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
 	"strconv"
-	"strings"
 	"sync"
+	"syscall"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+
+	"my-sort-app/linesort"
 )
 
 // Config holds the command-line flags
 type Config struct {
-	K int  // сортировать по столбцу (колонке)
-	N bool // сортировать по числовому значению
-	R bool // сортировать в обратном порядке
-	U bool // выводить только уникальные строки
-	M bool // сортировать по названию месяца
-	B bool // игнорировать хвостовые пробелы
-	C bool // проверить, отсортированы ли данные
-	H bool // сортировать по человекочитаемым размерам
+	linesort.Options        // флаги сравнения строк
+	C                bool   // проверить, отсортированы ли данные
+	O                string // выходной файл (пусто - стандартный вывод)
+
+	BufferSize int64  // память под сортируемые чанки в байтах (-S; 0 - defaultBufferSize)
+	Parallel   int    // количество горутин, сортирующих чанки (0 - GOMAXPROCS)
+	TempDir    string // директория для временных файлов (пусто - os.TempDir)
+	BatchSize  int    // сколько файлов сливается за раз (--batch-size; меньше 2 - defaultBatchSize)
+	Compress   bool   // сжимать временные файлы gzip'ом
 }
 
+const (
+	defaultBufferSize = 256 << 20 // память под сортируемые чанки по умолчанию
+	defaultBatchSize  = 16        // сколько файлов сливается за раз по умолчанию
+)
+
 func main() {
 	cfg := parseFlags()
 
 	if cfg.C {
-		var reader io.Reader = os.Stdin
+		if flag.NArg() > 1 {
+			fmt.Fprintf(os.Stderr, "error: extra operand %q not allowed with -c\n", flag.Arg(1))
+			os.Exit(1)
+		}
 		filename := flag.Arg(0)
-		if filename != "" {
-			file, err := os.Open(filename)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "error opening file: %v\n", err)
-				os.Exit(1)
-			}
-			defer file.Close()
-			reader = file
+		if filename == "" {
+			filename = "-"
+		}
+		reader, err := openInput(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
 		}
+		defer reader.Close()
 
 		isSorted, err := IsSorted(reader, cfg)
 		if err != nil {
@@ -71,18 +92,38 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Сборщик мусора освобождает память чанков, не дожидаясь удвоения кучи,
+	// чтобы память процесса оставалась около -S
+	debug.SetMemoryLimit(cfg.BufferSize + cfg.BufferSize/4)
+
+	// По Ctrl+C сортировка прерывается, а временные файлы удаляются
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Если читатель вывода завершился (sort | head), запись в stdout вернет ошибку EPIPE,
+	// а не убьет процесс сигналом, и временные файлы тоже будут удалены
+	signal.Ignore(syscall.SIGPIPE)
+
 	// большой файл не получится прочитать целиком, поэтому будем использовать внешнюю сортировку
-	err := externalSort(flag.Arg(0), "sorted_output.txt", cfg)
+	err := externalSort(ctx, flag.Args(), cfg.O, cfg)
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "error: interrupted")
+		os.Exit(130)
+	}
+	if errors.Is(err, syscall.EPIPE) {
+		os.Exit(1) // вывод больше никто не читает, сообщать об ошибке некуда
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("Sorted output written to sorted_output.txt")
 }
 
 func parseFlags() Config {
 	var cfg Config
-	flag.IntVar(&cfg.K, "k", 1, "sort by column (1-indexed)")
+	flag.Var((*linesort.KeyList)(&cfg.Keys), "k", "sort via a key POS1[,POS2][opts], POS is F[.C][opts], opts are nrMhbfd (repeatable)")
+	flag.StringVar(&cfg.T, "t", "", "use SEP as the field separator instead of blanks")
+	flag.StringVar(&cfg.O, "o", "", "write result to FILE instead of standard output")
+	flag.BoolVar(&cfg.S, "s", false, "stabilize sort by disabling last-resort comparison")
 	flag.BoolVar(&cfg.N, "n", false, "sort numerically")
 	flag.BoolVar(&cfg.R, "r", false, "reverse the result of comparisons")
 	flag.BoolVar(&cfg.U, "u", false, "output only the first of an equal run")
@@ -90,98 +131,155 @@ func parseFlags() Config {
 	flag.BoolVar(&cfg.B, "b", false, "ignore leading blanks")
 	flag.BoolVar(&cfg.C, "c", false, "check for sorted input; do not sort")
 	flag.BoolVar(&cfg.H, "h", false, "compare human readable numbers (e.g., 2K 1G)")
+	flag.BoolVar(&cfg.F, "f", false, "fold lower case to upper case characters")
+	flag.BoolVar(&cfg.D, "d", false, "consider only blanks and alphanumeric characters")
+	flag.StringVar(&cfg.Locale, "locale", "", "compare strings using collation rules of the locale (e.g., ru, de)")
+	flag.StringVar(&cfg.Locale, "L", "", "shorthand for --locale")
+	cfg.BufferSize = defaultBufferSize
+	flag.Var((*byteSize)(&cfg.BufferSize), "S", "use SIZE bytes for the main memory buffer, SIZE is N[bKMGT] (K by default)")
+	flag.Var((*byteSize)(&cfg.BufferSize), "buffer-size", "same as -S")
+	flag.IntVar(&cfg.Parallel, "parallel", runtime.GOMAXPROCS(0), "change the number of sorts run concurrently to N")
+	flag.StringVar(&cfg.TempDir, "T", "", "use DIR for temporaries, not $TMPDIR or /tmp")
+	flag.StringVar(&cfg.TempDir, "temporary-directory", "", "same as -T")
+	flag.IntVar(&cfg.BatchSize, "batch-size", defaultBatchSize, "merge at most N inputs at once; for more use temp files")
+	flag.BoolVar(&cfg.Compress, "compress", false, "compress temporaries with gzip")
 	flag.Parse()
 
-	if cfg.K < 1 {
-		fmt.Fprintln(os.Stderr, "error: column index must be greater than 0")
+	if utf8.RuneCountInString(cfg.T) > 1 {
+		fmt.Fprintln(os.Stderr, "error: field separator must be a single character")
+		os.Exit(1)
+	}
+	if !linesort.IsByteLocale(cfg.Locale) {
+		if _, err := language.Parse(cfg.Locale); err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid locale %q: %v\n", cfg.Locale, err)
+			os.Exit(1)
+		}
+	}
+	if cfg.Parallel < 1 {
+		fmt.Fprintln(os.Stderr, "error: number of parallel sorts must be at least 1")
+		os.Exit(1)
+	}
+	if cfg.BatchSize < 2 {
+		fmt.Fprintln(os.Stderr, "error: batch size must be at least 2")
 		os.Exit(1)
 	}
 	return cfg
 }
 
-// externalSort выполняет внешнюю сортировку для больших файлов
-func externalSort(inputFile, outputFile string, cfg Config) error {
+// externalSort выполняет внешнюю сортировку для больших файлов. Входные файлы inputFiles
+// ("-" - стандартный ввод, без файлов - тоже) сортируются вместе, как один ввод,
+// результат пишется в outputFile или, если он пустой, в стандартный вывод.
+// Временные файлы удаляются и при ошибке, и при отмене ctx (например, по Ctrl+C) -
+// тогда возвращается ctx.Err()
+func externalSort(ctx context.Context, inputFiles []string, outputFile string, cfg Config) error {
+	runs := newTempRuns(cfg)
+	// Гарантируем удаление временных файлов
+	defer runs.removeAll()
+
 	// Разделение на отсортированные чанки
-	tempFiles, err := createSortedChunks(inputFile, cfg)
+	tempFiles, err := createSortedChunks(ctx, inputFiles, runs, cfg)
+	if err != nil {
+		return err
+	}
+
+	// Чанков может быть больше, чем можно открыть за раз, поэтому сначала сливаем их группами
+	tempFiles, err = mergePasses(ctx, tempFiles, runs, cfg)
 	if err != nil {
 		return err
 	}
-	// Гарантируем удаление временных файлов
-	defer func() {
-		for _, f := range tempFiles { // tempFiles может быть nil, если произошла ошибка до создания файлов
-			if f != "" { // Проверяем, что имя файла не пустое
-				os.Remove(f)
-			}
-		}
-	}()
 
 	// Слияние временных файлов в один выходной файл
-	return mergeChunks(tempFiles, outputFile, cfg)
+	return mergeChunks(ctx, tempFiles, outputFile, runs, cfg)
 }
 
-// createSortedChunks читает файл по частям, сортирует и пишет во временные файлы
-func createSortedChunks(filename string, cfg Config) ([]string, error) {
-	// читаем из стандартного ввода или из файла
-	var reader io.Reader
-	if filename == "" {
-		reader = os.Stdin
-	} else {
-		file, err := os.Open(filename)
+// createSortedChunks читает файлы по частям, сортирует и пишет во временные файлы
+func createSortedChunks(ctx context.Context, filenames []string, runs *tempRuns, cfg Config) ([]string, error) {
+	// читаем из стандартного ввода или из файлов; открываем их сразу, чтобы не сортировать
+	// зря, если какой-то файл не открывается
+	if len(filenames) == 0 {
+		filenames = []string{"-"}
+	}
+	readers := make([]io.ReadCloser, 0, len(filenames))
+	defer func() {
+		for _, r := range readers {
+			r.Close()
+		}
+	}()
+	for _, filename := range filenames {
+		reader, err := openInput(filename)
 		if err != nil {
-			return nil, fmt.Errorf("failed to open input file: %w", err)
+			return nil, err
 		}
-		defer file.Close()
-		reader = file
+		readers = append(readers, reader)
 	}
 
-	// Каналы для коммуникации между горутинами
-	chunkChan := make(chan []string, 10) // канал для сырых чанков
-	resultChan := make(chan string, 10)  // канал для имен временных файлов
-	errChan := make(chan error, 1)       // канал для ошибок от горутин
+	// Первая ошибка горутины отменяет ctx, чтобы остальные горутины остановились
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
 
-	scanner := bufio.NewScanner(reader)
-	chunkSize := 100000 // устанавливаем размер чанка - 100_000 строк на чанк
-	lines := make([]string, 0, chunkSize)
+	numWorkers := cfg.Parallel // количество горутин-работников
+	if numWorkers <= 0 {
+		numWorkers = runtime.GOMAXPROCS(0)
+	}
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	// В памяти одновременно находятся чанки всех работников и чанк, который читается,
+	// поэтому память делится между ними поровну
+	chunkSize := bufferSize / int64(numWorkers+1)
+
+	// Каналы для коммуникации между горутинами. Канал чанков без буфера: читатель
+	// не набирает новые чанки, пока работники заняты
+	chunkChan := make(chan chunk)          // канал для сырых чанков
+	resultChan := make(chan chunkFile, 10) // канал для имен временных файлов
+
+	sizer := linesort.New(cfg.Options) // оценивает память, которую займет строка при сортировке
 
 	// Горутина-читатель
 	go func() {
 		defer close(chunkChan) // Закрываем канал чанков, когда чтение завершено
-		for {
-			readCount := 0
-			for scanner.Scan() {
-				lines = append(lines, scanner.Text())
-				readCount++
-				if readCount >= chunkSize {
-					break
-				}
+		send := func(c chunk) bool {
+			select {
+			case chunkChan <- c:
+				return true
+			case <-ctx.Done():
+				return false
 			}
+		}
 
-			if len(lines) == 0 {
-				break // Достигли конца файла
+		var lines []string
+		var size int64
+		chunkIdx := 0
+		// Файлы читаются подряд, и строки одного чанка могут быть из разных файлов.
+		// Каждый файл читается своим сканером, поэтому строка без перевода строки
+		// в конце файла не склеивается с первой строкой следующего
+		for _, reader := range readers {
+			scanner := bufio.NewScanner(reader)
+			for scanner.Scan() {
+				line := scanner.Text()
+				lines = append(lines, line)
+				size += sizer.LineSize(line)
+				if size >= chunkSize {
+					// Каждый чанк получает новый слайс, поэтому копировать строки не нужно
+					if !send(chunk{idx: chunkIdx, lines: lines}) {
+						return
+					}
+					chunkIdx++
+					lines, size = nil, 0
+				}
 			}
-
-			// Отправляем чанк в канал. Делаем копию, чтобы избежать гонки.
-			// Если lines[:0] будет вызван до того, как worker обработает чанк, worker увидит пустой чанк
-			chunkCopy := make([]string, len(lines))
-			copy(chunkCopy, lines)
-			chunkChan <- chunkCopy
-
-			lines = lines[:0] // Очищаем срез для следующего чанка
-
-			if readCount < chunkSize {
-				break // Достигли конца файла, выходим
+			if err := scanner.Err(); err != nil {
+				cancel(fmt.Errorf("scanner error: %w", err))
+				return
 			}
 		}
-		if err := scanner.Err(); err != nil {
-			select {
-			case errChan <- fmt.Errorf("scanner error: %w", err):
-			default:
-			}
+		if len(lines) > 0 {
+			send(chunk{idx: chunkIdx, lines: lines})
 		}
 	}()
 
 	var wg sync.WaitGroup
-	numWorkers := 4 // количество горутин-работников
 
 	// Запускаем горутины-работники
 	for i := 0; i < numWorkers; i++ {
@@ -189,43 +287,16 @@ func createSortedChunks(filename string, cfg Config) ([]string, error) {
 		go func() {
 			defer wg.Done()
 			for chunk := range chunkChan {
-				// Сортируем текущий чанк
-				sorter := NewLineSorter(chunk, cfg)
-				sorter.Sort()
-
-				// Создаем временный файл
-				tmpFile, err := os.CreateTemp("", "sort-chunk-*.txt")
+				name, err := writeChunk(chunk.lines, runs, cfg)
 				if err != nil {
-					select {
-					case errChan <- fmt.Errorf("failed to create temp file: %w", err):
-					default:
-					}
+					cancel(err)
 					return
 				}
-
-				// Пишем отсортированные строки во временный файл
-				writer := bufio.NewWriter(tmpFile)
-				for _, line := range chunk {
-					fmt.Fprintln(writer, line)
-				}
-				if err := writer.Flush(); err != nil {
-					select {
-					case errChan <- fmt.Errorf("failed to flush writer to temp file %s: %w", tmpFile.Name(), err):
-					default:
-					}
-					tmpFile.Close()
-					os.Remove(tmpFile.Name()) // Удаляем поврежденный файл
-					return
-				}
-				if err := tmpFile.Close(); err != nil {
-					select {
-					case errChan <- fmt.Errorf("failed to close temp file %s: %w", tmpFile.Name(), err):
-					default:
-					}
-					os.Remove(tmpFile.Name()) // Удаляем поврежденный файл
+				select {
+				case resultChan <- chunkFile{idx: chunk.idx, name: name}:
+				case <-ctx.Done():
 					return
 				}
-				resultChan <- tmpFile.Name()
 			}
 		}()
 	}
@@ -237,34 +308,219 @@ func createSortedChunks(filename string, cfg Config) ([]string, error) {
 	}()
 
 	var collectedTempFiles []string
-	var firstErr error
 
-	// Собираем имена временных файлов и проверяем ошибки
+	// Собираем имена временных файлов в порядке чанков во входном файле и проверяем ошибки
 	for {
 		select {
-		case tmpFileName, ok := <-resultChan:
+		case result, ok := <-resultChan:
 			if !ok { // Канал закрыт, все работники завершили работу
-				return collectedTempFiles, firstErr
+				if ctx.Err() != nil {
+					return nil, context.Cause(ctx)
+				}
+				return collectedTempFiles, nil
 			}
-			collectedTempFiles = append(collectedTempFiles, tmpFileName)
-		case err := <-errChan:
-			if firstErr == nil { // Сохраняем только первую ошибку
-				firstErr = err
+			for len(collectedTempFiles) <= result.idx {
+				collectedTempFiles = append(collectedTempFiles, "")
 			}
+			collectedTempFiles[result.idx] = result.name
+		case <-ctx.Done():
+			// Не ждем горутины: созданные ими файлы удалит externalSort, а новые создать не получится
+			return nil, context.Cause(ctx)
 		}
 	}
 }
 
+// openInput открывает входной файл; "-" - стандартный ввод
+func openInput(filename string) (io.ReadCloser, error) {
+	if filename == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file: %w", err)
+	}
+	return file, nil
+}
+
+// writeChunk сортирует чанк и пишет его во временный файл
+func writeChunk(lines []string, runs *tempRuns, cfg Config) (string, error) {
+	linesort.New(cfg.Options).Sort(lines)
+
+	run, err := runs.create()
+	if err != nil {
+		return "", err
+	}
+	// Ошибки записи в bufio.Writer сохраняются и возвращаются из Close
+	for _, line := range lines {
+		run.WriteString(line)
+		run.WriteByte('\n')
+	}
+	if err := run.Close(); err != nil {
+		runs.remove(run.Name()) // Удаляем поврежденный файл
+		return "", fmt.Errorf("failed to write temp file %s: %w", run.Name(), err)
+	}
+	return run.Name(), nil
+}
+
+// chunk - часть входного файла для сортировки; idx - номер части по порядку
+type chunk struct {
+	idx   int
+	lines []string
+}
+
+// chunkFile - временный файл с отсортированным чанком idx
+type chunkFile struct {
+	idx  int
+	name string
+}
+
+// tempRuns - временные файлы сортировки: создает их в -T, при --compress сжимает
+// и удаляет все оставшиеся файлы разом (removeAll)
+type tempRuns struct {
+	mu       sync.Mutex
+	dir      string
+	compress bool
+	names    map[string]bool // nil - файлы удалены, новые не создаются
+}
+
+// newTempRuns создает пустой набор временных файлов
+func newTempRuns(cfg Config) *tempRuns {
+	return &tempRuns{dir: cfg.TempDir, compress: cfg.Compress, names: make(map[string]bool)}
+}
+
+// create создает временный файл для записи отсортированных строк
+func (r *tempRuns) create() (*runFile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names == nil {
+		return nil, errors.New("temp files are already removed")
+	}
+
+	pattern := "sort-chunk-*.txt"
+	if r.compress {
+		pattern += ".gz"
+	}
+	file, err := os.CreateTemp(r.dir, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	r.names[file.Name()] = true
+
+	run := &runFile{file: file}
+	var w io.Writer = file
+	if r.compress {
+		// Временные файлы читаются один раз, поэтому сжимаем быстро, а не сильно
+		run.gz, _ = gzip.NewWriterLevel(file, gzip.BestSpeed)
+		w = run.gz
+	}
+	run.Writer = bufio.NewWriter(w)
+	return run, nil
+}
+
+// createOutput создает временный файл в директории выходного файла outputFile, чтобы
+// потом заменить им выходной файл. Права доступа берутся у существующего выходного файла
+func (r *tempRuns) createOutput(outputFile string) (*os.File, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names == nil {
+		return nil, errors.New("temp files are already removed")
+	}
+
+	dir, base := filepath.Split(outputFile)
+	file, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	r.names[file.Name()] = true
+
+	// CreateTemp создает файл с правами 0600; новый выходной файл получает обычные 0644
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(outputFile); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+	return file, nil
+}
+
+// open открывает временный файл для чтения строк
+func (r *tempRuns) open(name string) (io.ReadCloser, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if !r.compress {
+		return file, nil
+	}
+	gz, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read temp file %s: %w", name, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{gz, file}, nil
+}
+
+// remove удаляет временный файл, который больше не нужен
+func (r *tempRuns) remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	os.Remove(name)
+	delete(r.names, name)
+}
+
+// keep перестает считать файл временным: его не нужно удалять
+func (r *tempRuns) keep(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.names, name)
+}
+
+// removeAll удаляет все временные файлы; после этого новые файлы не создаются
+func (r *tempRuns) removeAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name := range r.names {
+		os.Remove(name)
+	}
+	r.names = nil
+}
+
+// runFile - временный файл, открытый для записи через буфер и, при --compress, gzip
+type runFile struct {
+	*bufio.Writer
+	gz   *gzip.Writer
+	file *os.File
+}
+
+// Name возвращает имя временного файла
+func (f *runFile) Name() string {
+	return f.file.Name()
+}
+
+// Close дописывает буферизованные и сжатые данные и закрывает файл
+func (f *runFile) Close() error {
+	err := f.Flush()
+	if f.gz != nil {
+		err = errors.Join(err, f.gz.Close())
+	}
+	return errors.Join(err, f.file.Close())
+}
+
 // heapItem представляет элемент в куче для слияния
 type heapItem struct {
-	line    string // Строка из файла
-	fileIdx int    // Индекс файла, из которого прочитана строка
+	linesort.Line     // Строка из файла и значения ее ключей
+	fileIdx       int // Индекс файла, из которого прочитана строка
 }
 
 // minHeap реализует heap.Interface для heapItem
 type minHeap struct {
 	items  []*heapItem
-	sorter *LineSorter
+	sorter *linesort.Sorter
 }
 
 // Len возвращает количество элементов в куче
@@ -272,9 +528,13 @@ func (h *minHeap) Len() int {
 	return len(h.items)
 }
 
-// Less сравнивает элементы в куче
+// Less сравнивает элементы в куче. Равные строки берутся из файлов по порядку чанков,
+// чтобы при -s сохранялся порядок строк во входном файле
 func (h *minHeap) Less(i, j int) bool {
-	return h.sorter.compareLines(h.items[i].line, h.items[j].line)
+	if c := h.sorter.Compare(&h.items[i].Line, &h.items[j].Line); c != 0 {
+		return c < 0
+	}
+	return h.items[i].fileIdx < h.items[j].fileIdx
 }
 
 // Swap меняет местами элементы в куче
@@ -297,93 +557,179 @@ func (h *minHeap) Pop() any {
 	return item
 }
 
-// mergeChunks сливает отсортированные временные файлы в один
-func mergeChunks(files []string, outputFile string, cfg Config) error {
-	scanners := make([]*bufio.Scanner, len(files)) // Слайс сканеров для каждого файла
-	fileHandles := make([]*os.File, len(files))    // Слайс для открытия каждого файла
+// mergePasses сливает временные файлы группами по --batch-size в промежуточные файлы,
+// пока их не останется не больше --batch-size. Группы идут подряд в порядке чанков,
+// поэтому порядок равных строк из разных чанков сохраняется
+func mergePasses(ctx context.Context, files []string, runs *tempRuns, cfg Config) ([]string, error) {
+	batchSize := cfg.BatchSize
+	if batchSize < 2 {
+		batchSize = defaultBatchSize
+	}
 
-	for i, filename := range files {
-		file, err := os.Open(filename)
-		if err != nil {
+	for len(files) > batchSize {
+		var merged []string
+		for start := 0; start < len(files); start += batchSize {
+			batch := files[start:min(start+batchSize, len(files))]
+			if len(batch) == 1 {
+				merged = append(merged, batch[0])
+				continue
+			}
+
+			run, err := runs.create()
+			if err != nil {
+				return nil, err
+			}
+			err = mergeFiles(ctx, batch, run.Writer, runs, cfg)
+			if closeErr := run.Close(); err == nil && closeErr != nil {
+				err = fmt.Errorf("failed to write temp file %s: %w", run.Name(), closeErr)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			// Слитые файлы больше не нужны: на диске остается примерно один размер ввода
+			for _, name := range batch {
+				runs.remove(name)
+			}
+			merged = append(merged, run.Name())
+		}
+		files = merged
+	}
+	return files, nil
+}
+
+// mergeChunks сливает отсортированные временные файлы в выходной файл или, если он
+// не задан, в стандартный вывод. Выходной файл сначала пишется во временный файл рядом
+// с ним и заменяет его только после успешного слияния, поэтому выходной файл может
+// совпадать с входным, а при ошибке прежнее содержимое не теряется
+func mergeChunks(ctx context.Context, files []string, outputFile string, runs *tempRuns, cfg Config) error {
+	if outputFile == "" {
+		writer := bufio.NewWriter(os.Stdout)
+		if err := mergeFiles(ctx, files, writer, runs, cfg); err != nil {
 			return err
 		}
-		fileHandles[i] = file
-		scanners[i] = bufio.NewScanner(file)
+		return writer.Flush()
+	}
+
+	// Открываем временный выходной файл для записи; при ошибке его удалит externalSort
+	outFile, err := runs.createOutput(outputFile)
+	if err != nil {
+		return err
 	}
+	defer outFile.Close()
+	writer := bufio.NewWriter(outFile)
+
+	if err := mergeFiles(ctx, files, writer, runs, cfg); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if err := outFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(outFile.Name(), outputFile); err != nil {
+		return fmt.Errorf("failed to replace output file: %w", err)
+	}
+	runs.keep(outFile.Name())
+	return nil
+}
+
+// mergeFiles сливает отсортированные временные файлы и пишет строки в writer
+func mergeFiles(ctx context.Context, files []string, writer *bufio.Writer, runs *tempRuns, cfg Config) error {
+	scanners := make([]*bufio.Scanner, len(files)) // Слайс сканеров для каждого файла
+	readers := make([]io.Closer, 0, len(files))    // Слайс открытых файлов
 
 	defer func() {
-		for _, f := range fileHandles {
-			f.Close()
+		for _, r := range readers {
+			r.Close()
 		}
 	}()
 
+	for i, filename := range files {
+		reader, err := runs.open(filename)
+		if err != nil {
+			return err
+		}
+		readers = append(readers, reader)
+		scanners[i] = bufio.NewScanner(reader)
+	}
+
 	// сейчас у нас открыто i временных файлов и i сканеров для них
 
 	// Инициализация кучи
 	h := &minHeap{
 		items:  make([]*heapItem, 0, len(files)), // слайс для хранения первых строк из каждого файла
-		sorter: NewLineSorter(nil, cfg),          // сортировщик
+		sorter: linesort.New(cfg.Options),        // сортировщик
 	}
 	for i, scanner := range scanners {
 		if scanner.Scan() {
-			heap.Push(h, &heapItem{line: scanner.Text(), fileIdx: i})
+			item := &heapItem{fileIdx: i}
+			h.sorter.ParseLine(scanner.Text(), &item.Line)
+			heap.Push(h, item)
+		} else if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read temp file %s: %w", files[i], err)
 		}
 	}
 
-	// Открываем выходной файл для записи
-	outFile, err := os.Create(outputFile)
-	if err != nil {
-		return err
-	}
-	defer outFile.Close()
-	writer := bufio.NewWriter(outFile)
-	defer writer.Flush()
-
-	var lastWritten string
+	var lastWritten linesort.Line
+	written := false
 	for h.Len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		item := heap.Pop(h).(*heapItem)
 
-		if !cfg.U || item.line != lastWritten { // учитываем флаг U для записи только уникальных строк
-			fmt.Fprintln(writer, item.line)
-			lastWritten = item.line
+		// учитываем флаг U: из строк с равными ключами записываем только первую
+		if !cfg.U || !written || h.sorter.CompareKeys(&item.Line, &lastWritten) != 0 {
+			// при ошибке записи (например, stdout закрыт) дальше сливать бессмысленно
+			writer.WriteString(item.Text)
+			if err := writer.WriteByte('\n'); err != nil {
+				return err
+			}
+			if cfg.U {
+				// значения ключей item перезапишет следующая строка, поэтому копируем их
+				lastWritten.Copy(&item.Line)
+			}
+			written = true
 		}
 
-		// Читаем следующую строку из того же файла и добавляем в кучу
-		if scanners[item.fileIdx].Scan() {
-			heap.Push(h, &heapItem{line: scanners[item.fileIdx].Text(), fileIdx: item.fileIdx})
+		// Читаем следующую строку из того же файла и добавляем в кучу,
+		// значения ключей пишем на место значений извлеченной строки
+		scanner := scanners[item.fileIdx]
+		if scanner.Scan() {
+			h.sorter.ParseLine(scanner.Text(), &item.Line)
+			heap.Push(h, item)
+		} else if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read temp file %s: %w", files[item.fileIdx], err)
 		}
 	}
 
-	return writer.Flush()
-}
-
-// LineSorter содержит логику сортировки
-type LineSorter struct {
-	lines []string
-	cfg   Config
+	return nil
 }
 
 // IsSorted проверяет, отсортирован ли ввод (из файла или STDIN) в соответствии с конфигурацией
 // читает ввод построчно, не загружая весь файл в память
 func IsSorted(reader io.Reader, cfg Config) (bool, error) {
 	scanner := bufio.NewScanner(reader)
-	var previousLine string
+	var previous, current linesort.Line
 	firstLine := true
 
-	sorter := NewLineSorter(nil, cfg)
+	sorter := linesort.New(cfg.Options)
 
 	for scanner.Scan() {
-		currentLine := scanner.Text()
+		// значения ключей текущей строки пишем на место значений строки перед предыдущей
+		sorter.ParseLine(scanner.Text(), &current)
 		if firstLine {
-			previousLine = currentLine
+			previous, current = current, previous
 			firstLine = false
 			continue
 		}
 
-		if sorter.compareLines(currentLine, previousLine) {
+		if sorter.Compare(&current, &previous) < 0 {
 			return false, nil
 		}
-		previousLine = currentLine
+		previous, current = current, previous
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -393,71 +739,31 @@ func IsSorted(reader io.Reader, cfg Config) (bool, error) {
 	return true, nil
 }
 
-// compareLines сравнивает строки в соответствии с конфигурацией
-func (s *LineSorter) compareLines(lineA, lineB string) bool {
-	valA := s.getCompareValue(lineA)
-	valB := s.getCompareValue(lineB)
-
-	var isLess bool
-	switch {
-	case s.cfg.N:
-		numA, errA := strconv.ParseFloat(valA, 64)
-		numB, errB := strconv.ParseFloat(valB, 64)
-		if errA != nil && errB == nil { // A - не число, B - число
-			isLess = true // Нечисловые значения считаем меньше числовых
-		} else if errA == nil && errB != nil { // A - число, B - не число
-			isLess = false
-		} else if errA != nil { // Оба не числа, сравниваем как строки
-			isLess = valA < valB
-		} else { // Оба числа
-			isLess = numA < numB
-		}
-	case s.cfg.M:
-		monthA := parseMonth(valA)
-		monthB := parseMonth(valB)
-		isLess = monthA < monthB
-	case s.cfg.H:
-		numA := parseHumanReadable(valA)
-		numB := parseHumanReadable(valB)
-		isLess = numA < numB
-	default:
-		isLess = valA < valB
-	}
+// byteSize - значение флага -S: размер в байтах с необязательным суффиксом b, K, M, G или T
+type byteSize int64
 
-	if s.cfg.R {
-		return !isLess
-	}
-	return isLess
+// String возвращает размер в байтах
+func (b *byteSize) String() string {
+	return strconv.FormatInt(int64(*b), 10)
 }
 
-// getCompareValue возвращает значение для сравнения
-func (s *LineSorter) getCompareValue(line string) string {
-	if s.cfg.B {
-		line = strings.TrimSpace(line)
+// Set разбирает размер, например "512M" или "100000"; без суффикса размер указан в килобайтах, как в GNU sort
+func (b *byteSize) Set(value string) error {
+	num, suffix := value, byte('K')
+	if i := len(value) - 1; i > 0 && (value[i] < '0' || value[i] > '9') {
+		num, suffix = value[:i], value[i]
 	}
-
-	fields := strings.Fields(line)
-	if s.cfg.K > 0 && s.cfg.K <= len(fields) {
-		return fields[s.cfg.K-1]
+	multipliers := map[byte]int64{'b': 1, 'K': 1 << 10, 'M': 1 << 20, 'G': 1 << 30, 'T': 1 << 40}
+	multiplier, ok := multipliers[suffix]
+	if !ok {
+		return fmt.Errorf("invalid size suffix in %q", value)
 	}
-	// если колонка -k не определена или вне диапазона, сортируем по всей строке
-	return line
-}
-
-// NewLineSorter создает новый экземпляр LineSorter
-func NewLineSorter(lines []string, cfg Config) *LineSorter {
-	return &LineSorter{lines: lines, cfg: cfg}
-}
-
-// Sort выполняет сортировку строк
-func (s *LineSorter) Sort() {
-	sort.SliceStable(s.lines, s.less)
-}
-
-// less определяет порядок сортировки
-func (s *LineSorter) less(i, j int) bool {
-	lineA, lineB := s.lines[i], s.lines[j]
-	return s.compareLines(lineA, lineB)
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/multiplier {
+		return fmt.Errorf("invalid size %q", value)
+	}
+	*b = byteSize(n * multiplier)
+	return nil
 }
 
 func printLines(lines []string) {
@@ -478,50 +784,3 @@ func unique(lines []string) []string {
 	}
 	return result
 }
-
-var monthMap = map[string]int{
-	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
-	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
-}
-
-func parseMonth(s string) int {
-	s = strings.ToLower(s)
-	if len(s) > 3 {
-		s = s[:3]
-	}
-	if val, ok := monthMap[s]; ok {
-		return val
-	}
-
-	// неизвестный месяц возвращаем как 0
-	return 0
-}
-
-func parseHumanReadable(s string) int64 {
-	s = strings.ToUpper(s)
-	var multiplier int64 = 1
-	suffix := ""
-	if len(s) > 1 {
-		lastChar := s[len(s)-1]
-		if lastChar >= 'A' && lastChar <= 'Z' {
-			suffix = string(lastChar)
-		}
-	}
-
-	switch suffix {
-	case "K":
-		multiplier = 1024
-	case "M":
-		multiplier = 1024 * 1024
-	case "G":
-		multiplier = 1024 * 1024 * 1024
-	}
-
-	numStr := strings.TrimSuffix(s, suffix)
-	num, err := strconv.ParseInt(numStr, 10, 64)
-	if err != nil {
-		return 0
-	}
-
-	return num * multiplier
-}
@@ -2,89 +2,18 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
-)
-
-// TestSortingLogic проверяет основную логику сравнения, используя сортировку в памяти
-func TestSortingLogic(t *testing.T) {
-	tests := []struct {
-		name  string
-		lines []string
-		cfg   Config
-		want  []string
-	}{
-		{
-			name:  "Simple sort",
-			lines: []string{"c", "a", "b"},
-			cfg:   Config{K: 1},
-			want:  []string{"a", "b", "c"},
-		},
-		{
-			name:  "Reverse sort",
-			lines: []string{"c", "a", "b"},
-			cfg:   Config{K: 1, R: true},
-			want:  []string{"c", "b", "a"},
-		},
-		{
-			name:  "Numeric sort",
-			lines: []string{"10", "2", "1"},
-			cfg:   Config{K: 1, N: true},
-			want:  []string{"1", "2", "10"},
-		},
-		{
-			name:  "Numeric reverse sort",
-			lines: []string{"10", "2", "1"},
-			cfg:   Config{K: 1, N: true, R: true},
-			want:  []string{"10", "2", "1"},
-		},
-		{
-			name:  "Column sort",
-			lines: []string{"a 3", "c 1", "b 2"},
-			cfg:   Config{K: 2},
-			want:  []string{"c 1", "b 2", "a 3"},
-		},
-		{
-			name:  "Column numeric sort",
-			lines: []string{"a 10", "c 2", "b 1"},
-			cfg:   Config{K: 2, N: true},
-			want:  []string{"b 1", "c 2", "a 10"},
-		},
-		{
-			name:  "Month sort",
-			lines: []string{"Mar", "Jan", "Feb"},
-			cfg:   Config{K: 1, M: true},
-			want:  []string{"Jan", "Feb", "Mar"},
-		},
-		{
-			name:  "Human-numeric sort",
-			lines: []string{"1G", "2K", "3M"},
-			cfg:   Config{K: 1, H: true},
-			want:  []string{"2K", "3M", "1G"},
-		},
-		{
-			name:  "Ignore leading blanks",
-			lines: []string{" b", "a "},
-			cfg:   Config{K: 1, B: true},
-			want:  []string{"a ", " b"},
-		},
-	}
+	"time"
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Используем LineSorter для сортировки среза в памяти
-			sorter := NewLineSorter(tt.lines, tt.cfg)
-			sorter.Sort()
-			lines := sorter.lines
-
-			if !reflect.DeepEqual(lines, tt.want) {
-				t.Errorf("got %v, want %v", lines, tt.want)
-			}
-		})
-	}
-}
+	"my-sort-app/linesort"
+)
 
 // TestIsSorted проверяет функцию IsSorted, которая работает с файлами
 func TestIsSorted_File(t *testing.T) {
@@ -97,25 +26,25 @@ func TestIsSorted_File(t *testing.T) {
 		{
 			name:  "Sorted",
 			lines: []string{"a", "b", "c"},
-			cfg:   Config{K: 1},
+			cfg:   Config{},
 			want:  true,
 		},
 		{
 			name:  "Not sorted",
 			lines: []string{"c", "a", "b"},
-			cfg:   Config{K: 1},
+			cfg:   Config{},
 			want:  false,
 		},
 		{
 			name:  "Reverse sorted",
 			lines: []string{"c", "b", "a"},
-			cfg:   Config{K: 1, R: true},
+			cfg:   Config{Options: linesort.Options{R: true}},
 			want:  true,
 		},
 		{
 			name:  "Not reverse sorted",
 			lines: []string{"a", "b", "c"},
-			cfg:   Config{K: 1, R: true},
+			cfg:   Config{Options: linesort.Options{R: true}},
 			want:  false,
 		},
 	}
@@ -168,25 +97,25 @@ func TestIsSorted_Stdin(t *testing.T) {
 		{
 			name:  "STDIN Sorted",
 			lines: []string{"1", "2", "10"},
-			cfg:   Config{K: 1, N: true},
+			cfg:   Config{Options: linesort.Options{N: true}},
 			want:  true,
 		},
 		{
 			name:  "STDIN Not sorted",
 			lines: []string{"c", "a", "b"},
-			cfg:   Config{K: 1},
+			cfg:   Config{},
 			want:  false,
 		},
 		{
 			name:  "STDIN Reverse sorted",
 			lines: []string{"c", "b", "a"},
-			cfg:   Config{K: 1, R: true},
+			cfg:   Config{Options: linesort.Options{R: true}},
 			want:  true,
 		},
 		{
 			name:  "STDIN Not reverse sorted",
 			lines: []string{"a", "b", "c"},
-			cfg:   Config{K: 1, R: true},
+			cfg:   Config{Options: linesort.Options{R: true}},
 			want:  false,
 		},
 	}
@@ -219,11 +148,245 @@ func TestIsSorted_Stdin(t *testing.T) {
 	}
 }
 
+// TestExternalSort_Locale проверяет сравнение по правилам языка при слиянии чанков в куче
+func TestExternalSort_Locale(t *testing.T) {
+	dir := t.TempDir()
+	input := dir + "/input.txt"
+	output := dir + "/output.txt"
+	content := "яблоко\nЕль\nёж\nарбуз\nЁлка\nЖук\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+
+	if err := externalSort(context.Background(), []string{input}, output, Config{Options: linesort.Options{Locale: "ru", F: true}}); err != nil {
+		t.Fatalf("externalSort() failed: %v", err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	want := "арбуз\nёж\nЁлка\nЕль\nЖук\nяблоко\n"
+	if string(got) != want {
+		t.Errorf("externalSort() with locale:\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+// TestExternalSort_Chunks проверяет сортировку, когда ввод не помещается в память (-S)
+// и делится на много чанков, которые сортируют несколько горутин и сливают в несколько проходов
+func TestExternalSort_Chunks(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "One merge pass", cfg: Config{BatchSize: 1000}},
+		{name: "Merge batches", cfg: Config{BatchSize: 3}},
+		{name: "Compressed", cfg: Config{BatchSize: 2, Compress: true}},
+		{name: "Unique", cfg: Config{BatchSize: 4, Compress: true, Options: linesort.Options{U: true}}},
+	}
+
+	lines := benchmarkLines(3000)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := dir + "/input.txt"
+			output := dir + "/output.txt"
+			tempDir := dir + "/tmp"
+			if err := os.Mkdir(tempDir, 0755); err != nil {
+				t.Fatalf("Failed to create temp dir: %v", err)
+			}
+			if err := os.WriteFile(input, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+				t.Fatalf("Failed to write input file: %v", err)
+			}
+
+			cfg := tt.cfg
+			cfg.Keys = []linesort.KeySpec{{StartField: 3, EndField: 3, M: true}, {StartField: 2, EndField: 2, H: true, R: true}}
+			cfg.BufferSize = 8 << 10
+			cfg.Parallel = 3
+			cfg.TempDir = tempDir
+			if err := externalSort(context.Background(), []string{input}, output, cfg); err != nil {
+				t.Fatalf("externalSort() failed: %v", err)
+			}
+
+			got, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("Failed to read output file: %v", err)
+			}
+			want := slices.Clone(lines)
+			sorter := linesort.New(cfg.Options)
+			sorter.Sort(want)
+			if cfg.U {
+				want = sorter.Unique(want)
+			}
+			if string(got) != strings.Join(want, "\n")+"\n" {
+				t.Errorf("externalSort() with small buffer differs from in-memory sort")
+			}
+
+			// временные файлы создаются в -T и удаляются после слияния
+			if entries, err := os.ReadDir(tempDir); err != nil || len(entries) != 0 {
+				t.Errorf("temp dir after externalSort(): %v, %v", entries, err)
+			}
+		})
+	}
+}
+
+// TestExternalSort_Cancel проверяет, что при отмене (Ctrl+C) сортировка останавливается,
+// даже если ждет ввода, и временные файлы удаляются
+func TestExternalSort_Cancel(t *testing.T) {
+	tempDir := t.TempDir()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer reader.Close()
+	defer writer.Close()
+	oldStdin := os.Stdin
+	os.Stdin = reader
+	defer func() { os.Stdin = oldStdin }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		cfg := Config{BufferSize: 8 << 10, Parallel: 2, TempDir: tempDir}
+		errc <- externalSort(ctx, nil, t.TempDir()+"/output.txt", cfg)
+	}()
+
+	// Пишем несколько чанков и, не закрывая ввод, ждем их временных файлов
+	go writer.WriteString(strings.Join(benchmarkLines(1000), "\n") + "\n")
+	deadline := time.Now().Add(5 * time.Second)
+	for entries, _ := os.ReadDir(tempDir); len(entries) == 0; entries, _ = os.ReadDir(tempDir) {
+		if time.Now().After(deadline) {
+			t.Fatal("externalSort() did not create temp files")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("externalSort() after cancel = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("externalSort() did not stop after cancel")
+	}
+	if entries, err := os.ReadDir(tempDir); err != nil || len(entries) != 0 {
+		t.Errorf("temp dir after cancel: %v, %v", entries, err)
+	}
+}
+
+// TestExternalSort_Inputs проверяет сортировку нескольких входных файлов и stdin ("-")
+// как одного ввода с записью результата в один из входных файлов и в stdout
+func TestExternalSort_Inputs(t *testing.T) {
+	dir := t.TempDir()
+	first := dir + "/first.txt"
+	second := dir + "/second.txt"
+	stdin := dir + "/stdin.txt"
+	files := map[string]string{
+		first:  "c\nb", // без перевода строки в конце: не склеивается со следующим файлом
+		second: "a\nd\n",
+		stdin:  "z\ne\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0640); err != nil {
+			t.Fatalf("Failed to write input file: %v", err)
+		}
+	}
+
+	// Подменяем stdin и stdout файлами
+	oldStdin, oldStdout := os.Stdin, os.Stdout
+	defer func() { os.Stdin, os.Stdout = oldStdin, oldStdout }()
+	in, err := os.Open(stdin)
+	if err != nil {
+		t.Fatalf("Failed to open stdin file: %v", err)
+	}
+	defer in.Close()
+	out, err := os.Create(dir + "/stdout.txt")
+	if err != nil {
+		t.Fatalf("Failed to create stdout file: %v", err)
+	}
+	defer out.Close()
+	os.Stdin, os.Stdout = in, out
+
+	if err := externalSort(context.Background(), []string{first, "-", second}, "", Config{}); err != nil {
+		t.Fatalf("externalSort() to stdout failed: %v", err)
+	}
+	got, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatalf("Failed to read stdout file: %v", err)
+	}
+	if want := "a\nb\nc\nd\ne\nz\n"; string(got) != want {
+		t.Errorf("externalSort() to stdout:\ngot:  %q\nwant: %q", got, want)
+	}
+
+	// Выходной файл совпадает с входным: он заменяется только после того, как прочитан
+	if err := externalSort(context.Background(), []string{first, second}, second, Config{Options: linesort.Options{R: true}}); err != nil {
+		t.Fatalf("externalSort() into input file failed: %v", err)
+	}
+	got, err = os.ReadFile(second)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if want := "d\nc\nb\na\n"; string(got) != want {
+		t.Errorf("externalSort() into input file:\ngot:  %q\nwant: %q", got, want)
+	}
+	if info, err := os.Stat(second); err != nil {
+		t.Errorf("Failed to stat output file: %v", err)
+	} else if info.Mode().Perm() != 0640 {
+		t.Errorf("output file mode = %v, want 0640", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != len(files)+1 {
+		t.Errorf("temp output file left in %s: %v", dir, entries)
+	}
+
+	// Если входной файл не открывается, выходной файл не меняется
+	if err := externalSort(context.Background(), []string{first, dir + "/missing.txt"}, second, Config{}); err == nil {
+		t.Error("externalSort() with missing input: expected error")
+	}
+	if got, _ := os.ReadFile(second); string(got) != "d\nc\nb\na\n" {
+		t.Errorf("output file changed after error: %q", got)
+	}
+}
+
+// TestByteSize тестирует разбор размера памяти -S
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "512M", want: 512 << 20},
+		{value: "100", want: 100 << 10},
+		{value: "1G", want: 1 << 30},
+		{value: "4096b", want: 4096},
+		{value: "2T", want: 2 << 40},
+		{value: "", wantErr: true},
+		{value: "M", wantErr: true},
+		{value: "0", wantErr: true},
+		{value: "-5K", wantErr: true},
+		{value: "12Q", wantErr: true},
+		{value: "9999999999T", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var got byteSize
+		err := got.Set(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("byteSize.Set(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && int64(got) != tt.want {
+			t.Errorf("byteSize.Set(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
 // TestExternalSort выполняет интеграционный тест для всего процесса внешней сортировки.
 func TestExternalSort(t *testing.T) {
 	lines := []string{"c 1", "a 3", "b 2", "a 1", "c 2"}
-	expected := []string{"a 3", "a 1", "b 2", "c 1", "c 2"}
-	cfg := Config{K: 1}
+	// строки с равными ключами упорядочиваются сравнением строк целиком
+	expected := []string{"a 1", "a 3", "b 2", "c 1", "c 2"}
+	cfg := Config{Options: linesort.Options{Keys: []linesort.KeySpec{{StartField: 1, EndField: 1}}}}
 
 	// Создаем временный входной файл
 	inputFile, err := os.CreateTemp("", "test-input-*.txt")
@@ -247,7 +410,7 @@ func TestExternalSort(t *testing.T) {
 	defer os.Remove(outputFile.Name())
 
 	// Запускаем внешнюю сортировку
-	err = externalSort(inputFile.Name(), outputFile.Name(), cfg)
+	err = externalSort(context.Background(), []string{inputFile.Name()}, outputFile.Name(), cfg)
 	if err != nil {
 		t.Fatalf("externalSort() failed: %v", err)
 	}
@@ -269,3 +432,15 @@ func TestExternalSort(t *testing.T) {
 		t.Errorf("externalSort() result is incorrect:\ngot:  %v\nwant: %v", gotLines, expected)
 	}
 }
+
+// benchmarkLines создает n строк журнала: имя, размер, месяц и число
+func benchmarkLines(n int) []string {
+	months := []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+	sizes := []string{"K", "M", "G"}
+	lines := make([]string, n)
+	for i := range lines {
+		x := (i*7919 + 13) % 100003
+		lines[i] = fmt.Sprintf("host%d\t%d%s\t%s\t%d.%d", x%97, x%1000, sizes[x%3], months[x%12], x, x%10)
+	}
+	return lines
+}
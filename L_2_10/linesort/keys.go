@@ -0,0 +1,208 @@
+package linesort
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// KeySpec описывает ключ сортировки -k POS1[,POS2][опции], где POS - поле[.символ][опции].
+// Поля и символы нумеруются с 1
+type KeySpec struct {
+	StartField int // поле, с которого начинается ключ (0 - ключ - вся строка)
+	StartChar  int // символ в начальном поле (0 - с начала поля)
+	EndField   int // поле, которым заканчивается ключ включительно (0 - до конца строки)
+	EndChar    int // последний символ в конечном поле (0 - до конца поля)
+
+	// модификаторы сравнения ключа; ключ без модификаторов сравнивается по общим флагам
+	N, R, M, H, B, F, D bool
+}
+
+// KeyList - значение повторяемого флага -k
+type KeyList []KeySpec
+
+// String возвращает ключи в виде флагов -k
+func (l *KeyList) String() string {
+	specs := make([]string, len(*l))
+	for i, key := range *l {
+		specs[i] = fmt.Sprintf("%d.%d,%d.%d", key.StartField, key.StartChar, key.EndField, key.EndChar)
+	}
+	return strings.Join(specs, " ")
+}
+
+// Set разбирает очередной флаг -k
+func (l *KeyList) Set(spec string) error {
+	key, err := ParseKeySpec(spec)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, key)
+	return nil
+}
+
+// ParseKeySpec разбирает описание ключа POS1[,POS2][опции], например "2", "3,3nr" или "2.3,2.5"
+func ParseKeySpec(spec string) (KeySpec, error) {
+	var key KeySpec
+	pos1, pos2, hasEnd := strings.Cut(spec, ",")
+
+	var err error
+	key.StartField, key.StartChar, err = parseKeyPos(pos1, &key)
+	if err != nil {
+		return key, fmt.Errorf("invalid key %q: %w", spec, err)
+	}
+	if key.StartField < 1 {
+		return key, fmt.Errorf("invalid key %q: field number must be greater than 0", spec)
+	}
+	if strings.Contains(pos1, ".") && key.StartChar < 1 {
+		return key, fmt.Errorf("invalid key %q: character offset must be greater than 0", spec)
+	}
+
+	if hasEnd {
+		key.EndField, key.EndChar, err = parseKeyPos(pos2, &key)
+		if err != nil {
+			return key, fmt.Errorf("invalid key %q: %w", spec, err)
+		}
+		if key.EndField < 1 {
+			return key, fmt.Errorf("invalid key %q: field number must be greater than 0", spec)
+		}
+	}
+	return key, nil
+}
+
+// parseKeyPos разбирает позицию поле[.символ][опции] и добавляет модификаторы в key
+func parseKeyPos(pos string, key *KeySpec) (field, char int, err error) {
+	end := strings.IndexFunc(pos, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if end < 0 {
+		end = len(pos)
+	}
+	fieldStr, charStr, hasChar := strings.Cut(pos[:end], ".")
+
+	field, err = strconv.Atoi(fieldStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid field number %q", fieldStr)
+	}
+	if hasChar {
+		char, err = strconv.Atoi(charStr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid character offset %q", charStr)
+		}
+	}
+
+	for _, opt := range pos[end:] {
+		switch opt {
+		case 'n':
+			key.N = true
+		case 'r':
+			key.R = true
+		case 'M':
+			key.M = true
+		case 'h':
+			key.H = true
+		case 'b':
+			key.B = true
+		case 'f':
+			key.F = true
+		case 'd':
+			key.D = true
+		default:
+			return 0, 0, fmt.Errorf("unknown key option %q", opt)
+		}
+	}
+	return field, char, nil
+}
+
+// hasOptions сообщает, заданы ли у ключа свои модификаторы
+func (k KeySpec) hasOptions() bool {
+	return k.N || k.R || k.M || k.H || k.B || k.F || k.D
+}
+
+// sortKeys возвращает ключи сравнения: ключи без модификаторов получают общие флаги,
+// а без -k ключ - вся строка
+func sortKeys(opts Options) []KeySpec {
+	keys := slices.Clone(opts.Keys)
+	if len(keys) == 0 {
+		keys = append(keys, KeySpec{})
+	}
+	for i, key := range keys {
+		if !key.hasOptions() {
+			key.N, key.R, key.M, key.H, key.B, key.F, key.D = opts.N, opts.R, opts.M, opts.H, opts.B, opts.F, opts.D
+			keys[i] = key
+		}
+	}
+	return keys
+}
+
+// extract возвращает текст ключа в строке; spans - границы полей строки (fieldSpans)
+func (k KeySpec) extract(line string, spans [][2]int) string {
+	if k.StartField == 0 {
+		return line
+	}
+	if k.StartField > len(spans) {
+		return ""
+	}
+
+	span := spans[k.StartField-1]
+	start := span[0]
+	if k.StartChar > 1 {
+		start = skipChars(line, span[0], span[1], k.StartChar-1)
+	}
+
+	end := len(line)
+	if k.EndField > 0 && k.EndField <= len(spans) {
+		span = spans[k.EndField-1]
+		end = span[1]
+		if k.EndChar > 0 {
+			end = skipChars(line, span[0], span[1], k.EndChar)
+		}
+	}
+	if end < start {
+		return ""
+	}
+	return line[start:end]
+}
+
+// fieldSpans добавляет к spans границы полей строки: байтовые смещения начала и конца
+// каждого поля. sep - разделитель полей (-t); без него поля разделяются пробелами
+// и табуляциями, а пробелы перед полем в него не входят
+func fieldSpans(spans [][2]int, line, sep string) [][2]int {
+	if sep != "" {
+		start := 0
+		for {
+			i := strings.Index(line[start:], sep)
+			if i < 0 {
+				return append(spans, [2]int{start, len(line)})
+			}
+			spans = append(spans, [2]int{start, start + i})
+			start += i + len(sep)
+		}
+	}
+
+	start := -1
+	for i, r := range line {
+		blank := unicode.IsSpace(r)
+		switch {
+		case blank && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		case !blank && start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(line)})
+	}
+	return spans
+}
+
+// skipChars возвращает смещение в line после n символов, начиная с from, но не дальше limit
+func skipChars(line string, from, limit, n int) int {
+	pos := from
+	for ; n > 0 && pos < limit; n-- {
+		_, size := utf8.DecodeRuneInString(line[pos:])
+		pos += size
+	}
+	return min(pos, limit)
+}
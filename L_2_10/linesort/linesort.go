@@ -0,0 +1,358 @@
+// Package linesort сравнивает и сортирует строки по ключам, как GNU sort: разбирает ключи -k,
+// вычисляет их значения (числа, месяцы, размеры, ключи сравнения по правилам языка)
+// и сравнивает строки по ним. Пакет общий для команд сортировки в памяти и внешней сортировки
+package linesort
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unsafe"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// Options - флаги сравнения строк
+type Options struct {
+	N bool // сортировать по числовому значению
+	R bool // сортировать в обратном порядке
+	U bool // выводить только уникальные строки
+	M bool // сортировать по названию месяца
+	B bool // игнорировать хвостовые пробелы
+	H bool // сортировать по человекочитаемым размерам
+	F bool // не различать регистр букв
+	D bool // учитывать только буквы, цифры и пробелы
+	S bool // не сравнивать строки целиком, если ключи равны (стабильная сортировка)
+
+	Keys   []KeySpec // ключи сортировки (-k) по порядку; пусто - вся строка
+	T      string    // разделитель полей (пусто - пробелы и табуляции)
+	Locale string    // язык для сравнения строк по правилам Unicode (пусто или C - побайтово)
+}
+
+// Sorter сравнивает и сортирует строки по ключам.
+// Collator нельзя использовать из нескольких горутин, поэтому каждой горутине нужен свой Sorter
+type Sorter struct {
+	opts     Options
+	keys     []KeySpec         // ключи сравнения с учетом общих флагов
+	collator *collate.Collator // сравнение по правилам языка (nil - побайтово)
+
+	foldCollator *collate.Collator // то же без учета регистра (модификатор f)
+
+	spans   [][2]int       // буфер границ полей строки для ParseLine
+	collBuf collate.Buffer // буфер ключей сравнения collator'а
+}
+
+// New создает Sorter для флагов opts
+func New(opts Options) *Sorter {
+	return &Sorter{
+		opts:         opts,
+		keys:         sortKeys(opts),
+		collator:     newCollator(opts.Locale, false),
+		foldCollator: newCollator(opts.Locale, true),
+	}
+}
+
+// keyValue - значение ключа строки, вычисленное один раз до сортировки
+type keyValue struct {
+	text  string  // текст ключа после модификаторов b, d и f; сравнивается побайтово
+	coll  []byte  // ключ сравнения collator'а: при --locale сравнивается вместо text
+	num   float64 // число (n)
+	isNum bool    // значение ключа n - число
+	month int     // номер месяца (M), 0 - неизвестный месяц
+	size  int64   // размер в байтах (h)
+}
+
+// collationKeySize - примерное отношение длины ключа сравнения collator'а к длине текста
+const collationKeySize = 3
+
+// Line - строка вместе со значениями ее ключей (ParseLine)
+type Line struct {
+	Text string
+	keys []keyValue
+}
+
+// Copy копирует в l строку src и значения ее ключей, используя память значений l
+func (l *Line) Copy(src *Line) {
+	l.Text = src.Text
+	l.keys = append(l.keys[:0], src.keys...)
+}
+
+// Sort сортирует строки на месте. Ключи каждой строки вычисляются один раз до сортировки
+func (s *Sorter) Sort(lines []string) {
+	if s.plainKey() {
+		// ключ - вся строка без модификаторов: вычислять значения ключей не нужно
+		slices.SortStableFunc(lines, func(a, b string) int {
+			if s.opts.R {
+				return strings.Compare(b, a)
+			}
+			return strings.Compare(a, b)
+		})
+		return
+	}
+	// переставляем указатели на строки со значениями ключей: так перестановки дешевле
+	parsed := s.parseLines(lines)
+	order := make([]*Line, len(parsed))
+	for i := range parsed {
+		order[i] = &parsed[i]
+	}
+	slices.SortStableFunc(order, s.Compare)
+	for i, p := range order {
+		lines[i] = p.Text
+	}
+}
+
+// IsSorted сообщает, что строки уже отсортированы
+func (s *Sorter) IsSorted(lines []string) bool {
+	parsed := s.parseLines(lines)
+	for i := 1; i < len(parsed); i++ {
+		if s.Compare(&parsed[i], &parsed[i-1]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Unique оставляет первую строку из каждой группы подряд идущих строк с равными ключами (-u)
+func (s *Sorter) Unique(lines []string) []string {
+	if len(lines) == 0 {
+		return lines
+	}
+	parsed := s.parseLines(lines)
+	result := []string{lines[0]}
+	last := &parsed[0]
+	for i := 1; i < len(parsed); i++ {
+		if s.CompareKeys(&parsed[i], last) != 0 {
+			result = append(result, parsed[i].Text)
+			last = &parsed[i]
+		}
+	}
+	return result
+}
+
+// plainKey сообщает, что строки сравниваются целиком и побайтово
+func (s *Sorter) plainKey() bool {
+	key := s.keys[0]
+	return len(s.keys) == 1 && key.StartField == 0 && s.collator == nil &&
+		!key.N && !key.M && !key.H && !key.B && !key.F && !key.D
+}
+
+// LineSize оценивает память, которую строка занимает при сортировке: сама строка,
+// ее место в слайсе и значения ключей, если они вычисляются
+func (s *Sorter) LineSize(line string) int64 {
+	size := len(line) + int(unsafe.Sizeof(line))
+	if !s.plainKey() {
+		size += int(unsafe.Sizeof(Line{})+unsafe.Sizeof(&Line{})) +
+			len(s.keys)*int(unsafe.Sizeof(keyValue{}))
+		if s.collator != nil {
+			size += len(s.keys) * collationKeySize * len(line) // ключи сравнения collator'а
+		}
+	}
+	return int64(size)
+}
+
+// parseLines вычисляет значения ключей всех строк; значения хранятся в одном общем слайсе
+func (s *Sorter) parseLines(lines []string) []Line {
+	n := len(s.keys)
+	values := make([]keyValue, len(lines)*n)
+	parsed := make([]Line, len(lines))
+	for i, line := range lines {
+		parsed[i].keys = values[i*n : (i+1)*n : (i+1)*n]
+		s.ParseLine(line, &parsed[i])
+	}
+	return parsed
+}
+
+// ParseLine записывает в dst строку text и значения ее ключей. Значения пишутся
+// на место прежних значений dst, если там хватает места, иначе - в новый слайс
+func (s *Sorter) ParseLine(text string, dst *Line) {
+	keys := dst.keys
+	if cap(keys) < len(s.keys) {
+		keys = make([]keyValue, len(s.keys))
+	}
+	keys = keys[:len(s.keys)]
+
+	// границы полей нужны, только если ключ - не вся строка
+	s.spans = s.spans[:0]
+	if s.keys[0].StartField > 0 || len(s.keys) > 1 {
+		s.spans = fieldSpans(s.spans, text, s.opts.T)
+	}
+	for i, key := range s.keys {
+		keys[i] = s.parseKey(key, key.extract(text, s.spans))
+	}
+	dst.Text, dst.keys = text, keys
+}
+
+// parseKey вычисляет значение ключа с учетом его модификаторов
+func (s *Sorter) parseKey(key KeySpec, val string) keyValue {
+	var v keyValue
+	if key.B {
+		val = strings.TrimSpace(val)
+	}
+
+	switch {
+	case key.N:
+		num, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		if err == nil {
+			v.num, v.isNum = num, true
+			return v
+		}
+		// не число сравнивается как текст
+	case key.M:
+		v.month = parseMonth(strings.TrimSpace(val))
+		return v
+	case key.H:
+		v.size = parseHumanReadable(strings.TrimSpace(val))
+		return v
+	}
+
+	if key.D {
+		val = dictionaryOrder(val)
+	}
+	switch {
+	case s.collator != nil:
+		// регистр при f не учитывает сам collator
+		collator := s.collator
+		if key.F {
+			collator = s.foldCollator
+		}
+		v.coll = bytes.Clone(collator.KeyFromString(&s.collBuf, val))
+		s.collBuf.Reset()
+	case key.F:
+		val = strings.ToUpper(val)
+	}
+	v.text = val
+	return v
+}
+
+// Compare сравнивает строки по ключам, а если ключи равны - целиком, побайтово
+// (кроме -s и -u): -1, 0 или 1
+func (s *Sorter) Compare(a, b *Line) int {
+	if c := s.CompareKeys(a, b); c != 0 || s.opts.S || s.opts.U {
+		return c
+	}
+	c := strings.Compare(a.Text, b.Text)
+	if s.opts.R {
+		return -c
+	}
+	return c
+}
+
+// CompareKeys сравнивает строки по ключам по очереди: следующий ключ сравнивается,
+// только если предыдущие равны
+func (s *Sorter) CompareKeys(a, b *Line) int {
+	for i := range s.keys {
+		key := &s.keys[i]
+		c := s.compareValues(key, &a.keys[i], &b.keys[i])
+		if c != 0 {
+			if key.R {
+				return -c
+			}
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues сравнивает значения ключа: -1, 0 или 1
+func (s *Sorter) compareValues(key *KeySpec, a, b *keyValue) int {
+	switch {
+	case key.N:
+		switch {
+		case !a.isNum && b.isNum: // A - не число, B - число
+			return -1 // Нечисловые значения считаем меньше числовых
+		case a.isNum && !b.isNum: // A - число, B - не число
+			return 1
+		case a.isNum: // Оба числа
+			return cmp.Compare(a.num, b.num)
+		}
+		// Оба не числа, сравниваем как строки
+	case key.M:
+		return cmp.Compare(a.month, b.month)
+	case key.H:
+		return cmp.Compare(a.size, b.size)
+	}
+
+	if s.collator != nil {
+		return bytes.Compare(a.coll, b.coll)
+	}
+	return strings.Compare(a.text, b.text)
+}
+
+// newCollator создает collator для языка locale, с fold - без учета регистра;
+// для побайтового сравнения - nil
+func newCollator(locale string, fold bool) *collate.Collator {
+	if IsByteLocale(locale) {
+		return nil
+	}
+	var opts []collate.Option
+	if fold {
+		opts = append(opts, collate.IgnoreCase)
+	}
+	return collate.New(language.Make(locale), opts...)
+}
+
+// IsByteLocale сообщает, что строки сравниваются побайтово, как в локали C
+func IsByteLocale(locale string) bool {
+	return locale == "" || locale == "C" || locale == "POSIX"
+}
+
+// dictionaryOrder оставляет в строке только буквы, цифры и пробелы (-d)
+func dictionaryOrder(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+var monthMap = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+func parseMonth(s string) int {
+	s = strings.ToLower(s)
+	if len(s) > 3 {
+		s = s[:3]
+	}
+	if val, ok := monthMap[s]; ok {
+		return val
+	}
+
+	// неизвестный месяц возвращаем как 0
+	return 0
+}
+
+func parseHumanReadable(s string) int64 {
+	s = strings.ToUpper(s)
+	var multiplier int64 = 1
+	suffix := ""
+	if len(s) > 1 {
+		lastChar := s[len(s)-1]
+		if lastChar >= 'A' && lastChar <= 'Z' {
+			suffix = string(lastChar)
+		}
+	}
+
+	switch suffix {
+	case "K":
+		multiplier = 1024
+	case "M":
+		multiplier = 1024 * 1024
+	case "G":
+		multiplier = 1024 * 1024 * 1024
+	}
+
+	numStr := strings.TrimSuffix(s, suffix)
+	num, err := strconv.ParseInt(numStr, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return num * multiplier
+}
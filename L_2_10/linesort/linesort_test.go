@@ -0,0 +1,313 @@
+package linesort
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// TestSort проверяет сортировку строк по флагам и ключам
+func TestSort(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		opts  Options
+		want  []string
+	}{
+		{
+			name:  "Simple sort",
+			lines: []string{"c", "a", "b"},
+			opts:  Options{},
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			name:  "Reverse sort",
+			lines: []string{"c", "a", "b"},
+			opts:  Options{R: true},
+			want:  []string{"c", "b", "a"},
+		},
+		{
+			name:  "Numeric sort",
+			lines: []string{"10", "2", "1"},
+			opts:  Options{N: true},
+			want:  []string{"1", "2", "10"},
+		},
+		{
+			name:  "Numeric reverse sort",
+			lines: []string{"10", "2", "1"},
+			opts:  Options{N: true, R: true},
+			want:  []string{"10", "2", "1"},
+		},
+		{
+			name:  "Column sort",
+			lines: []string{"a 3", "c 1", "b 2"},
+			opts:  Options{Keys: []KeySpec{mustKey("2,2")}},
+			want:  []string{"c 1", "b 2", "a 3"},
+		},
+		{
+			name:  "Column numeric sort",
+			lines: []string{"a 10", "c 2", "b 1"},
+			opts:  Options{Keys: []KeySpec{mustKey("2,2")}, N: true},
+			want:  []string{"b 1", "c 2", "a 10"},
+		},
+		{
+			name:  "Month sort",
+			lines: []string{"Mar", "Jan", "Feb"},
+			opts:  Options{M: true},
+			want:  []string{"Jan", "Feb", "Mar"},
+		},
+		{
+			name:  "Human-numeric sort",
+			lines: []string{"1G", "2K", "3M"},
+			opts:  Options{H: true},
+			want:  []string{"2K", "3M", "1G"},
+		},
+		{
+			name:  "Unique sort",
+			lines: []string{"c", "a", "b", "a"},
+			opts:  Options{U: true},
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			name:  "Ignore leading blanks",
+			lines: []string{" b", "a "},
+			opts:  Options{B: true},
+			want:  []string{"a ", " b"},
+		},
+		{
+			name:  "Multiple keys",
+			lines: []string{"b x 2", "a y 10", "c z 2", "a w 1"},
+			opts:  Options{Keys: []KeySpec{mustKey("3,3nr"), mustKey("1,1")}},
+			want:  []string{"a y 10", "b x 2", "c z 2", "a w 1"},
+		},
+		{
+			name:  "Field separator",
+			lines: []string{"x,b c,2", "y,a b,3", "z,a b,1"},
+			opts:  Options{Keys: []KeySpec{mustKey("2,2"), mustKey("3n")}, T: ","},
+			want:  []string{"z,a b,1", "y,a b,3", "x,b c,2"},
+		},
+		{
+			name:  "Character offsets",
+			lines: []string{"id ab30x", "id zz10y", "id cd20z"},
+			opts:  Options{Keys: []KeySpec{mustKey("2.3,2.4n")}},
+			want:  []string{"id zz10y", "id cd20z", "id ab30x"},
+		},
+		{
+			name:  "Last-resort comparison",
+			lines: []string{"b 1", "a 1", "c 0"},
+			opts:  Options{Keys: []KeySpec{mustKey("2,2n")}},
+			want:  []string{"c 0", "a 1", "b 1"},
+		},
+		{
+			name:  "Stable sort",
+			lines: []string{"b 1", "a 1", "c 0"},
+			opts:  Options{Keys: []KeySpec{mustKey("2,2n")}, S: true},
+			want:  []string{"c 0", "b 1", "a 1"},
+		},
+		{
+			name:  "Key options override global flags",
+			lines: []string{"Feb b", "Jan a", "Jan c"},
+			opts:  Options{Keys: []KeySpec{mustKey("1,1M"), mustKey("2")}, R: true},
+			want:  []string{"Jan c", "Jan a", "Feb b"},
+		},
+		{
+			name:  "Unique by key",
+			lines: []string{"b 2", "a 1", "c 1"},
+			opts:  Options{Keys: []KeySpec{mustKey("2,2")}, U: true},
+			want:  []string{"a 1", "b 2"},
+		},
+		{
+			name:  "Russian collation",
+			lines: []string{"яблоко", "Ель", "ёж", "арбуз", "Ёлка", "Жук"},
+			opts:  Options{Locale: "ru"},
+			want:  []string{"арбуз", "ёж", "Ёлка", "Ель", "Жук", "яблоко"},
+		},
+		{
+			name:  "German collation",
+			lines: []string{"Zebra", "Straße", "Äpfel", "Strase", "Apfel"},
+			opts:  Options{Locale: "de"},
+			want:  []string{"Apfel", "Äpfel", "Strase", "Straße", "Zebra"},
+		},
+		{
+			name:  "Collation ignoring case",
+			lines: []string{"ель", "Ель", "Ёж", "ёж"},
+			opts:  Options{Locale: "ru", F: true},
+			want:  []string{"Ёж", "ёж", "Ель", "ель"}, // равные без учета регистра - побайтово
+		},
+		{
+			name:  "Fold case",
+			lines: []string{"b", "A", "a", "B"},
+			opts:  Options{F: true},
+			want:  []string{"A", "a", "B", "b"},
+		},
+		{
+			name:  "Dictionary order",
+			lines: []string{"#c", "b", "(a)"},
+			opts:  Options{D: true},
+			want:  []string{"(a)", "b", "#c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorter := New(tt.opts)
+			lines := tt.lines
+			sorter.Sort(lines)
+			if tt.opts.U {
+				lines = sorter.Unique(lines)
+			}
+			if !reflect.DeepEqual(lines, tt.want) {
+				t.Errorf("got %v, want %v", lines, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsSorted проверяет, что IsSorted распознает отсортированные строки (-c)
+func TestIsSorted(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		opts  Options
+		want  bool
+	}{
+		{
+			name:  "Sorted",
+			lines: []string{"a", "b", "c"},
+			opts:  Options{},
+			want:  true,
+		},
+		{
+			name:  "Not sorted",
+			lines: []string{"c", "a", "b"},
+			opts:  Options{},
+			want:  false,
+		},
+		{
+			name:  "Reverse sorted",
+			lines: []string{"c", "b", "a"},
+			opts:  Options{R: true},
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.opts).IsSorted(tt.lines); got != tt.want {
+				t.Errorf("IsSorted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// mustKey разбирает описание ключа -k для тестов
+func mustKey(spec string) KeySpec {
+	key, err := ParseKeySpec(spec)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// TestParseKeySpec проверяет разбор описаний ключей -k
+func TestParseKeySpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		want KeySpec
+	}{
+		{spec: "2", want: KeySpec{StartField: 2}},
+		{spec: "3,3nr", want: KeySpec{StartField: 3, EndField: 3, N: true, R: true}},
+		{spec: "2.3,2.5", want: KeySpec{StartField: 2, StartChar: 3, EndField: 2, EndChar: 5}},
+		{spec: "1b,1Mf", want: KeySpec{StartField: 1, EndField: 1, B: true, M: true, F: true}},
+		{spec: "4h", want: KeySpec{StartField: 4, H: true}},
+	}
+	for _, tt := range tests {
+		got, err := ParseKeySpec(tt.spec)
+		if err != nil {
+			t.Errorf("ParseKeySpec(%q) returned an unexpected error: %v", tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseKeySpec(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"", "0", "x", "1.0", "1,0", "2z", "1,a"} {
+		if _, err := ParseKeySpec(spec); err == nil {
+			t.Errorf("ParseKeySpec(%q): expected error", spec)
+		}
+	}
+}
+
+// benchmarkLines создает n строк журнала: имя, размер, месяц и число
+func benchmarkLines(n int) []string {
+	months := []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+	sizes := []string{"K", "M", "G"}
+	lines := make([]string, n)
+	for i := range lines {
+		x := (i*7919 + 13) % 100003
+		lines[i] = fmt.Sprintf("host%d\t%d%s\t%s\t%d.%d", x%97, x%1000, sizes[x%3], months[x%12], x, x%10)
+	}
+	return lines
+}
+
+// benchmarkConfigs - конфигурации для бенчмарков сортировки
+var benchmarkConfigs = []struct {
+	name string
+	opts Options
+}{
+	{name: "Text", opts: Options{}},
+	{name: "Numeric", opts: Options{Keys: []KeySpec{mustKey("4,4n")}}},
+	{name: "Keys", opts: Options{Keys: []KeySpec{mustKey("3,3M"), mustKey("2,2hr"), mustKey("1,1")}}},
+	{name: "Locale", opts: Options{Locale: "ru", F: true}},
+}
+
+// BenchmarkSort измеряет сортировку строк по ключам разных типов. Для сравнения
+// PerCompare вычисляет значения ключей обеих строк при каждом сравнении, как раньше
+func BenchmarkSort(b *testing.B) {
+	lines := benchmarkLines(20000)
+
+	for _, c := range benchmarkConfigs {
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			work := make([]string, len(lines))
+			for b.Loop() {
+				copy(work, lines)
+				New(c.opts).Sort(work)
+			}
+		})
+		b.Run(c.name+"/PerCompare", func(b *testing.B) {
+			b.ReportAllocs()
+			work := make([]string, len(lines))
+			for b.Loop() {
+				copy(work, lines)
+				sorter := New(c.opts)
+				var lineA, lineB Line
+				slices.SortStableFunc(work, func(a, b string) int {
+					sorter.ParseLine(a, &lineA)
+					sorter.ParseLine(b, &lineB)
+					return sorter.Compare(&lineA, &lineB)
+				})
+			}
+		})
+	}
+}
+
+// TestCompareAllocs тестирует, что сравнение строк с вычисленными ключами не выделяет память
+func TestCompareAllocs(t *testing.T) {
+	lines := benchmarkLines(2)
+	for _, c := range benchmarkConfigs {
+		sorter := New(c.opts)
+		var a, b Line
+		sorter.ParseLine(lines[0], &a)
+		sorter.ParseLine(lines[1], &b)
+		allocs := testing.AllocsPerRun(100, func() {
+			sorter.Compare(&a, &b)
+			sorter.CompareKeys(&b, &a)
+		})
+		if allocs != 0 {
+			t.Errorf("%s: compare allocates %v times per run, want 0", c.name, allocs)
+		}
+	}
+}
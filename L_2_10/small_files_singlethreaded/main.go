@@ -24,21 +24,17 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"sort"
-	"strconv"
-	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+
+	"my-sort-app/linesort"
 )
 
 // Config holds the command-line flags
 type Config struct {
-	K int  // сортировать по столбцу (колонке)
-	N bool // сортировать по числовому значению
-	R bool // сортировать в обратном порядке
-	U bool // выводить только уникальные строки
-	M bool // сортировать по названию месяца
-	B bool // игнорировать хвостовые пробелы
-	C bool // проверить, отсортированы ли данные
-	H bool // сортировать по человекочитаемым размерам
+	linesort.Options      // флаги сравнения строк
+	C                bool // проверить, отсортированы ли данные
 }
 
 func main() {
@@ -50,20 +46,20 @@ func main() {
 		os.Exit(1)
 	}
 
-	sorter := NewLineSorter(lines, cfg)
+	sorter := linesort.New(cfg.Options)
 
 	if cfg.C {
-		if !sorter.IsSorted() {
+		if !sorter.IsSorted(lines) {
 			fmt.Println("file is not sorted")
 			os.Exit(1)
 		}
 		os.Exit(0)
 	}
 
-	sorter.Sort()
+	sorter.Sort(lines)
 
 	if cfg.U {
-		lines = unique(lines)
+		lines = sorter.Unique(lines)
 	}
 
 	printLines(lines)
@@ -71,7 +67,9 @@ func main() {
 
 func parseFlags() Config {
 	var cfg Config
-	flag.IntVar(&cfg.K, "k", 1, "sort by column (1-indexed)")
+	flag.Var((*linesort.KeyList)(&cfg.Keys), "k", "sort via a key POS1[,POS2][opts], POS is F[.C][opts], opts are nrMhbfd (repeatable)")
+	flag.StringVar(&cfg.T, "t", "", "use SEP as the field separator instead of blanks")
+	flag.BoolVar(&cfg.S, "s", false, "stabilize sort by disabling last-resort comparison")
 	flag.BoolVar(&cfg.N, "n", false, "sort numerically")
 	flag.BoolVar(&cfg.R, "r", false, "reverse the result of comparisons")
 	flag.BoolVar(&cfg.U, "u", false, "output only the first of an equal run")
@@ -79,12 +77,22 @@ func parseFlags() Config {
 	flag.BoolVar(&cfg.B, "b", false, "ignore leading blanks")
 	flag.BoolVar(&cfg.C, "c", false, "check for sorted input; do not sort")
 	flag.BoolVar(&cfg.H, "h", false, "compare human readable numbers (e.g., 2K 1G)")
+	flag.BoolVar(&cfg.F, "f", false, "fold lower case to upper case characters")
+	flag.BoolVar(&cfg.D, "d", false, "consider only blanks and alphanumeric characters")
+	flag.StringVar(&cfg.Locale, "locale", "", "compare strings using collation rules of the locale (e.g., ru, de)")
+	flag.StringVar(&cfg.Locale, "L", "", "shorthand for --locale")
 	flag.Parse()
 
-	if cfg.K < 1 {
-		fmt.Fprintln(os.Stderr, "error: column index must be greater than 0")
+	if utf8.RuneCountInString(cfg.T) > 1 {
+		fmt.Fprintln(os.Stderr, "error: field separator must be a single character")
 		os.Exit(1)
 	}
+	if !linesort.IsByteLocale(cfg.Locale) {
+		if _, err := language.Parse(cfg.Locale); err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid locale %q: %v\n", cfg.Locale, err)
+			os.Exit(1)
+		}
+	}
 	return cfg
 }
 
@@ -109,142 +117,8 @@ func readLines(filename string) ([]string, error) {
 	return lines, scanner.Err()
 }
 
-// LineSorter encapsulates the sorting logic
-type LineSorter struct {
-	lines []string
-	cfg   Config
-}
-
-func NewLineSorter(lines []string, cfg Config) *LineSorter {
-	return &LineSorter{lines: lines, cfg: cfg}
-}
-
-func (s *LineSorter) IsSorted() bool {
-	for i := 1; i < len(s.lines); i++ {
-		if s.less(i, i-1) {
-			return false
-		}
-	}
-	return true
-}
-
-func (s *LineSorter) Sort() {
-	sort.SliceStable(s.lines, s.less)
-}
-
-func (s *LineSorter) less(i, j int) bool {
-	lineA, lineB := s.lines[i], s.lines[j]
-
-	valA := s.getCompareValue(lineA)
-	valB := s.getCompareValue(lineB)
-
-	var isLess bool
-	switch {
-	case s.cfg.N:
-		numA, errA := strconv.ParseFloat(valA, 64)
-		numB, errB := strconv.ParseFloat(valB, 64)
-		if errA != nil && errB == nil { // A - не число, B - число
-			isLess = true // Нечисловые значения считаем меньше числовых
-		} else if errA == nil && errB != nil { // A - число, B - не число
-			isLess = false
-		} else if errA != nil { // Оба не числа, сравниваем как строки
-			isLess = valA < valB
-		} else { // Оба числа
-			isLess = numA < numB
-		}
-	case s.cfg.M:
-		monthA := parseMonth(valA)
-		monthB := parseMonth(valB)
-		isLess = monthA < monthB
-	case s.cfg.H:
-		numA := parseHumanReadable(valA)
-		numB := parseHumanReadable(valB)
-		isLess = numA < numB
-	default:
-		isLess = valA < valB
-	}
-
-	if s.cfg.R {
-		return !isLess
-	}
-	return isLess
-}
-
-func (s *LineSorter) getCompareValue(line string) string {
-	if s.cfg.B {
-		line = strings.TrimSpace(line)
-	}
-
-	fields := strings.Fields(line)
-	if s.cfg.K > 0 && s.cfg.K <= len(fields) {
-		return fields[s.cfg.K-1]
-	}
-	// если колонка -k не определена или вне диапазона, сортируем по всей строке
-	return line
-}
-
 func printLines(lines []string) {
 	for _, line := range lines {
 		fmt.Println(line)
 	}
 }
-
-func unique(lines []string) []string {
-	if len(lines) == 0 {
-		return lines
-	}
-	result := []string{lines[0]}
-	for i := 1; i < len(lines); i++ {
-		if lines[i] != lines[i-1] {
-			result = append(result, lines[i])
-		}
-	}
-	return result
-}
-
-var monthMap = map[string]int{
-	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
-	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
-}
-
-func parseMonth(s string) int {
-	s = strings.ToLower(s)
-	if len(s) > 3 {
-		s = s[:3]
-	}
-	if val, ok := monthMap[s]; ok {
-		return val
-	}
-
-	// неизвестный месяц возвращаем как 0
-	return 0
-}
-
-func parseHumanReadable(s string) int64 {
-	s = strings.ToUpper(s)
-	var multiplier int64 = 1
-	suffix := ""
-	if len(s) > 1 {
-		lastChar := s[len(s)-1]
-		if lastChar >= 'A' && lastChar <= 'Z' {
-			suffix = string(lastChar)
-		}
-	}
-
-	switch suffix {
-	case "K":
-		multiplier = 1024
-	case "M":
-		multiplier = 1024 * 1024
-	case "G":
-		multiplier = 1024 * 1024 * 1024
-	}
-
-	numStr := strings.TrimSuffix(s, suffix)
-	num, err := strconv.ParseInt(numStr, 10, 64)
-	if err != nil {
-		return 0
-	}
-
-	return num * multiplier
-}
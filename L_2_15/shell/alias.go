@@ -0,0 +1,151 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// activeAlias - алиас, значение которого сейчас разбирается
+type activeAlias struct {
+	end   int  // Смещение конца подставленного значения в тексте лексера
+	blank bool // Значение кончается пробелом: следующее слово тоже может быть алиасом
+}
+
+// expandAlias заменяет текущее слово значением алиаса, если слово стоит на месте
+// имени команды (cmdPos) или сразу после алиаса, значение которого кончается пробелом.
+// Слово с кавычками или экранированием алиасом не считается. Значение подставляется
+// в исходный текст и разбирается заново, поэтому может содержать несколько команд,
+// операторы и другие алиасы. Алиас не раскрывается внутри собственного значения,
+// что исключает бесконечную рекурсию
+func (p *Parser) expandAlias(cmdPos bool) error {
+	for {
+		afterBlank := p.finishAliases()
+		word := p.tok.val
+		value, ok := p.aliases[word]
+		if p.tok.kind != tokWord || !(cmdPos || afterBlank) || !ok || strings.ContainsAny(word, "'\"\\$`") {
+			return nil
+		}
+		if _, active := p.activeAliases[word]; active {
+			return nil
+		}
+
+		pos := p.tok.pos
+		delta := len(value) - len(word)
+		for _, a := range p.activeAliases {
+			if a.end > pos {
+				a.end += delta
+			}
+		}
+		p.activeAliases[word] = &activeAlias{
+			end:   pos + len(value),
+			blank: strings.HasSuffix(value, " ") || strings.HasSuffix(value, "\t"),
+		}
+
+		l := p.lex
+		l.src = l.src[:pos] + value + l.src[pos+len(word):]
+		l.off = pos
+		// prevEnd остается концом лексемы перед алиасом
+		prevEnd := p.prevEnd
+		if err := p.next(); err != nil {
+			return err
+		}
+		p.prevEnd = prevEnd
+		cmdPos = true
+	}
+}
+
+// finishAliases забывает алиасы, значения которых уже полностью разобраны. Возвращает true,
+// если текущая лексема - первая после значения алиаса, которое кончается пробелом
+func (p *Parser) finishAliases() bool {
+	afterBlank := false
+	for name, a := range p.activeAliases {
+		if p.tok.pos >= a.end {
+			if a.blank && p.prevEnd <= a.end {
+				afterBlank = true
+			}
+			delete(p.activeAliases, name)
+		}
+	}
+	return afterBlank
+}
+
+// validAliasName сообщает, можно ли использовать name как имя алиаса
+func validAliasName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\n=/$`'\"\\|&;<>()")
+}
+
+// builtinAlias определяет и выводит алиасы:
+//
+//	alias [-p] [имя[=значение] ...]
+//
+// Без аргументов (или с -p) выводит все алиасы в виде, пригодном для повторного ввода
+func (s *Shell) builtinAlias(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	args = args[1:]
+	printAll := len(args) == 0
+	if len(args) > 0 && args[0] == "-p" {
+		args, printAll = args[1:], true
+	}
+	if printAll {
+		s.printAliases(stdout, slices.Sorted(maps.Keys(s.aliases)))
+	}
+
+	exitCode := 0
+	for _, arg := range args {
+		name, value, assign := strings.Cut(arg, "=")
+		if !assign {
+			if _, ok := s.aliases[name]; !ok {
+				fmt.Fprintf(stderr, "alias: %s: не найден\n", name)
+				exitCode = 1
+				continue
+			}
+			s.printAliases(stdout, []string{name})
+			continue
+		}
+		if !validAliasName(name) {
+			fmt.Fprintf(stderr, "alias: '%s': недопустимое имя псевдонима\n", name)
+			exitCode = 1
+			continue
+		}
+		s.aliases[name] = value
+	}
+	return exitCode
+}
+
+// printAliases выводит алиасы names как команды alias
+func (s *Shell) printAliases(w io.Writer, names []string) {
+	for _, name := range names {
+		fmt.Fprintf(w, "alias %s=%s\n", name, singleQuote(s.aliases[name]))
+	}
+}
+
+// singleQuote заключает строку в одинарные кавычки, экранируя кавычки внутри
+func singleQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// builtinUnalias удаляет алиасы: unalias имя ... или unalias -a (все)
+func (s *Shell) builtinUnalias(args []string, _ io.Reader, _, stderr io.Writer) int {
+	args = args[1:]
+	if len(args) > 0 && args[0] == "-a" {
+		clear(s.aliases)
+		return 0
+	}
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "unalias: использование: unalias [-a] имя [имя ...]")
+		return 2
+	}
+
+	exitCode := 0
+	for _, name := range args {
+		if _, ok := s.aliases[name]; !ok {
+			fmt.Fprintf(stderr, "unalias: %s: не найден\n", name)
+			exitCode = 1
+			continue
+		}
+		delete(s.aliases, name)
+	}
+	return exitCode
+}
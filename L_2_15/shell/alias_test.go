@@ -0,0 +1,59 @@
+package shell
+
+import "testing"
+
+// TestAliasExpansion тестирует раскрытие алиасов в начале команды
+func TestAliasExpansion(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"simple", "alias ll='echo LL'\nll x\n", "LL x\n"},
+		{"after assignment", "alias ll='echo LL'\nX=1 ll\n", "LL\n"},
+		{"not an argument", "alias ll='echo LL'\necho ll\n", "ll\n"},
+		{"quoted name", "alias e='echo E'\n'e'cho q\n", "q\n"},
+		{"recursive", "alias a='b 1' b='echo B'\na 2\n", "B 1 2\n"},
+		{"self reference", "alias echo='echo E:'\necho x\n", "E: x\n"},
+		{"trailing blank", "alias s='echo S ' ll='echo LL'\ns ll x\n", "S echo LL x\n"},
+		{"operators", "alias two='echo one; echo two'\ntwo | tr a-z A-Z\n", "one\nTWO\n"},
+		{"after operator", "alias ok='echo ok'\ntrue && ok\n", "ok\n"},
+		{"reserved word", "alias yes='if true; then echo y; fi'\nyes\n", "y\n"},
+		{"same line", "alias true='echo X'; true\ntrue\n", "X\n"}, // Действует со следующей строки
+		{"in function", "alias ll='echo LL'\nf() { ll f; }\nunalias ll\nf\n", "LL f\n"},
+		{"command substitution", "alias ll='echo LL'\necho [$(ll s)]\n", "[LL s]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, _ := runScript(t, tt.script)
+			if result != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, result)
+			}
+		})
+	}
+}
+
+// TestBuiltinAlias тестирует вывод и удаление алиасов
+func TestBuiltinAlias(t *testing.T) {
+	t.Parallel()
+	script := "alias b='echo it'\\''s' a=ls\n" +
+		"alias\n" +
+		"alias a\n" +
+		"alias none 2>/dev/null; echo st=$?\n" +
+		"alias 'x y=1' 2>/dev/null; echo st=$?\n" +
+		"unalias a none 2>/dev/null; echo st=$?\n" +
+		"alias -p\n" +
+		"unalias -a; alias; echo done\n"
+	result, _ := runScript(t, script)
+
+	want := "alias a='ls'\nalias b='echo it'\\''s'\n" +
+		"alias a='ls'\n" +
+		"st=1\nst=1\nst=1\n" +
+		"alias b='echo it'\\''s'\n" +
+		"done\n"
+	if result != want {
+		t.Errorf("alias: expected %q, got %q", want, result)
+	}
+}
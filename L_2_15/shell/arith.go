@@ -0,0 +1,344 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// arithOps перечисляет операторы арифметических выражений; более длинные идут раньше
+var arithOps = []string{
+	"<<=", ">>=",
+	"**", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||", "+=", "-=", "*=", "/=", "%=",
+	"+", "-", "*", "/", "%", "<", ">", "&", "|", "^", "!", "~", "?", ":", "(", ")", "=",
+}
+
+// arithParser вычисляет арифметическое выражение $((...)) над 64-битными целыми числами.
+// Приоритеты операторов - как в C:
+//
+//	= += -= *= /= %= <<= >>=  (присваивание переменной)
+//	?:  ||  &&  |  ^  &  == !=  < <= > >=  << >>  + -  * / %  ** (возведение в степень)
+//	унарные + - ! ~,  скобки,  числа (10, 0x1f, 017) и имена переменных
+type arithParser struct {
+	shell *Shell
+	src   string
+	pos   int
+}
+
+// errDivByZero - ошибка деления на ноль
+var errDivByZero = errors.New("деление на ноль")
+
+// arithSubst вычисляет $((expr)); параметры и команды в выражении подставляются заранее
+func (s *Shell) arithSubst(expr string) string {
+	value, err := s.evalArith(s.expandString(expr))
+	if err != nil {
+		s.expandFailed(fmt.Errorf("%s: %v", strings.TrimSpace(expr), err))
+		return ""
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+// evalArith вычисляет арифметическое выражение
+func (s *Shell) evalArith(expr string) (int64, error) {
+	p := &arithParser{shell: s, src: expr}
+	p.skipSpaces()
+	if p.pos == len(p.src) {
+		return 0, nil // Пустое выражение равно нулю
+	}
+
+	value, err := p.parseAssign()
+	if err != nil {
+		return 0, err
+	}
+	if p.skipSpaces(); p.pos < len(p.src) {
+		return 0, p.errorf("неожиданный символ %q", p.src[p.pos:p.pos+1])
+	}
+	return value, nil
+}
+
+// errorf создает ошибку разбора выражения
+func (p *arithParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("ошибка в выражении: "+format, args...)
+}
+
+// skipSpaces пропускает пробельные символы
+func (p *arithParser) skipSpaces() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// peekOp возвращает оператор в текущей позиции (или "", если там не оператор)
+func (p *arithParser) peekOp() string {
+	p.skipSpaces()
+	for _, op := range arithOps {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// acceptOp пропускает оператор, если он один из ops, и возвращает его
+func (p *arithParser) acceptOp(ops ...string) (string, bool) {
+	op := p.peekOp()
+	for _, want := range ops {
+		if op == want {
+			p.pos += len(op)
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// readName читает имя переменной в текущей позиции
+func (p *arithParser) readName() string {
+	p.skipSpaces()
+	start := p.pos
+	for p.pos < len(p.src) && isNameChar(p.src[p.pos], p.pos == start) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// parseAssign разбирает присваивание NAME op= expr или тернарный оператор
+func (p *arithParser) parseAssign() (int64, error) {
+	start := p.pos
+	if name := p.readName(); name != "" {
+		if op, ok := p.acceptOp("=", "+=", "-=", "*=", "/=", "%=", "<<=", ">>="); ok {
+			value, err := p.parseAssign()
+			if err != nil {
+				return 0, err
+			}
+			if op != "=" {
+				current, err := p.variable(name)
+				if err != nil {
+					return 0, err
+				}
+				if value, err = applyArith(strings.TrimSuffix(op, "="), current, value); err != nil {
+					return 0, err
+				}
+			}
+			p.shell.setVar(name, strconv.FormatInt(value, 10))
+			return value, nil
+		}
+	}
+	p.pos = start
+	return p.parseTernary()
+}
+
+// parseTernary разбирает cond ? a : b
+func (p *arithParser) parseTernary() (int64, error) {
+	cond, err := p.parseBinary(0)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := p.acceptOp("?"); !ok {
+		return cond, nil
+	}
+
+	a, err := p.parseAssign()
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := p.acceptOp(":"); !ok {
+		return 0, p.errorf("ожидается ':'")
+	}
+	b, err := p.parseTernary()
+	if err != nil {
+		return 0, err
+	}
+	if cond != 0 {
+		return a, nil
+	}
+	return b, nil
+}
+
+// binaryLevels - бинарные операторы по возрастанию приоритета
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"|"},
+	{"^"},
+	{"&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// parseBinary разбирает левоассоциативные бинарные операторы уровня level и выше
+func (p *arithParser) parseBinary(level int) (int64, error) {
+	if level == len(binaryLevels) {
+		return p.parsePower()
+	}
+
+	left, err := p.parseBinary(level + 1)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op, ok := p.acceptOp(binaryLevels[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseBinary(level + 1)
+		if err != nil {
+			return 0, err
+		}
+		if left, err = applyArith(op, left, right); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// parsePower разбирает правоассоциативное возведение в степень
+func (p *arithParser) parsePower() (int64, error) {
+	base, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := p.acceptOp("**"); !ok {
+		return base, nil
+	}
+	exp, err := p.parsePower()
+	if err != nil {
+		return 0, err
+	}
+	return applyArith("**", base, exp)
+}
+
+// parseUnary разбирает унарные операторы, скобки, числа и переменные
+func (p *arithParser) parseUnary() (int64, error) {
+	if op, ok := p.acceptOp("+", "-", "!", "~"); ok {
+		value, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "-":
+			return -value, nil
+		case "!":
+			return boolToInt(value == 0), nil
+		case "~":
+			return ^value, nil
+		}
+		return value, nil
+	}
+
+	if _, ok := p.acceptOp("("); ok {
+		value, err := p.parseAssign()
+		if err != nil {
+			return 0, err
+		}
+		if _, ok := p.acceptOp(")"); !ok {
+			return 0, p.errorf("ожидается ')'")
+		}
+		return value, nil
+	}
+
+	p.skipSpaces()
+	if p.pos >= len(p.src) {
+		return 0, p.errorf("неожиданный конец выражения")
+	}
+
+	if c := p.src[p.pos]; c >= '0' && c <= '9' {
+		start := p.pos
+		for p.pos < len(p.src) && isNameChar(p.src[p.pos], false) {
+			p.pos++
+		}
+		value, err := strconv.ParseInt(p.src[start:p.pos], 0, 64)
+		if err != nil {
+			return 0, p.errorf("неверное число %q", p.src[start:p.pos])
+		}
+		return value, nil
+	}
+
+	if name := p.readName(); name != "" {
+		return p.variable(name)
+	}
+	return 0, p.errorf("неожиданный символ %q", p.src[p.pos:p.pos+1])
+}
+
+// variable возвращает числовое значение переменной; пустая или незаданная переменная равна нулю
+func (p *arithParser) variable(name string) (int64, error) {
+	value, _ := p.shell.lookupVar(name)
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		return 0, p.errorf("%s: значение %q не является числом", name, value)
+	}
+	return n, nil
+}
+
+// applyArith применяет бинарный оператор
+func applyArith(op string, a, b int64) (int64, error) {
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/", "%":
+		if b == 0 {
+			return 0, errDivByZero
+		}
+		if op == "/" {
+			return a / b, nil
+		}
+		return a % b, nil
+	case "**":
+		if b < 0 {
+			return 0, errors.New("отрицательная степень")
+		}
+		result := int64(1)
+		for ; b > 0; b >>= 1 {
+			if b&1 == 1 {
+				result *= a
+			}
+			a *= a
+		}
+		return result, nil
+	case "<<":
+		return a << uint64(b&63), nil
+	case ">>":
+		return a >> uint64(b&63), nil
+	case "<":
+		return boolToInt(a < b), nil
+	case "<=":
+		return boolToInt(a <= b), nil
+	case ">":
+		return boolToInt(a > b), nil
+	case ">=":
+		return boolToInt(a >= b), nil
+	case "==":
+		return boolToInt(a == b), nil
+	case "!=":
+		return boolToInt(a != b), nil
+	case "&":
+		return a & b, nil
+	case "^":
+		return a ^ b, nil
+	case "|":
+		return a | b, nil
+	case "&&":
+		return boolToInt(a != 0 && b != 0), nil
+	case "||":
+		return boolToInt(a != 0 || b != 0), nil
+	}
+	return 0, fmt.Errorf("неизвестный оператор %s", op)
+}
+
+// boolToInt переводит логическое значение в 1 или 0
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package shell
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestEvalArith тестирует вычисление арифметических выражений
+func TestEvalArith(t *testing.T) {
+	t.Parallel()
+	shell := NewShell(nil, nil, nil)
+	shell.setVar("n", "6")
+	shell.setVar("empty", "")
+
+	tests := []struct {
+		expr string
+		want int64
+	}{
+		{expr: "1 + 2 * 3", want: 7},
+		{expr: "(1 + 2) * 3", want: 9},
+		{expr: "7 / 2", want: 3},
+		{expr: "-7 % 3", want: -1},
+		{expr: "2 ** 3 ** 2", want: 512},
+		{expr: "1 << 4 | 1", want: 17},
+		{expr: "0x1f + 010", want: 39},
+		{expr: "n * 2", want: 12},
+		{expr: "empty + undefined", want: 0},
+		{expr: "n > 5 && n < 10", want: 1},
+		{expr: "!n || ~0 == -1", want: 1},
+		{expr: "n == 6 ? 100 : 200", want: 100},
+		{expr: "m = n + 1", want: 7},
+		{expr: "m *= 2", want: 14},
+		{expr: "", want: 0},
+	}
+
+	for _, tt := range tests {
+		got, err := shell.evalArith(tt.expr)
+		if err != nil {
+			t.Errorf("evalArith(%q): unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("evalArith(%q): expected %d, got %d", tt.expr, tt.want, got)
+		}
+	}
+	if m, _ := shell.lookupVar("m"); m != "14" {
+		t.Errorf("evalArith: expected m=14 after assignments, got %q", m)
+	}
+
+	for _, expr := range []string{"1 +", "(1", "1 2", "1 / 0", "n % 0", "2 ** -1", "09"} {
+		if _, err := shell.evalArith(expr); err == nil {
+			t.Errorf("evalArith(%q): expected error", expr)
+		}
+	}
+	if _, err := shell.evalArith("1/0"); !errors.Is(err, errDivByZero) {
+		t.Errorf("evalArith: expected division by zero, got %v", err)
+	}
+}
+
+// TestArithSubstitution тестирует $((...)) в командах
+func TestArithSubstitution(t *testing.T) {
+	t.Parallel()
+	result, errOut, shell := runScriptStderr(t, "i=1\necho $((i + 1)) \"$(( $(echo 2) * 3 ))\"\ntrue $((i += 10)); echo $i\necho $((1 / 0)) never\n")
+
+	want := "2 6\n11\n"
+	if result != want {
+		t.Errorf("arithmetic: expected %q, got %q", want, result)
+	}
+	if errOut != "ошибка: 1 / 0: деление на ноль\n" {
+		t.Errorf("arithmetic: unexpected error output %q", errOut)
+	}
+	if shell.status != 1 {
+		t.Errorf("arithmetic: expected status 1 after error, got %d", shell.status)
+	}
+}
@@ -0,0 +1,152 @@
+package shell
+
+// Синтаксическое дерево командной строки.
+//
+// Строка "a | b > out && c; d" разбирается так:
+//
+//	List
+//	├── AndOr: Pipeline(a | b > out) && Pipeline(c)
+//	└── AndOr: Pipeline(d)
+//
+// Составные команды (if, while, for, case) и определения функций тоже являются
+// командами конвейера (Command) и содержат вложенные списки List
+
+// Word - слово в исходном виде, вместе с кавычками и экранированием.
+// Кавычки снимаются и переменные подставляются при раскрытии (expandWord),
+// непосредственно перед выполнением команды
+type Word struct {
+	Raw string
+	Pos int // Смещение слова в исходной строке
+}
+
+// IORedirect - перенаправление ввода-вывода команды:
+//
+//	[n]< file   [n]> file   [n]>> file   &> file   &>> file
+//	[n]<&m      [n]>&m      [n]>&-       (дублирование и закрытие дескриптора)
+//	[n]<< END   [n]<<- END  [n]<<< word  (here-document и here-string)
+type IORedirect struct {
+	Fd      int      // Перенаправляемый дескриптор (по умолчанию 0 для ввода и 1 для вывода)
+	Op      string   // Оператор без номера дескриптора
+	Target  Word     // Имя файла, номер дескриптора, ограничитель here-document или слово here-string
+	HereDoc *HereDoc // Тело here-document для << и <<-
+}
+
+// HereDoc - here-document: строки после команды до строки-ограничителя
+type HereDoc struct {
+	Delim     string // Ограничитель без кавычек
+	Quoted    bool   // Ограничитель был в кавычках: тело не раскрывается
+	StripTabs bool   // <<-: начальные табуляции строк тела и ограничителя удаляются
+	Body      string
+}
+
+// Command - узел, который может быть элементом конвейера
+type Command interface {
+	commandNode()
+}
+
+// SimpleCommand - простая команда: присваивания, имя, аргументы и перенаправления
+type SimpleCommand struct {
+	Assigns   []Word // Присваивания NAME=value перед именем команды
+	Args      []Word
+	Redirects []*IORedirect
+}
+
+func (*SimpleCommand) commandNode() {}
+
+// Pipeline - команды, соединенные через |
+type Pipeline struct {
+	Commands []Command
+	Negated  bool   // Конвейер начинается с !: код завершения инвертируется
+	Text     string // Исходный текст конвейера (для вывода jobs)
+}
+
+// AndOr - цепочка конвейеров, соединенных через && и ||.
+// Ops[i] связывает Pipelines[i] и Pipelines[i+1]
+type AndOr struct {
+	Pipelines  []*Pipeline
+	Ops        []string
+	Background bool   // Цепочка завершается & и выполняется в фоне
+	Text       string // Исходный текст цепочки (для вывода jobs)
+}
+
+// List - последовательность цепочек, разделенных ;, & или переводом строки.
+// Это и вся программа, и тело составной команды
+type List struct {
+	Items []*AndOr
+}
+
+// IfClause - условие if/elif/else.
+// Conds[i] и Bodies[i] - условие и тело ветки if (i = 0) или elif (i > 0)
+type IfClause struct {
+	Conds  []*List
+	Bodies []*List
+	Else   *List // Ветка else; nil, если ее нет
+}
+
+func (*IfClause) commandNode() {}
+
+// WhileClause - цикл while (или until, если Until = true)
+type WhileClause struct {
+	Cond  *List
+	Body  *List
+	Until bool // until: выполнять тело, пока условие не станет истинным
+}
+
+func (*WhileClause) commandNode() {}
+
+// ForClause - цикл for NAME in WORDS; do BODY; done.
+// Без in цикл перебирает позиционные параметры ("$@")
+type ForClause struct {
+	Var   string
+	Words []Word
+	Body  *List
+}
+
+func (*ForClause) commandNode() {}
+
+// CaseClause - выбор case WORD in PATTERN) LIST ;; ... esac
+type CaseClause struct {
+	Word  Word
+	Items []*CaseItem
+}
+
+func (*CaseClause) commandNode() {}
+
+// CaseItem - ветка case: шаблоны через | и команды, выполняемые при совпадении
+type CaseItem struct {
+	Patterns []Word
+	Body     *List
+}
+
+// BraceGroup - группа команд { LIST; }, выполняемая в самом shell'е.
+// Перенаправление или труба после группы действуют на вывод всех ее команд
+type BraceGroup struct {
+	Body *List
+}
+
+func (*BraceGroup) commandNode() {}
+
+// Subshell - команды в круглых скобках ( LIST ), выполняемые в копии shell'а:
+// cd, присваивания и определения функций внутри не меняют сам shell
+type Subshell struct {
+	Body *List
+}
+
+func (*Subshell) commandNode() {}
+
+// Redirected - составная команда с перенаправлениями, которые действуют
+// на все команды внутри нее: { ...; } > file, while ...; done < file
+type Redirected struct {
+	Command   Command
+	Redirects []*IORedirect
+}
+
+func (*Redirected) commandNode() {}
+
+// FuncDef - определение функции NAME() { LIST; }
+type FuncDef struct {
+	Name string
+	Body *List
+}
+
+func (*FuncDef) commandNode() {}
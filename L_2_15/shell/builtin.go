@@ -0,0 +1,195 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// Builtin - встроенная команда shell'а. Run получает аргументы (args[0] - имя команды),
+// потоки ввода-вывода и shell, в котором выполняется команда, и возвращает код завершения.
+// В конвейере встроенная команда выполняется в горутине с копией shell'а,
+// поэтому ее изменения (cd, export) не действуют на сам shell - как и в bash
+type Builtin interface {
+	Run(s *Shell, args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+// BuiltinFunc позволяет использовать функцию как Builtin
+type BuiltinFunc func(s *Shell, args []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+// Run вызывает f
+func (f BuiltinFunc) Run(s *Shell, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	return f(s, args, stdin, stdout, stderr)
+}
+
+// defaultBuiltins возвращает встроенные команды, доступные в каждом новом shell'е
+func defaultBuiltins() map[string]Builtin {
+	return map[string]Builtin{
+		"cd":       BuiltinFunc((*Shell).builtinCd),
+		"pwd":      BuiltinFunc((*Shell).builtinPwd),
+		"pushd":    BuiltinFunc((*Shell).builtinPushd),
+		"popd":     BuiltinFunc((*Shell).builtinPopd),
+		"dirs":     BuiltinFunc((*Shell).builtinDirs),
+		"echo":     BuiltinFunc((*Shell).builtinEcho),
+		"ps":       BuiltinFunc((*Shell).builtinPs),
+		"kill":     BuiltinFunc((*Shell).builtinKill),
+		"exit":     BuiltinFunc((*Shell).builtinExit),
+		"jobs":     BuiltinFunc((*Shell).builtinJobs),
+		"fg":       BuiltinFunc((*Shell).builtinFg),
+		"bg":       BuiltinFunc((*Shell).builtinBg),
+		"wait":     BuiltinFunc((*Shell).builtinWait),
+		"export":   BuiltinFunc((*Shell).builtinExport),
+		"unset":    BuiltinFunc((*Shell).builtinUnset),
+		"set":      BuiltinFunc((*Shell).builtinSet),
+		"shift":    BuiltinFunc((*Shell).builtinShift),
+		"read":     BuiltinFunc((*Shell).builtinRead),
+		"return":   BuiltinFunc((*Shell).builtinReturn),
+		"break":    BuiltinFunc((*Shell).builtinLoopControl),
+		"continue": BuiltinFunc((*Shell).builtinLoopControl),
+		"local":    BuiltinFunc((*Shell).builtinLocal),
+		"history":  BuiltinFunc((*Shell).builtinHistory),
+		"alias":    BuiltinFunc((*Shell).builtinAlias),
+		"unalias":  BuiltinFunc((*Shell).builtinUnalias),
+		"source":   BuiltinFunc((*Shell).builtinSource),
+		".":        BuiltinFunc((*Shell).builtinSource),
+		"type":     BuiltinFunc((*Shell).builtinType),
+		"which":    BuiltinFunc((*Shell).builtinWhich),
+		"command":  BuiltinFunc((*Shell).builtinCommand),
+		"hash":     BuiltinFunc((*Shell).builtinHash),
+		"true":     BuiltinFunc(builtinStatus(0)),
+		":":        BuiltinFunc(builtinStatus(0)),
+		"false":    BuiltinFunc(builtinStatus(1)),
+	}
+}
+
+// RegisterBuiltin добавляет встроенную команду или заменяет существующую;
+// b == nil удаляет команду, и имя снова ищется среди функций и в $PATH
+func (s *Shell) RegisterBuiltin(name string, b Builtin) {
+	if b == nil {
+		delete(s.builtins, name)
+		return
+	}
+	s.builtins[name] = b
+}
+
+// cloneBuiltins копирует таблицу встроенных команд для копии shell'а
+func (s *Shell) cloneBuiltins() map[string]Builtin {
+	return maps.Clone(s.builtins)
+}
+
+// builtinStatus возвращает команду, которая ничего не делает и завершается с кодом code (true, false, :)
+func builtinStatus(code int) func(*Shell, []string, io.Reader, io.Writer, io.Writer) int {
+	return func(*Shell, []string, io.Reader, io.Writer, io.Writer) int {
+		return code
+	}
+}
+
+// builtinPwd выводит текущую директорию
+func (s *Shell) builtinPwd(_ []string, _ io.Reader, stdout, _ io.Writer) int {
+	fmt.Fprintln(stdout, s.cwd)
+	return 0
+}
+
+// builtinEcho выводит аргументы через пробел.
+// -n - без перевода строки в конце, -e - с обработкой \n, \t и других escape-последовательностей,
+// -E - без обработки (по умолчанию). Флаги можно объединять: -ne
+func (s *Shell) builtinEcho(args []string, _ io.Reader, stdout, _ io.Writer) int {
+	args = args[1:]
+	newline, escapes := true, false
+
+	for len(args) > 0 && isEchoFlags(args[0]) {
+		for _, flag := range args[0][1:] {
+			switch flag {
+			case 'n':
+				newline = false
+			case 'e':
+				escapes = true
+			case 'E':
+				escapes = false
+			}
+		}
+		args = args[1:]
+	}
+
+	text := strings.Join(args, " ")
+	if escapes {
+		var stop bool
+		text, stop = echoEscapes(text)
+		if stop {
+			newline = false
+		}
+	}
+	if newline {
+		text += "\n"
+	}
+	io.WriteString(stdout, text)
+	return 0
+}
+
+// isEchoFlags сообщает, является ли аргумент флагами echo (только -n, -e, -E)
+func isEchoFlags(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' {
+		return false
+	}
+	return strings.Trim(arg[1:], "neE") == ""
+}
+
+// echoEscapes обрабатывает escape-последовательности echo -e:
+// \a \b \e \f \n \r \t \v \\, \0nnn (восьмеричный код), \xHH (шестнадцатеричный код)
+// и \c (остановить вывод). Возвращает true, если встретилась \c
+func echoEscapes(text string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 >= len(text) {
+			b.WriteByte(text[i])
+			continue
+		}
+
+		i++
+		switch c := text[i]; c {
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'e', 'E':
+			b.WriteByte(0x1b)
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '\\':
+			b.WriteByte('\\')
+		case 'c':
+			return b.String(), true
+		case '0', 'x':
+			// \0nnn - до трех восьмеричных цифр, \xHH - до двух шестнадцатеричных
+			base, size, digits := 8, 3, "01234567"
+			if c == 'x' {
+				base, size, digits = 16, 2, "0123456789abcdefABCDEF"
+			}
+			end := i + 1
+			for end < len(text) && end-i-1 < size && strings.IndexByte(digits, text[end]) >= 0 {
+				end++
+			}
+			if c == 'x' && end == i+1 {
+				b.WriteString(`\x`) // \x без цифр выводится как есть
+				continue
+			}
+			n, _ := strconv.ParseUint("0"+text[i+1:end], base, 8)
+			b.WriteByte(byte(n))
+			i = end - 1
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), false
+}
@@ -0,0 +1,84 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestEchoFlags тестирует флаги -n, -e, -E встроенной команды echo
+func TestEchoFlags(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"echo -n abc", "abc"},
+		{"echo -e 'a\\tb\\nc'", "a\tb\nc\n"},
+		{"echo -E 'a\\tb'", "a\\tb\n"},
+		{"echo 'a\\tb'", "a\\tb\n"},
+		{"echo -ne 'x\\x41\\0102'", "xAB"},
+		{"echo -e 'stop\\chere'", "stop"},
+		{"echo -x -n", "-x -n\n"},
+		{"echo -n -e 'a\\\\b'", "a\\b"},
+	}
+
+	for _, tt := range tests {
+		result, _ := runScript(t, tt.input+"\n")
+		if result != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.want, result)
+		}
+	}
+}
+
+// TestBuiltinsInPipeline тестирует встроенные команды и функции внутри конвейеров
+func TestBuiltinsInPipeline(t *testing.T) {
+	t.Parallel()
+	script := "cd /tmp\n" +
+		"pwd | wc -c\n" +
+		"echo -n abc | tr a-z A-Z; echo\n" +
+		"f() { echo in-f; cat; }\n" +
+		"echo piped | f | tr a-z A-Z\n" +
+		"echo a | echo b | cat\n" +
+		"cd / | cat; pwd\n" +
+		"x=1 | cat; echo x=[$x]\n" +
+		"yes | head -2\n"
+	result, _ := runScript(t, script)
+
+	want := "5\nABC\nIN-F\nPIPED\nb\n/tmp\nx=[]\ny\ny\n"
+	if result != want {
+		t.Errorf("builtins in pipeline: expected %q, got %q", want, result)
+	}
+}
+
+// TestRegisterBuiltin тестирует добавление и удаление встроенной команды
+func TestRegisterBuiltin(t *testing.T) {
+	t.Parallel()
+	output := &bytes.Buffer{}
+	shell := NewShell(bufio.NewReader(strings.NewReader("greet world | tr a-z A-Z\ngreet again\n")), output, output)
+
+	calls := 0
+	shell.RegisterBuiltin("greet", BuiltinFunc(func(s *Shell, args []string, _ io.Reader, stdout, _ io.Writer) int {
+		calls++
+		fmt.Fprintln(stdout, "hello", args[1])
+		return 0
+	}))
+	shell.Run()
+	if got := output.String(); got != "HELLO WORLD\nhello again\n" {
+		t.Errorf("registered builtin: unexpected output %q", got)
+	}
+	if calls != 2 {
+		t.Errorf("registered builtin: expected 2 calls, got %d", calls)
+	}
+
+	shell.RegisterBuiltin("echo", nil)
+	if _, ok := shell.builtins["echo"]; ok {
+		t.Error("RegisterBuiltin(nil): echo is still registered")
+	}
+	if code, ok := shell.executeBuiltin([]string{"echo", "x"}); ok {
+		t.Errorf("RegisterBuiltin(nil): echo still runs as builtin (code %d)", code)
+	}
+}
@@ -0,0 +1,297 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// keywords - зарезервированные слова, которые распознает парсер (для type и command -v)
+var keywords = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true, "fi": true,
+	"while": true, "until": true, "for": true, "in": true, "do": true, "done": true,
+	"case": true, "esac": true, "{": true, "}": true,
+}
+
+// builtinSource выполняет команды из файла в самом shell'е, а не в дочернем процессе,
+// поэтому переменные, функции, алиасы и текущая директория сохраняются:
+//
+//	source файл [аргументы ...]
+//	. файл [аргументы ...]
+//
+// Имя без / ищется в $PATH, затем в текущей директории. Аргументы на время выполнения
+// файла становятся позиционными параметрами. return в файле прекращает его выполнение
+func (s *Shell) builtinSource(args []string, _ io.Reader, _, stderr io.Writer) int {
+	name := args[0]
+	args = args[1:]
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		fmt.Fprintf(stderr, "%s: требуется имя файла\n", name)
+		fmt.Fprintf(stderr, "%s: использование: %s файл [аргументы]\n", name, name)
+		return 2
+	}
+
+	path := args[0]
+	if !strings.Contains(path, "/") {
+		if found := s.searchSourcePath(path); found != "" {
+			path = found
+		}
+	}
+
+	if len(args) > 1 {
+		savedParams := s.params
+		s.params = args[1:]
+		defer func() { s.params = savedParams }()
+	}
+
+	s.status = 0
+	if err := s.runFile(s.resolvePath(path)); err != nil {
+		message := err.Error()
+		if os.IsNotExist(err) {
+			message = "нет такого файла или каталога"
+		}
+		fmt.Fprintf(stderr, "%s: %s: %s\n", name, args[0], message)
+		return 1
+	}
+	return s.status
+}
+
+// searchSourcePath ищет читаемый файл для source в каталогах $PATH ("" - не найден)
+func (s *Shell) searchSourcePath(name string) string {
+	pathVar, _ := s.lookupVar("PATH")
+	for _, dir := range filepath.SplitList(pathVar) {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(s.resolvePath(dir), name)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+	}
+	return ""
+}
+
+// commandKind описывает, чем является имя команды: алиасом, ключевым словом,
+// функцией, встроенной командой или файлом
+type commandKind struct {
+	kind string // alias, keyword, function, builtin, file
+	path string // Путь к файлу или значение алиаса
+}
+
+// describeCommand находит все значения имени в порядке поиска shell'а.
+// Если all == false, возвращается только первое - то, что будет выполнено
+func (s *Shell) describeCommand(name string, all bool) []commandKind {
+	var kinds []commandKind
+	add := func(kind, path string) bool {
+		kinds = append(kinds, commandKind{kind, path})
+		return !all
+	}
+
+	if value, ok := s.aliases[name]; ok && add("alias", value) {
+		return kinds
+	}
+	if keywords[name] && add("keyword", "") {
+		return kinds
+	}
+	if s.funcs[name] != nil && add("function", "") {
+		return kinds
+	}
+	if s.builtins[name] != nil && add("builtin", "") {
+		return kinds
+	}
+
+	if strings.Contains(name, "/") {
+		if checkExecutable(s.resolvePath(name)) == nil {
+			add("file", name)
+		}
+		return kinds
+	}
+	if e, ok := s.hash[name]; ok && !all && checkExecutable(e.path) == nil {
+		return append(kinds, commandKind{"hashed", e.path})
+	}
+	for _, path := range s.searchPath(name, all) {
+		add("file", path)
+	}
+	return kinds
+}
+
+// builtinType сообщает, как shell выполнит каждое имя:
+//
+//	type [-a] [-t | -p | -P] имя ...
+//
+// -t выводит одно слово (alias, keyword, function, builtin, file), -p - путь к файлу,
+// если имя - файл, -P - путь к файлу в $PATH, даже если есть функция или встроенная команда,
+// -a - все значения имени, а не только первое
+func (s *Shell) builtinType(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	args = args[1:]
+	var all, short, pathOnly, forcePath bool
+	for len(args) > 0 && len(args[0]) > 1 && args[0][0] == '-' {
+		if args[0] == "--" {
+			args = args[1:]
+			break
+		}
+		for _, flag := range args[0][1:] {
+			switch flag {
+			case 'a':
+				all = true
+			case 't':
+				short = true
+			case 'p':
+				pathOnly = true
+			case 'P':
+				forcePath = true
+			default:
+				fmt.Fprintf(stderr, "type: -%c: неверный параметр\n", flag)
+				fmt.Fprintln(stderr, "type: использование: type [-afptP] имя [имя ...]")
+				return 2
+			}
+		}
+		args = args[1:]
+	}
+
+	exitCode := 0
+	for _, name := range args {
+		kinds := s.describeCommand(name, all)
+		if forcePath {
+			kinds = nil
+			for _, path := range s.searchPath(name, all) {
+				kinds = append(kinds, commandKind{"file", path})
+			}
+		}
+		if len(kinds) == 0 {
+			if !short && !pathOnly && !forcePath {
+				fmt.Fprintf(stderr, "type: %s: не найден\n", name)
+			}
+			exitCode = 1
+			continue
+		}
+
+		for _, k := range kinds {
+			switch {
+			case short:
+				kind := k.kind
+				if kind == "hashed" {
+					kind = "file"
+				}
+				fmt.Fprintln(stdout, kind)
+			case pathOnly || forcePath:
+				if k.kind == "file" || k.kind == "hashed" {
+					fmt.Fprintln(stdout, k.path)
+				}
+			default:
+				fmt.Fprintln(stdout, describeKind(name, k))
+			}
+		}
+	}
+	return exitCode
+}
+
+// describeKind возвращает описание имени для type
+func describeKind(name string, k commandKind) string {
+	switch k.kind {
+	case "alias":
+		return fmt.Sprintf("%s - алиас для %s", name, singleQuote(k.path))
+	case "keyword":
+		return fmt.Sprintf("%s - ключевое слово shell'а", name)
+	case "function":
+		return fmt.Sprintf("%s - функция", name)
+	case "builtin":
+		return fmt.Sprintf("%s - встроенная команда shell'а", name)
+	case "hashed":
+		return fmt.Sprintf("%s - хэширован (%s)", name, k.path)
+	}
+	return fmt.Sprintf("%s - %s", name, k.path)
+}
+
+// builtinWhich выводит пути к исполняемым файлам команд из $PATH, как which(1):
+//
+//	which [-a] имя ...
+//
+// Функции, алиасы и встроенные команды не учитываются. Код завершения 1,
+// если хотя бы одно имя не найдено
+func (s *Shell) builtinWhich(args []string, _ io.Reader, stdout, _ io.Writer) int {
+	args = args[1:]
+	all := false
+	if len(args) > 0 && args[0] == "-a" {
+		all, args = true, args[1:]
+	}
+
+	exitCode := 0
+	for _, name := range args {
+		var found []string
+		if strings.Contains(name, "/") {
+			if checkExecutable(s.resolvePath(name)) == nil {
+				found = []string{name}
+			}
+		} else {
+			found = s.searchPath(name, all)
+		}
+		if len(found) == 0 {
+			exitCode = 1
+		}
+		for _, path := range found {
+			fmt.Fprintln(stdout, path)
+		}
+	}
+	return exitCode
+}
+
+// builtinCommand выполняет команду, минуя функции с тем же именем, или описывает ее:
+//
+//	command имя [аргументы ...]   выполнить встроенную команду или программу
+//	command -v имя ...           вывести путь к файлу, имя (функция, встроенная команда)
+//	                             или определение алиаса
+//	command -V имя ...           описать имя, как type
+func (s *Shell) builtinCommand(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	args = args[1:]
+	mode := ""
+	for len(args) > 0 && (args[0] == "-v" || args[0] == "-V" || args[0] == "-p") {
+		if args[0] != "-p" {
+			mode = args[0]
+		}
+		args = args[1:]
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return 0
+	}
+
+	switch mode {
+	case "-V":
+		return s.builtinType(append([]string{"type"}, args...), nil, stdout, stderr)
+	case "-v":
+		exitCode := 0
+		for _, name := range args {
+			kinds := s.describeCommand(name, false)
+			if len(kinds) == 0 {
+				exitCode = 1
+				continue
+			}
+			switch k := kinds[0]; k.kind {
+			case "alias":
+				fmt.Fprintf(stdout, "alias %s=%s\n", name, singleQuote(k.path))
+			case "file", "hashed":
+				fmt.Fprintln(stdout, k.path)
+			default:
+				fmt.Fprintln(stdout, name)
+			}
+		}
+		return exitCode
+	}
+
+	if code, ok := s.executeBuiltin(args); ok {
+		return code
+	}
+	job, err := s.startJob([]stage{{args: args, noFunc: true}}, strings.Join(args, " "), true)
+	if err != nil {
+		fmt.Fprintf(stderr, "ошибка: %v\n", err)
+		return 1
+	}
+	return s.waitForeground(job)
+}
@@ -0,0 +1,82 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestBuiltinSource тестирует выполнение файла в текущем shell'е
+func TestBuiltinSource(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "lib.sh")
+	os.WriteFile(file, []byte("cd "+dir+"\nX=set\nf() { echo f; }\necho args=$#:$1\nreturn 3\necho not-reached\n"), 0644)
+
+	script := "source " + file + " a b; echo st=$? x=$X params=$#\n" +
+		"cd /\n" +
+		"PATH=" + dir + " . lib.sh; echo st=$?\n" +
+		"pwd\n" +
+		"f\n" +
+		"source /nonexistent 2>/dev/null; echo st=$?\n"
+	result, _ := runScript(t, script)
+
+	want := "args=2:a\nst=3 x=set params=0\nargs=0:\nst=3\n" + dir + "\nf\nst=1\n"
+	if result != want {
+		t.Errorf("source: expected %q, got %q", want, result)
+	}
+}
+
+// TestCommandNotFound тестирует коды завершения 127 и 126 для ненайденных и неисполняемых команд
+func TestCommandNotFound(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "noexec"), []byte("echo\n"), 0644)
+
+	script := "nosuchcommand 2>/dev/null; echo st=$?\n" +
+		dir + "/missing 2>/dev/null; echo st=$?\n" +
+		dir + "/noexec 2>/dev/null; echo st=$?\n" +
+		dir + " 2>/dev/null; echo st=$?\n" +
+		"echo x | nosuchcommand 2>/dev/null | cat; echo ${PIPESTATUS[@]}\n" +
+		"nosuchcommand 2>&1\n"
+	result, _ := runScript(t, script)
+
+	want := "st=127\nst=127\nst=126\nst=126\n0 127 0\nnosuchcommand: команда не найдена\n"
+	if result != want {
+		t.Errorf("not found: expected %q, got %q", want, result)
+	}
+}
+
+// TestBuiltinType тестирует type, command -v и which
+func TestBuiltinType(t *testing.T) {
+	t.Parallel()
+	catPath := strings.Join(NewShell(nil, nil, nil).searchPath("cat", false), "")
+	if catPath == "" {
+		t.Skip("cat not found")
+	}
+
+	script := "alias ll='ls -l'\n" +
+		"f() { echo f; }\n" +
+		"type ll if f cd cat\n" +
+		"type nosuch 2>/dev/null; echo st=$?\n" +
+		"type -t ll if f cd cat\n" +
+		"command -v ll f cd cat nosuch; echo st=$?\n" +
+		"which cat nosuch; echo st=$?\n" +
+		"cat() { echo function; }\n" +
+		"cat </dev/null; command cat </dev/null; echo st=$?\n" +
+		"command cd /; pwd\n"
+	result, _ := runScript(t, script)
+
+	want := "ll - алиас для 'ls -l'\nif - ключевое слово shell'а\nf - функция\n" +
+		"cd - встроенная команда shell'а\ncat - " + catPath + "\n" +
+		"st=1\n" +
+		"alias\nkeyword\nfunction\nbuiltin\nfile\n" +
+		"alias ll='ls -l'\nf\ncd\n" + catPath + "\nst=1\n" +
+		catPath + "\nst=1\n" +
+		"function\nst=0\n" +
+		"/\n"
+	if result != want {
+		t.Errorf("type: expected %q, got %q", want, result)
+	}
+}
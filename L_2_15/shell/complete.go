@@ -0,0 +1,119 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// complete возвращает варианты дополнения слова перед курсором и позицию начала этого слова.
+// Первое слово команды дополняется встроенными командами, функциями и программами из $PATH,
+// остальные слова (и слова с /) - путями к файлам относительно текущей директории shell'а
+func (s *Shell) complete(line []rune, pos int) (int, []string) {
+	start := pos
+	for start > 0 && (!strings.ContainsRune(" \t|&;<>()", line[start-1]) || start > 1 && line[start-2] == '\\') {
+		start--
+	}
+	word := string(line[start:pos])
+
+	// Слово в начале команды: до него только пробелы или оператор
+	before := strings.TrimRight(string(line[:start]), " \t")
+	commandPos := before == "" || strings.ContainsRune("|&;(", rune(before[len(before)-1]))
+
+	var candidates []string
+	if commandPos && !strings.Contains(word, "/") {
+		candidates = s.completeCommand(word)
+	} else {
+		candidates = s.completePath(word)
+	}
+	return start, candidates
+}
+
+// completeCommand возвращает встроенные команды, функции и программы из $PATH, начинающиеся с prefix
+func (s *Shell) completeCommand(prefix string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	add := func(name string) {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			result = append(result, name+" ")
+		}
+	}
+
+	for name := range s.builtins {
+		add(name)
+	}
+	for name := range s.funcs {
+		add(name)
+	}
+
+	path, _ := s.lookupVar("PATH")
+	for _, dir := range filepath.SplitList(path) {
+		entries, err := os.ReadDir(s.resolvePath(dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !strings.HasPrefix(entry.Name(), prefix) || seen[entry.Name()] {
+				continue
+			}
+			if info, err := os.Stat(filepath.Join(s.resolvePath(dir), entry.Name())); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+				add(entry.Name())
+			}
+		}
+	}
+
+	slices.Sort(result)
+	return result
+}
+
+// completePath возвращает пути к файлам, начинающиеся с word.
+// Директории дополняются символом /, файлы - пробелом; ~ в начале слова сохраняется
+func (s *Shell) completePath(word string) []string {
+	dir, base := "", word
+	if i := strings.LastIndexByte(word, '/'); i >= 0 {
+		dir, base = word[:i+1], word[i+1:]
+	}
+	base = unescapePattern(base)
+
+	lookup := dir
+	if strings.HasPrefix(lookup, "~") {
+		lookup = s.homeDir() + strings.TrimPrefix(lookup, "~")
+	}
+	if lookup == "" {
+		lookup = "."
+	}
+
+	entries, err := os.ReadDir(s.resolvePath(lookup))
+	if err != nil {
+		return nil
+	}
+
+	var result []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		suffix := " "
+		if info, err := os.Stat(filepath.Join(s.resolvePath(lookup), name)); err == nil && info.IsDir() {
+			suffix = "/"
+		}
+		result = append(result, dir+escapeCompletion(name)+suffix)
+	}
+	slices.Sort(result)
+	return result
+}
+
+// escapeCompletion экранирует в имени файла пробелы и символы, особые для shell'а
+func escapeCompletion(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(" \t\\'\"|&;<>()$`*?[#~{}!", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,336 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// executeCompound выполняет составную команду или определение функции
+func (s *Shell) executeCompound(cmd Command) int {
+	switch c := cmd.(type) {
+	case *IfClause:
+		return s.executeIf(c)
+	case *WhileClause:
+		return s.executeWhile(c)
+	case *ForClause:
+		return s.executeFor(c)
+	case *CaseClause:
+		return s.executeCase(c)
+	case *FuncDef:
+		s.funcs[c.Name] = c
+		return 0
+	case *BraceGroup:
+		return s.executeList(c.Body)
+	case *Subshell:
+		return s.executeSubshell(c)
+	case *Redirected:
+		redirects, err := s.resolveRedirects(c.Redirects)
+		if err != nil {
+			fmt.Fprintf(s.errWriter, "ошибка: %v\n", err)
+			return 1
+		}
+		return s.withRedirects(redirects, func() int {
+			return s.executeCompound(c.Command)
+		})
+	}
+	return 0
+}
+
+// executeSubshell выполняет ( ... ) в копии shell'а: exit, cd и присваивания внутри скобок
+// действуют только на копию. Команды копии - задания самого shell'а: при управлении
+// заданиями они получают свою группу процессов и терминал, а остановленные Ctrl+Z
+// переходят в таблицу заданий shell'а, и их можно продолжить fg
+func (s *Shell) executeSubshell(c *Subshell) int {
+	sub := s.subshell()
+	sub.jobControl, sub.terminal, sub.shellPgid = s.jobControl, s.terminal, s.shellPgid
+	sub.jobs = slices.Clone(s.jobs) // номера новых заданий продолжают номера shell'а
+	exitCode := sub.executeList(c.Body)
+
+	for _, job := range sub.jobs {
+		if job.state == jobStopped && !slices.Contains(s.jobs, job) {
+			s.jobs = append(s.jobs, job)
+		}
+	}
+	return exitCode
+}
+
+// interrupted сообщает, что выполнение списка нужно прервать:
+// после exit, return, break или continue, после отмены контекста Exec и Ctrl+C
+func (s *Shell) interrupted() bool {
+	return s.exit || s.returning || s.breakN > 0 || s.continueN > 0 || s.aborted()
+}
+
+// aborted сообщает, что выполнение прервано извне: отменен контекст Exec или нажат Ctrl+C
+func (s *Shell) aborted() bool {
+	return s.cancelled() || s.sigint.Load()
+}
+
+// cancelled сообщает, что контекст выполняемого Exec отменен
+func (s *Shell) cancelled() bool {
+	return s.ctx != nil && s.ctx.Err() != nil
+}
+
+// executeIf выполняет первую ветку, условие которой завершилось успешно
+func (s *Shell) executeIf(c *IfClause) int {
+	for i, cond := range c.Conds {
+		exitCode := s.withoutErrexit(func() int { return s.executeList(cond) })
+		if s.interrupted() {
+			return exitCode
+		}
+		if exitCode == 0 {
+			return s.executeList(c.Bodies[i])
+		}
+	}
+
+	if c.Else != nil {
+		return s.executeList(c.Else)
+	}
+	return 0
+}
+
+// loopDone обрабатывает break и continue после очередной итерации цикла.
+// Возвращает true, если цикл нужно завершить
+func (s *Shell) loopDone() bool {
+	if s.breakN > 0 {
+		s.breakN--
+		return true
+	}
+	if s.continueN > 0 {
+		// continue N продолжает N-й внешний цикл, а внутренние завершает
+		s.continueN--
+		return s.continueN > 0
+	}
+	return s.exit || s.returning || s.aborted()
+}
+
+// executeWhile выполняет тело цикла, пока условие успешно (для until - пока неуспешно)
+func (s *Shell) executeWhile(c *WhileClause) int {
+	s.loopDepth++
+	defer func() { s.loopDepth-- }()
+
+	exitCode := 0
+	for {
+		condCode := s.withoutErrexit(func() int { return s.executeList(c.Cond) })
+		if s.interrupted() {
+			if s.loopDone() {
+				break
+			}
+			continue
+		}
+		if (condCode == 0) == c.Until {
+			break
+		}
+
+		exitCode = s.executeList(c.Body)
+		if s.loopDone() {
+			break
+		}
+	}
+	return exitCode
+}
+
+// executeFor присваивает переменной цикла каждое слово по очереди и выполняет тело
+func (s *Shell) executeFor(c *ForClause) int {
+	s.loopDepth++
+	defer func() { s.loopDepth-- }()
+
+	s.expandErr = nil
+	values := s.expandWords(c.Words)
+	if s.expandErr != nil {
+		fmt.Fprintf(s.errWriter, "ошибка: %v\n", s.expandErr)
+		return 1
+	}
+
+	exitCode := 0
+	for _, value := range values {
+		s.setVar(c.Var, value)
+		exitCode = s.executeList(c.Body)
+		if s.loopDone() {
+			break
+		}
+	}
+	return exitCode
+}
+
+// executeCase выполняет ветку, один из шаблонов которой совпадает со словом
+func (s *Shell) executeCase(c *CaseClause) int {
+	word := s.expandString(c.Word.Raw)
+
+	for _, item := range c.Items {
+		for _, pattern := range item.Patterns {
+			if matchPattern(s.expandPattern(pattern.Raw), word) {
+				return s.executeList(item.Body)
+			}
+		}
+	}
+	return 0
+}
+
+// matchPattern сопоставляет строку с шаблоном shell'а целиком:
+// * - любая строка, ? - любой символ, [...] - класс символов ([!...] - отрицание),
+// \x - символ x как есть
+func matchPattern(pattern, str string) bool {
+	re, err := regexp.Compile("^(?s:" + patternToRegexp(pattern) + ")$")
+	if err != nil {
+		return pattern == str
+	}
+	return re.MatchString(str)
+}
+
+// patternToRegexp переводит шаблон shell'а в регулярное выражение
+func patternToRegexp(pattern string) string {
+	var re strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			} else {
+				re.WriteString(`\\`)
+			}
+		case '[':
+			end := classEnd(pattern, i)
+			if end < 0 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : end]
+			if class[0] == '!' {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + strings.ReplaceAll(class, `[`, `\[`) + "]")
+			i = end
+		default:
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	return re.String()
+}
+
+// classEnd возвращает индекс ], закрывающей класс символов, который начинается в позиции i.
+// ] сразу после [ или [! входит в класс
+func classEnd(pattern string, i int) int {
+	j := i + 1
+	if j < len(pattern) && pattern[j] == '!' {
+		j++
+	}
+	if j < len(pattern) && pattern[j] == ']' {
+		j++
+	}
+	for ; j < len(pattern); j++ {
+		if pattern[j] == ']' {
+			return j
+		}
+	}
+	return -1
+}
+
+// callFunction вызывает функцию: аргументы становятся позиционными параметрами
+// на время ее выполнения, а переменные, объявленные через local, восстанавливаются после
+func (s *Shell) callFunction(fn *FuncDef, args []string) int {
+	savedParams, savedLoopDepth := s.params, s.loopDepth
+	s.params = args[1:]
+	s.loopDepth = 0 // break и continue в функции не действуют на циклы вызывающего кода
+	s.locals = append(s.locals, make(map[string]*variable))
+
+	defer func() {
+		frame := s.locals[len(s.locals)-1]
+		s.locals = s.locals[:len(s.locals)-1]
+		for name, v := range frame {
+			if v == nil {
+				delete(s.vars, name)
+			} else {
+				s.vars[name] = v
+			}
+		}
+		s.params, s.loopDepth = savedParams, savedLoopDepth
+		s.returning = false
+	}()
+
+	return s.executeList(fn.Body)
+}
+
+// builtinReturn завершает функцию с кодом из аргумента или кодом последней команды
+func (s *Shell) builtinReturn(args []string, _ io.Reader, _, stderr io.Writer) int {
+	args = args[1:]
+	if len(s.locals) == 0 && s.sourceDepth == 0 {
+		fmt.Fprintln(stderr, "return: можно использовать только в функции или в файле, выполняемом source")
+		return 1
+	}
+
+	code := s.status
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Fprintf(stderr, "return: %s: требуется числовой аргумент\n", args[0])
+			n = 2
+		}
+		code = n & 0xff
+	}
+
+	s.returning = true
+	return code
+}
+
+// builtinLoopControl выполняет break и continue: прерывает N вложенных циклов
+// (по умолчанию один) или переходит к следующей итерации N-го из них
+func (s *Shell) builtinLoopControl(args []string, _ io.Reader, _, stderr io.Writer) int {
+	name, args := args[0], args[1:]
+	if s.loopDepth == 0 {
+		fmt.Fprintf(stderr, "%s: имеет смысл только в цикле\n", name)
+		return 0
+	}
+
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+			fmt.Fprintf(stderr, "%s: %s: требуется положительное число\n", name, args[0])
+			return 1
+		}
+	}
+	n = min(n, s.loopDepth)
+
+	if name == "break" {
+		s.breakN = n
+	} else {
+		s.continueN = n
+	}
+	return 0
+}
+
+// builtinLocal объявляет переменные, видимые только до конца текущей функции
+func (s *Shell) builtinLocal(args []string, _ io.Reader, _, stderr io.Writer) int {
+	args = args[1:]
+	if len(s.locals) == 0 {
+		fmt.Fprintln(stderr, "local: можно использовать только в функции")
+		return 1
+	}
+	frame := s.locals[len(s.locals)-1]
+
+	exitCode := 0
+	for _, arg := range args {
+		name, value, _ := strings.Cut(arg, "=")
+		if !isName(name) {
+			fmt.Fprintf(stderr, "local: '%s': неверный идентификатор\n", arg)
+			exitCode = 1
+			continue
+		}
+
+		// Прежнее значение запоминается один раз, при первом объявлении в функции
+		if _, saved := frame[name]; !saved {
+			frame[name] = s.vars[name]
+		}
+		s.vars[name] = &variable{value: value}
+	}
+	return exitCode
+}
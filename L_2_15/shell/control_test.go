@@ -0,0 +1,252 @@
+package shell
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestParseCompound тестирует разбор составных команд и определений функций
+func TestParseCompound(t *testing.T) {
+	t.Parallel()
+	list, err := Parse("if a; then b; elif c\nthen d; else e; fi\nwhile x; do y; done | sort\nfor v in 1 2; do :; done\ncase $1 in a|b) one;; *) ;; esac\nf() { g; }")
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if len(list.Items) != 5 {
+		t.Fatalf("Parse: expected 5 items, got %d", len(list.Items))
+	}
+
+	ifClause, ok := list.Items[0].Pipelines[0].Commands[0].(*IfClause)
+	if !ok || len(ifClause.Conds) != 2 || ifClause.Else == nil {
+		t.Errorf("if: expected if/elif/else, got %#v", list.Items[0].Pipelines[0].Commands[0])
+	}
+
+	pipeline := list.Items[1].Pipelines[0]
+	if _, ok := pipeline.Commands[0].(*WhileClause); !ok || len(pipeline.Commands) != 2 {
+		t.Errorf("while: expected loop piped into sort, got %#v", pipeline.Commands)
+	}
+
+	forClause, ok := list.Items[2].Pipelines[0].Commands[0].(*ForClause)
+	if !ok || forClause.Var != "v" || len(forClause.Words) != 2 {
+		t.Errorf("for: expected loop over 2 words, got %#v", list.Items[2].Pipelines[0].Commands[0])
+	}
+
+	caseClause, ok := list.Items[3].Pipelines[0].Commands[0].(*CaseClause)
+	if !ok || len(caseClause.Items) != 2 || len(caseClause.Items[0].Patterns) != 2 || len(caseClause.Items[1].Body.Items) != 0 {
+		t.Errorf("case: expected 2 items, got %#v", list.Items[3].Pipelines[0].Commands[0])
+	}
+
+	if fn, ok := list.Items[4].Pipelines[0].Commands[0].(*FuncDef); !ok || fn.Name != "f" {
+		t.Errorf("funcdef: expected function f, got %#v", list.Items[4].Pipelines[0].Commands[0])
+	}
+}
+
+// TestParseCompoundErrors тестирует ошибки в составных командах
+func TestParseCompoundErrors(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+	}{
+		{input: "if true; then echo a", incomplete: true},
+		{input: "while true; do", incomplete: true},
+		{input: "case x in a)", incomplete: true},
+		{input: "f() {", incomplete: true},
+		{input: "echo a \\", incomplete: true},
+		{input: "fi"},
+		{input: "if true; fi"},
+		{input: "for 1 in a; do :; done"},
+		{input: "if; then :; fi"},
+		{input: "{ echo a", incomplete: true},
+		{input: "( echo a", incomplete: true},
+		{input: "{ }"},
+		{input: "( )"},
+		{input: "echo a )"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("Parse(%q): expected SyntaxError, got %v", tt.input, err)
+			continue
+		}
+		if syntaxErr.Incomplete != tt.incomplete {
+			t.Errorf("Parse(%q): expected incomplete=%v, got %v (%v)", tt.input, tt.incomplete, syntaxErr.Incomplete, err)
+		}
+	}
+}
+
+// TestParseGroups тестирует разбор групп { ...; }, подоболочек ( ... ) и перенаправлений составных команд
+func TestParseGroups(t *testing.T) {
+	t.Parallel()
+	list, err := Parse("{ a; b; } > out 2>&1 | c\n(cd /; d) &\nwhile x; do y; done < in")
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+
+	pipeline := list.Items[0].Pipelines[0]
+	redirected, ok := pipeline.Commands[0].(*Redirected)
+	if !ok || len(redirected.Redirects) != 2 || len(pipeline.Commands) != 2 {
+		t.Fatalf("group: expected redirected group piped into c, got %#v", pipeline.Commands)
+	}
+	if group, ok := redirected.Command.(*BraceGroup); !ok || len(group.Body.Items) != 2 {
+		t.Errorf("group: expected 2 commands, got %#v", redirected.Command)
+	}
+
+	if sub, ok := list.Items[1].Pipelines[0].Commands[0].(*Subshell); !ok || len(sub.Body.Items) != 2 || !list.Items[1].Background {
+		t.Errorf("subshell: expected background subshell, got %#v", list.Items[1].Pipelines[0].Commands[0])
+	}
+
+	if r, ok := list.Items[2].Pipelines[0].Commands[0].(*Redirected); !ok || r.Redirects[0].Op != "<" {
+		t.Errorf("while: expected redirected loop, got %#v", list.Items[2].Pipelines[0].Commands[0])
+	}
+}
+
+// TestGroupsAndSubshells тестирует выполнение групп и подоболочек
+func TestGroupsAndSubshells(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	script := "cd " + dir + "\n" +
+		"x=1; (x=2; cd /; echo in $x $PWD; exit 3; echo no); echo st=$? out $x $PWD\n" +
+		"{ x=5; f() { echo f; }; }; echo $x; f\n" +
+		"{ echo a; echo b; } | tr a-z A-Z\n" +
+		"{ echo out; ls /nonexistent; } > log 2>&1; wc -l < log\n" +
+		"for i in 1 2; do echo n$i; done > nums; { cat; echo end; } < nums\n" +
+		"echo x | (cat; echo y) | wc -l\n" +
+		"if true; then echo i1; echo i2; fi | wc -l\n" +
+		"{ echo nested; (echo deep) | cat; } | cat\n" +
+		"{ echo bg; } > bg &\n" +
+		"fg > /dev/null; cat bg\n"
+	result, _ := runScript(t, script)
+
+	want := "in 2 /\nst=3 out 1 " + dir + "\n5\nf\nA\nB\n2\nn1\nn2\nend\n2\n2\nnested\ndeep\nbg\n"
+	if !strings.HasSuffix(result, want) {
+		t.Errorf("groups: expected %q, got %q", want, result)
+	}
+}
+
+// TestControlFlow тестирует выполнение if, циклов, case и функций
+func TestControlFlow(t *testing.T) {
+	t.Parallel()
+	script := `if false; then echo no; elif true; then echo elif; else echo no; fi
+for x in a "b c" d; do
+  if [ "$x" = d ]; then break; fi
+  echo "for:$x"
+done
+i=
+while [ ${#i} -lt 2 ]; do i=${i}x; echo while:$i; done
+until true; do echo never; done
+for w in 1 2 3; do
+  case $w in
+    1|2) continue ;;
+    *) echo case:$w ;;
+  esac
+done
+for a in x y; do for b in 1 2; do [ $b = 2 ] && continue 2; echo $a$b; done; done
+`
+	result, _ := runScript(t, script)
+
+	want := "elif\nfor:a\nfor:b c\nwhile:x\nwhile:xx\ncase:3\nx1\ny1\n"
+	if result != want {
+		t.Errorf("control flow: expected %q, got %q", want, result)
+	}
+}
+
+// TestNegation тестирует инверсию кода завершения конвейера через ! и то, что
+// конвейер с ! не завершает shell при set -e
+func TestNegation(t *testing.T) {
+	t.Parallel()
+	script := `! true; echo $?
+! false; echo $?
+! echo hi | grep -q x && echo negated
+if ! grep -q x /dev/null; then echo not-found; fi
+while ! [ ${#i} -ge 2 ]; do i=${i}x; done; echo $i
+'!' true 2>/dev/null; echo quoted:$?
+set -e
+! true
+! { false; echo in-group; }
+echo survived
+false
+echo not-reached
+`
+	result, shell := runScript(t, script)
+
+	want := "1\n0\nnegated\nnot-found\nxx\nquoted:127\nin-group\nsurvived\n"
+	if result != want || shell.status != 1 {
+		t.Errorf("negation: expected %q, got %q, status %d", want, result, shell.status)
+	}
+
+	if _, err := Parse("! | cat"); err == nil {
+		t.Error("Parse(\"! | cat\"): expected syntax error")
+	}
+}
+
+// TestFunctions тестирует аргументы функций, return и local
+func TestFunctions(t *testing.T) {
+	t.Parallel()
+	script := `greet() {
+  local who=$1
+  echo "hello $who ($#)"
+  return 3
+}
+who=outer
+set -- p1
+greet world x
+echo "status=$? who=$who arg=$1"
+find() { for x in 1 2 3; do [ $x = 2 ] && return 7; done; echo unreachable; }
+find; echo find=$?
+`
+	result, _ := runScript(t, script)
+
+	want := "hello world (2)\nstatus=3 who=outer arg=p1\nfind=7\n"
+	if result != want {
+		t.Errorf("functions: expected %q, got %q", want, result)
+	}
+}
+
+// TestMultilineInput тестирует продолжение команды на следующих строках
+func TestMultilineInput(t *testing.T) {
+	t.Parallel()
+	result, errOut, shell := runScriptStderr(t, "echo a \\\n  b\necho 'c\nd'\ntrue &&\n  echo e\nif true\nthen\n  echo f\nfi\necho line $UNSET\nif true; then\n")
+
+	if result != "a b\nc\nd\ne\nf\nline\n" {
+		t.Errorf("multiline: unexpected output %q", result)
+	}
+	if !strings.Contains(errOut, "синтаксическая ошибка (13:1): неожиданный конец ввода") {
+		t.Errorf("multiline: expected error at end of input, got %q", errOut)
+	}
+	if shell.status != 2 {
+		t.Errorf("multiline: expected status 2, got %d", shell.status)
+	}
+}
+
+// TestMatchPattern тестирует сопоставление с шаблонами case
+func TestMatchPattern(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		pattern, str string
+		want         bool
+	}{
+		{pattern: "*", str: "a/b", want: true},
+		{pattern: "a?c", str: "abc", want: true},
+		{pattern: "a?c", str: "ac", want: false},
+		{pattern: "[a-c]x", str: "bx", want: true},
+		{pattern: "[!a-c]x", str: "bx", want: false},
+		{pattern: `a\*`, str: "a*", want: true},
+		{pattern: `a\*`, str: "ab", want: false},
+		{pattern: "*.go", str: "main.go", want: true},
+		{pattern: "[", str: "[", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := matchPattern(tt.pattern, tt.str); got != tt.want {
+			t.Errorf("matchPattern(%q, %q): expected %v, got %v", tt.pattern, tt.str, tt.want, got)
+		}
+	}
+
+	shell := NewShell(nil, nil, nil)
+	if got := shell.expandPattern(`"a*"*`); got != `a\**` {
+		t.Errorf("expandPattern: expected quoted star to be escaped, got %q", got)
+	}
+}
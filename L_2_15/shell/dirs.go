@@ -0,0 +1,192 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Стек каталогов pushd/popd/dirs: его вершина - текущая директория (s.cwd),
+// остальные элементы хранятся в s.dirStack
+
+// dirsEntries возвращает стек каталогов целиком, начиная с текущей директории
+func (s *Shell) dirsEntries() []string {
+	return append([]string{s.cwd}, s.dirStack...)
+}
+
+// stackIndex переводит +N (N-й элемент слева, с нуля) или -N (N-й справа)
+// в индекс стека из n элементов
+func stackIndex(arg string, n int) (int, bool) {
+	if len(arg) < 2 || (arg[0] != '+' && arg[0] != '-') {
+		return 0, false
+	}
+	k, err := strconv.Atoi(arg[1:])
+	if err != nil || k < 0 || k >= n {
+		return 0, false
+	}
+	if arg[0] == '-' {
+		k = n - 1 - k
+	}
+	return k, true
+}
+
+// isStackIndex сообщает, похож ли аргумент на +N или -N
+func isStackIndex(arg string) bool {
+	if len(arg) < 2 || (arg[0] != '+' && arg[0] != '-') {
+		return false
+	}
+	_, err := strconv.Atoi(arg[1:])
+	return err == nil
+}
+
+// builtinPushd добавляет директорию в стек и переходит в нее:
+//
+//	pushd каталог   перейти в каталог, запомнив текущую директорию
+//	pushd           поменять местами две верхние директории
+//	pushd +N | -N   повернуть стек так, чтобы N-я директория стала текущей
+//
+// После перехода выводит стек, как dirs
+func (s *Shell) builtinPushd(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	args = args[1:]
+	entries := s.dirsEntries()
+
+	var target string
+	var rest []string
+	switch {
+	case len(args) == 0:
+		if len(s.dirStack) == 0 {
+			fmt.Fprintln(stderr, "pushd: нет другого каталога")
+			return 1
+		}
+		target, rest = s.dirStack[0], append([]string{s.cwd}, s.dirStack[1:]...)
+	case isStackIndex(args[0]):
+		k, ok := stackIndex(args[0], len(entries))
+		if !ok {
+			fmt.Fprintf(stderr, "pushd: %s: неверный номер в стеке каталогов\n", args[0])
+			return 1
+		}
+		rotated := append(entries[k:], entries[:k]...)
+		target, rest = rotated[0], rotated[1:]
+	default:
+		target, rest = args[0], entries
+	}
+
+	if err := s.changeDir(target); err != nil {
+		fmt.Fprintf(stderr, "pushd: %v\n", err)
+		return 1
+	}
+	s.dirStack = rest
+	s.printDirs(stdout, false, false)
+	return 0
+}
+
+// builtinPopd удаляет директорию из стека:
+//
+//	popd           перейти в следующую директорию стека, убрав текущую
+//	popd +N | -N   убрать N-ю директорию, не меняя текущую (+0 - как popd)
+//
+// После изменения выводит стек, как dirs
+func (s *Shell) builtinPopd(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	args = args[1:]
+	if len(s.dirStack) == 0 {
+		fmt.Fprintln(stderr, "popd: стек каталогов пуст")
+		return 1
+	}
+
+	k := 0
+	if len(args) > 0 {
+		var ok bool
+		if k, ok = stackIndex(args[0], len(s.dirStack)+1); !ok {
+			fmt.Fprintf(stderr, "popd: %s: неверный номер в стеке каталогов\n", args[0])
+			return 1
+		}
+	}
+
+	if k == 0 {
+		if err := s.changeDir(s.dirStack[0]); err != nil {
+			fmt.Fprintf(stderr, "popd: %v\n", err)
+			return 1
+		}
+		s.dirStack = s.dirStack[1:]
+	} else {
+		s.dirStack = append(s.dirStack[:k-1:k-1], s.dirStack[k:]...)
+	}
+	s.printDirs(stdout, false, false)
+	return 0
+}
+
+// builtinDirs выводит стек каталогов, начиная с текущей директории:
+//
+//	dirs [-c] [-l] [-p] [-v] [+N | -N]
+//
+// -c очищает стек, -l выводит полные пути (без ~), -p - по одному в строке,
+// -v - по одному в строке с номерами, +N / -N - только N-й элемент
+func (s *Shell) builtinDirs(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	args = args[1:]
+	var long, numbered bool
+	perLine := false
+	for _, arg := range args {
+		if isStackIndex(arg) {
+			entries := s.dirsEntries()
+			k, ok := stackIndex(arg, len(entries))
+			if !ok {
+				fmt.Fprintf(stderr, "dirs: %s: неверный номер в стеке каталогов\n", arg)
+				return 1
+			}
+			fmt.Fprintln(stdout, s.formatDir(entries[k], long))
+			return 0
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			fmt.Fprintf(stderr, "dirs: %s: неверный аргумент\n", arg)
+			return 2
+		}
+		for _, flag := range arg[1:] {
+			switch flag {
+			case 'c':
+				s.dirStack = nil
+				return 0
+			case 'l':
+				long = true
+			case 'p':
+				perLine = true
+			case 'v':
+				perLine, numbered = true, true
+			default:
+				fmt.Fprintf(stderr, "dirs: -%c: неверный параметр\n", flag)
+				fmt.Fprintln(stderr, "dirs: использование: dirs [-clpv] [+N] [-N]")
+				return 2
+			}
+		}
+	}
+
+	if numbered {
+		for i, dir := range s.dirsEntries() {
+			fmt.Fprintf(stdout, "%2d  %s\n", i, s.formatDir(dir, long))
+		}
+		return 0
+	}
+	s.printDirs(stdout, long, perLine)
+	return 0
+}
+
+// printDirs выводит стек каталогов в одну строку через пробел или по одному в строке
+func (s *Shell) printDirs(w io.Writer, long, perLine bool) {
+	var dirs []string
+	for _, dir := range s.dirsEntries() {
+		dirs = append(dirs, s.formatDir(dir, long))
+	}
+	sep := " "
+	if perLine {
+		sep = "\n"
+	}
+	fmt.Fprintln(w, strings.Join(dirs, sep))
+}
+
+// formatDir сокращает домашнюю директорию в пути до ~, если не задан long
+func (s *Shell) formatDir(dir string, long bool) string {
+	if long {
+		return dir
+	}
+	return s.tildePath(dir)
+}
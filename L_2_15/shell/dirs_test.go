@@ -0,0 +1,48 @@
+package shell
+
+import "testing"
+
+// TestCdOldpwd тестирует cd - и $OLDPWD
+func TestCdOldpwd(t *testing.T) {
+	t.Parallel()
+	script := "unset OLDPWD\n" +
+		"cd - 2>/dev/null; echo st=$?\n" +
+		"cd /tmp; cd /\n" +
+		"cd -; echo $PWD $OLDPWD\n" +
+		"cd -\n"
+	result, _ := runScript(t, script)
+
+	want := "st=1\n/tmp\n/tmp /\n/\n"
+	if result != want {
+		t.Errorf("cd -: expected %q, got %q", want, result)
+	}
+}
+
+// TestDirStack тестирует pushd, popd и dirs
+func TestDirStack(t *testing.T) {
+	t.Parallel()
+	script := "HOME=/home/nobody; cd /\n" +
+		"pushd /usr; pushd /etc\n" +
+		"pushd\n" +
+		"dirs -v\n" +
+		"pushd +2\n" +
+		"popd +1\n" +
+		"pushd /nonexistent 2>/dev/null; echo st=$?\n" +
+		"popd\n" +
+		"popd 2>/dev/null; echo st=$? $PWD\n" +
+		"pushd /tmp >/dev/null; dirs -p; dirs +1; dirs -c; dirs\n"
+	result, _ := runScript(t, script)
+
+	want := "/usr /\n/etc /usr /\n" +
+		"/usr /etc /\n" +
+		" 0  /usr\n 1  /etc\n 2  /\n" +
+		"/ /usr /etc\n" +
+		"/ /etc\n" +
+		"st=1\n" +
+		"/etc\n" +
+		"st=1 /etc\n" +
+		"/tmp\n/etc\n/etc\n/tmp\n"
+	if result != want {
+		t.Errorf("dirs: expected %q, got %q", want, result)
+	}
+}
@@ -0,0 +1,502 @@
+package shell
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// errInterrupted - ввод строки прерван по Ctrl+C
+var errInterrupted = errors.New("ввод прерван")
+
+// lineEditor - редактор строки для интерактивного режима в терминале.
+// Терминал переводится в посимвольный режим только на время чтения строки.
+// Клавиши - как в emacs (и в bash):
+//
+//	Ctrl+A/Ctrl+E, Home/End        в начало/конец строки
+//	Ctrl+B/Ctrl+F, стрелки         на символ влево/вправо
+//	Alt+B/Alt+F                    на слово влево/вправо
+//	Backspace, Ctrl+D, Delete      удалить символ (Ctrl+D в пустой строке - конец ввода)
+//	Ctrl+K, Ctrl+U, Ctrl+W, Alt+D  вырезать до конца/начала строки, слово назад/вперед
+//	Ctrl+Y                         вставить вырезанное
+//	Ctrl+P/Ctrl+N, стрелки         предыдущая/следующая команда истории
+//	Ctrl+R                         поиск по истории
+//	Tab                            дополнение команды или имени файла
+//	Ctrl+L                         очистить экран
+//	Ctrl+C                         отменить ввод
+type lineEditor struct {
+	fd       int // Дескриптор терминала (-1 - не переключать режим терминала)
+	in       *bufio.Reader
+	out      io.Writer
+	history  *History
+	complete func(line []rune, pos int) (int, []string)
+
+	prompt    string // Последняя строка приглашения
+	line      []rune
+	pos       int    // Позиция курсора в line
+	cursorRow int    // На сколько строк экрана курсор ниже начала приглашения
+	killed    []rune // Вырезанный текст для Ctrl+Y
+}
+
+// newLineEditor создает редактор, читающий нажатия клавиш из in и выводящий строку в out
+func newLineEditor(fd int, in io.Reader, out io.Writer, history *History) *lineEditor {
+	return &lineEditor{fd: fd, in: bufio.NewReader(in), out: out, history: history}
+}
+
+// ctrl возвращает код клавиши Ctrl+c
+func ctrl(c byte) rune {
+	return rune(c & 0x1f)
+}
+
+// ReadLine выводит приглашение и читает строку. Как и bufio.Reader.ReadString,
+// возвращает строку с завершающим \n; io.EOF - по Ctrl+D в пустой строке
+func (ed *lineEditor) ReadLine(prompt string) (string, error) {
+	if ed.fd >= 0 {
+		if saved, err := getTermios(ed.fd); err == nil {
+			setTermios(ed.fd, rawTermios(saved))
+			defer setTermios(ed.fd, saved)
+		}
+	}
+
+	// Многострочное приглашение выводится один раз, перерисовывается только последняя строка
+	if i := strings.LastIndexByte(prompt, '\n'); i >= 0 {
+		fmt.Fprint(ed.out, prompt[:i+1])
+		prompt = prompt[i+1:]
+	}
+	ed.prompt = prompt
+	ed.line = nil
+	ed.pos = 0
+	ed.cursorRow = 0
+	ed.refresh()
+
+	histIndex, draft := ed.historyLen(), []rune(nil)
+	pending, hasPending := rune(0), false
+
+	for {
+		r := pending
+		if hasPending {
+			hasPending = false
+		} else {
+			var err error
+			if r, _, err = ed.in.ReadRune(); err != nil {
+				ed.finish()
+				if len(ed.line) > 0 {
+					return string(ed.line), err
+				}
+				return "", err
+			}
+		}
+
+		switch r {
+		case '\r', '\n':
+			ed.finish()
+			return string(ed.line) + "\n", nil
+
+		case ctrl('C'):
+			ed.pos = len(ed.line)
+			ed.refresh()
+			fmt.Fprint(ed.out, "^C\n")
+			return "", errInterrupted
+
+		case ctrl('D'):
+			if len(ed.line) == 0 {
+				return "", io.EOF
+			}
+			ed.deleteRange(ed.pos, ed.pos+1)
+
+		case ctrl('A'):
+			ed.pos = 0
+		case ctrl('E'):
+			ed.pos = len(ed.line)
+		case ctrl('B'):
+			ed.pos = max(ed.pos-1, 0)
+		case ctrl('F'):
+			ed.pos = min(ed.pos+1, len(ed.line))
+
+		case 127, ctrl('H'):
+			if ed.pos > 0 {
+				ed.deleteRange(ed.pos-1, ed.pos)
+			}
+
+		case ctrl('K'):
+			ed.kill(ed.pos, len(ed.line))
+		case ctrl('U'):
+			ed.kill(0, ed.pos)
+		case ctrl('W'):
+			ed.kill(ed.wordStart(), ed.pos)
+		case ctrl('Y'):
+			ed.insert(ed.killed...)
+
+		case ctrl('L'):
+			fmt.Fprint(ed.out, "\x1b[H\x1b[2J")
+			ed.cursorRow = 0
+
+		case ctrl('P'), ctrl('N'):
+			histIndex, draft = ed.moveHistory(r == ctrl('P'), histIndex, draft)
+
+		case ctrl('R'):
+			pending, hasPending = ed.search()
+
+		case '\t':
+			ed.completeWord()
+
+		case 27: // ESC: стрелки, Home/End, Delete и сочетания с Alt
+			switch key := ed.readEscape(); key {
+			case "A":
+				histIndex, draft = ed.moveHistory(true, histIndex, draft)
+			case "B":
+				histIndex, draft = ed.moveHistory(false, histIndex, draft)
+			case "C":
+				ed.pos = min(ed.pos+1, len(ed.line))
+			case "D":
+				ed.pos = max(ed.pos-1, 0)
+			case "H", "1~", "7~":
+				ed.pos = 0
+			case "F", "4~", "8~":
+				ed.pos = len(ed.line)
+			case "3~":
+				ed.deleteRange(ed.pos, ed.pos+1)
+			case "b":
+				ed.pos = ed.wordStart()
+			case "f":
+				ed.pos = ed.wordEnd()
+			case "d":
+				ed.kill(ed.pos, ed.wordEnd())
+			case "\x7f":
+				ed.kill(ed.wordStart(), ed.pos)
+			}
+
+		default:
+			if unicode.IsPrint(r) {
+				ed.insert(r)
+			}
+		}
+
+		ed.refresh()
+	}
+}
+
+// readEscape читает escape-последовательность после ESC: "A" для ESC [ A, "3~" для ESC [ 3 ~,
+// "b" для Alt+B (ESC b)
+func (ed *lineEditor) readEscape() string {
+	r, _, err := ed.in.ReadRune()
+	if err != nil {
+		return ""
+	}
+	if r != '[' && r != 'O' {
+		return string(r)
+	}
+
+	var seq strings.Builder
+	for {
+		c, _, err := ed.in.ReadRune()
+		if err != nil {
+			return ""
+		}
+		seq.WriteRune(c)
+		// Последовательность заканчивается символом из диапазона @..~
+		if c >= '@' && c <= '~' {
+			return seq.String()
+		}
+	}
+}
+
+// insert вставляет символы в позицию курсора
+func (ed *lineEditor) insert(runes ...rune) {
+	ed.line = append(ed.line[:ed.pos], append(runes, ed.line[ed.pos:]...)...)
+	ed.pos += len(runes)
+}
+
+// deleteRange удаляет символы line[from:to]
+func (ed *lineEditor) deleteRange(from, to int) {
+	to = min(to, len(ed.line))
+	if from >= to {
+		return
+	}
+	ed.line = append(ed.line[:from], ed.line[to:]...)
+	if ed.pos > to {
+		ed.pos -= to - from
+	} else if ed.pos > from {
+		ed.pos = from
+	}
+}
+
+// kill вырезает символы line[from:to] для последующей вставки по Ctrl+Y
+func (ed *lineEditor) kill(from, to int) {
+	if from >= to {
+		return
+	}
+	ed.killed = append([]rune(nil), ed.line[from:to]...)
+	ed.deleteRange(from, to)
+}
+
+// wordStart возвращает начало слова слева от курсора
+func (ed *lineEditor) wordStart() int {
+	i := ed.pos
+	for i > 0 && unicode.IsSpace(ed.line[i-1]) {
+		i--
+	}
+	for i > 0 && !unicode.IsSpace(ed.line[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordEnd возвращает конец слова справа от курсора
+func (ed *lineEditor) wordEnd() int {
+	i := ed.pos
+	for i < len(ed.line) && unicode.IsSpace(ed.line[i]) {
+		i++
+	}
+	for i < len(ed.line) && !unicode.IsSpace(ed.line[i]) {
+		i++
+	}
+	return i
+}
+
+// historyLen возвращает количество команд в истории
+func (ed *lineEditor) historyLen() int {
+	if ed.history == nil {
+		return 0
+	}
+	return ed.history.Len()
+}
+
+// moveHistory заменяет строку предыдущей (older) или следующей командой истории.
+// Набранная, но не выполненная строка сохраняется в draft и возвращается после последней команды
+func (ed *lineEditor) moveHistory(older bool, index int, draft []rune) (int, []rune) {
+	n := ed.historyLen()
+	if older && index == 0 || !older && index >= n {
+		return index, draft
+	}
+	if index == n {
+		draft = append([]rune(nil), ed.line...)
+	}
+
+	if older {
+		index--
+	} else {
+		index++
+	}
+	if index == n {
+		ed.line = draft
+	} else {
+		ed.line = []rune(ed.history.Entry(index))
+	}
+	ed.pos = len(ed.line)
+	return index, draft
+}
+
+// search выполняет поиск по истории (Ctrl+R): набранный текст ищется от новых команд к старым,
+// повторное Ctrl+R ищет следующее совпадение. Enter или любая клавиша редактирования принимает
+// найденную команду; эта клавиша возвращается для обработки. Ctrl+G и Ctrl+C отменяют поиск
+func (ed *lineEditor) search() (rune, bool) {
+	prompt, original := ed.prompt, append([]rune(nil), ed.line...)
+	defer func() { ed.prompt = prompt }()
+
+	var query []rune
+	index := ed.historyLen()
+	failed := false
+
+	find := func(from int) {
+		for k := min(from, ed.historyLen()-1); k >= 0; k-- {
+			entry := ed.history.Entry(k)
+			if at := strings.Index(entry, string(query)); at >= 0 {
+				index, failed = k, false
+				ed.line = []rune(entry)
+				ed.pos = len([]rune(entry[:at]))
+				return
+			}
+		}
+		failed = true
+	}
+
+	for {
+		status := "reverse-i-search"
+		if failed {
+			status = "failed reverse-i-search"
+		}
+		ed.prompt = fmt.Sprintf("(%s)`%s': ", status, string(query))
+		ed.refresh()
+
+		r, _, err := ed.in.ReadRune()
+		if err != nil {
+			return 0, false
+		}
+
+		switch {
+		case r == ctrl('R'):
+			if len(query) > 0 {
+				find(index - 1)
+			}
+		case r == ctrl('G') || r == ctrl('C'):
+			ed.line, ed.pos = original, len(original)
+			return 0, false
+		case r == 127 || r == ctrl('H'):
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(ed.historyLen() - 1)
+			}
+		case unicode.IsPrint(r):
+			query = append(query, r)
+			find(index)
+		default:
+			ed.prompt = prompt
+			return r, true
+		}
+	}
+}
+
+// completeWord дополняет слово перед курсором. Единственный вариант подставляется целиком,
+// из нескольких - их общее начало; если дополнить нечего, варианты выводятся списком
+func (ed *lineEditor) completeWord() {
+	if ed.complete == nil {
+		return
+	}
+	start, candidates := ed.complete(ed.line, ed.pos)
+	if len(candidates) == 0 {
+		fmt.Fprint(ed.out, "\a")
+		return
+	}
+
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		prefix = commonPrefix(prefix, c)
+	}
+	if len(candidates) == 1 || len([]rune(prefix)) > ed.pos-start {
+		ed.deleteRange(start, ed.pos)
+		ed.insert([]rune(prefix)...)
+		return
+	}
+
+	// Варианты выводятся под строкой ввода по столбцам, затем строка рисуется заново
+	ed.pos = len(ed.line)
+	ed.refresh()
+	fmt.Fprint(ed.out, "\n")
+	ed.printColumns(candidates)
+	ed.cursorRow = 0
+}
+
+// printColumns выводит варианты дополнения по столбцам (без пути к директории)
+func (ed *lineEditor) printColumns(candidates []string) {
+	names := make([]string, len(candidates))
+	width := 0
+	for i, c := range candidates {
+		name := strings.TrimSuffix(c, " ")
+		if j := strings.LastIndexByte(strings.TrimSuffix(name, "/"), '/'); j >= 0 {
+			name = name[j+1:]
+		}
+		names[i] = name
+		width = max(width, len([]rune(name))+2)
+	}
+
+	cols := max(ed.width()/width, 1)
+	rows := (len(names) + cols - 1) / cols
+	for row := 0; row < rows; row++ {
+		var b strings.Builder
+		for col := 0; col < cols; col++ {
+			if i := col*rows + row; i < len(names) {
+				b.WriteString(names[i])
+				b.WriteString(strings.Repeat(" ", width-len([]rune(names[i]))))
+			}
+		}
+		fmt.Fprintln(ed.out, strings.TrimRight(b.String(), " "))
+	}
+}
+
+// commonPrefix возвращает общее начало двух строк
+func commonPrefix(a, b string) string {
+	ra, rb := []rune(a), []rune(b)
+	n := 0
+	for n < len(ra) && n < len(rb) && ra[n] == rb[n] {
+		n++
+	}
+	return string(ra[:n])
+}
+
+// width возвращает ширину терминала
+func (ed *lineEditor) width() int {
+	if ed.fd < 0 {
+		return 80
+	}
+	return terminalWidth(ed.fd)
+}
+
+// layout возвращает строку и столбец экрана (относительно начала приглашения),
+// в которых окажется курсор после вывода text
+func layout(text []rune, width int) (row, col int) {
+	for _, r := range text {
+		if r == '\n' {
+			row, col = row+1, 0
+			continue
+		}
+		if col++; col == width {
+			row, col = row+1, 0
+		}
+	}
+	return row, col
+}
+
+// refresh перерисовывает приглашение и строку и ставит курсор в позицию pos.
+// Длинная строка может занимать несколько строк экрана: курсор сначала
+// возвращается к началу приглашения, затем все выводится заново
+func (ed *lineEditor) refresh() {
+	width := ed.width()
+	prompt := []rune(stripEscapes(ed.prompt))
+
+	var b strings.Builder
+	if ed.cursorRow > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", ed.cursorRow)
+	}
+	b.WriteString("\r\x1b[J")
+	b.WriteString(ed.prompt)
+	b.WriteString(strings.ReplaceAll(string(ed.line), "\n", "\r\n"))
+
+	full := append(append([]rune(nil), prompt...), ed.line...)
+	endRow, endCol := layout(full, width)
+	if endCol == 0 && len(full) > 0 && full[len(full)-1] != '\n' {
+		// Курсор в последнем столбце ждет следующего символа: переводим его на новую строку явно
+		b.WriteString("\r\n")
+	}
+
+	row, col := layout(full[:len(prompt)+ed.pos], width)
+	if endRow > row {
+		fmt.Fprintf(&b, "\x1b[%dA", endRow-row)
+	}
+	b.WriteString("\r")
+	if col > 0 {
+		fmt.Fprintf(&b, "\x1b[%dC", col)
+	}
+	ed.cursorRow = row
+
+	io.WriteString(ed.out, b.String())
+}
+
+// finish переводит курсор в конец строки перед выводом команды или сообщения
+func (ed *lineEditor) finish() {
+	ed.pos = len(ed.line)
+	ed.refresh()
+	fmt.Fprint(ed.out, "\n")
+}
+
+// stripEscapes убирает из текста escape-последовательности (цвета), которые не занимают места на экране
+func stripEscapes(text string) string {
+	if !strings.Contains(text, "\x1b") {
+		return text
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\x1b' && i+1 < len(text) && text[i+1] == '[' {
+			i += 2
+			for i < len(text) && (text[i] < '@' || text[i] > '~') {
+				i++
+			}
+			continue
+		}
+		b.WriteByte(text[i])
+	}
+	return b.String()
+}
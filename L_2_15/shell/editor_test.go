@@ -0,0 +1,101 @@
+package shell
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readLineKeys передает редактору нажатия клавиш и возвращает прочитанную строку
+func readLineKeys(t *testing.T, history *History, keys string) (string, error) {
+	t.Helper()
+	ed := newLineEditor(-1, strings.NewReader(keys), io.Discard, history)
+	return ed.ReadLine("$ ")
+}
+
+// TestLineEditorKeys тестирует клавиши редактирования строки
+func TestLineEditorKeys(t *testing.T) {
+	t.Parallel()
+	history := &History{entries: []string{"first", "second"}}
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{name: "enter", keys: "echo hi\r", want: "echo hi\n"},
+		{name: "arrows", keys: "ac\x1b[Db\x1b[C!\r", want: "abc!\n"},
+		{name: "home and end", keys: "bc\x01a\x05d\r", want: "abcd\n"},
+		{name: "backspace and delete", keys: "abxc\x1b[D\x7f\x1b[3~\r", want: "ab\n"},
+		{name: "kill and yank", keys: "one two\x17\x01\x19 \r", want: "two one \n"},
+		{name: "kill line", keys: "abc\x02\x02\x0b\x15x\r", want: "x\n"},
+		{name: "word motion", keys: "one two three\x1bb\x1bb\x1bd\r", want: "one  three\n"},
+		{name: "history up", keys: "\x1b[A\x1b[A\r", want: "first\n"},
+		{name: "history draft", keys: "new\x10\x0e\r", want: "new\n"},
+		{name: "reverse search", keys: "\x12ir\r", want: "first\n"},
+		{name: "reverse search again", keys: "\x12s\x12\x12\x05!\r", want: "first!\n"},
+		{name: "search cancel", keys: "keep\x12sec\x07\r", want: "keep\n"},
+		{name: "utf-8", keys: "привет\x7f\r", want: "приве\n"},
+	}
+
+	for _, tt := range tests {
+		got, err := readLineKeys(t, history, tt.keys)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	if _, err := readLineKeys(t, history, "\x04"); err != io.EOF {
+		t.Errorf("ctrl+d: expected io.EOF on empty line, got %v", err)
+	}
+	if _, err := readLineKeys(t, history, "abc\x03"); !errors.Is(err, errInterrupted) {
+		t.Errorf("ctrl+c: expected errInterrupted, got %v", err)
+	}
+}
+
+// TestComplete тестирует дополнение команд и имен файлов
+func TestComplete(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	for _, name := range []string{"alpha.txt", "alpine.txt", "my file", "dir/inner", ".hidden", "bin/mytool"} {
+		path := filepath.Join(tmpDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, nil, 0755)
+	}
+
+	shell := NewShell(nil, nil, nil)
+	shell.cwd = tmpDir
+	shell.setVar("PATH", "bin")
+	shell.funcs["myfunc"] = &FuncDef{Name: "myfunc"}
+
+	tests := []struct {
+		line      string
+		wantStart int
+		want      []string
+	}{
+		{line: "cat al", wantStart: 4, want: []string{"alpha.txt ", "alpine.txt "}},
+		{line: "cat d", wantStart: 4, want: []string{"dir/"}},
+		{line: "cat dir/", wantStart: 4, want: []string{"dir/inner "}},
+		{line: "cat my", wantStart: 4, want: []string{`my\ file `}},
+		{line: "cat my\\ f", wantStart: 4, want: []string{`my\ file `}},
+		{line: "cat .h", wantStart: 4, want: []string{".hidden "}},
+		{line: "my", wantStart: 0, want: []string{"myfunc ", "mytool "}},
+		{line: "ls | hist", wantStart: 5, want: []string{"history "}},
+		{line: "./d", wantStart: 0, want: []string{"./dir/"}},
+	}
+
+	for _, tt := range tests {
+		line := []rune(tt.line)
+		start, got := shell.complete(line, len(line))
+		if start != tt.wantStart || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("complete(%q): expected %d %q, got %d %q", tt.line, tt.wantStart, tt.want, start, got)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrExited возвращается Exec после того, как в shell'е выполнена команда exit
+var ErrExited = errors.New("shell завершен командой exit")
+
+// ExitStatus - код завершения команды ($?)
+type ExitStatus int
+
+// Success сообщает, что команда завершилась успешно
+func (st ExitStatus) Success() bool {
+	return st == 0
+}
+
+// Config - настройки shell'а, встроенного в другую программу (New)
+type Config struct {
+	Env      []string           // Окружение "NAME=value" (nil - окружение процесса)
+	Dir      string             // Текущая директория ("" - директория процесса)
+	Stdin    io.Reader          // Ввод команд (nil - пустой ввод)
+	Stdout   io.Writer          // Вывод команд (nil - вывод отбрасывается)
+	Stderr   io.Writer          // Поток ошибок команд и сообщения shell'а (nil - отбрасываются)
+	Builtins map[string]Builtin // Дополнительные встроенные команды; nil удаляет стандартную
+}
+
+// New создает shell для выполнения команд через Exec.
+// В отличие от NewShell, shell не читает команды сам и не трогает stdin, stdout и stderr процесса
+func New(cfg Config) (*Shell, error) {
+	s := NewShell(nil, orDiscard(cfg.Stdout), orDiscard(cfg.Stderr))
+	if cfg.Env != nil {
+		s.vars = importEnviron(cfg.Env)
+	}
+
+	if cfg.Dir != "" {
+		path := s.resolvePath(cfg.Dir)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%s: не директория", cfg.Dir)
+		}
+		s.cwd = path
+	}
+	s.setVar("PWD", s.cwd)
+
+	s.stdin = cfg.Stdin
+	if s.stdin == nil {
+		s.stdin = strings.NewReader("")
+	}
+	for name, b := range cfg.Builtins {
+		s.RegisterBuiltin(name, b)
+	}
+	return s, nil
+}
+
+// Exec выполняет строку команд line (одну или несколько строк) и возвращает код завершения.
+// Переменные, функции, алиасы и текущая директория сохраняются между вызовами.
+//
+// Синтаксическая ошибка возвращается как *SyntaxError, команды при этом не выполняются.
+// При отмене ctx выполняемые задания переднего плана убиваются (SIGKILL всей группе
+// процессов), оставшиеся команды не выполняются, а Exec возвращает ctx.Err().
+// Фоновые задания (&) продолжают работать.
+//
+// Вызовы Exec и Run одного shell'а сериализуются: пока выполняется одна команда,
+// остальные ждут. Встроенные команды не должны вызывать Exec своего shell'а
+func (s *Shell) Exec(ctx context.Context, line string) (ExitStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.exit {
+		return ExitStatus(s.status), ErrExited
+	}
+	if err := ctx.Err(); err != nil {
+		return ExitStatus(s.status), err
+	}
+
+	list, err := s.parse(line)
+	if err != nil {
+		s.status = 2
+		return ExitStatus(s.status), err
+	}
+
+	s.ctx = ctx
+	defer func() { s.ctx = nil }()
+	s.sigint.Store(false)
+	if len(list.Items) > 0 {
+		s.status = s.executeList(list)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return ExitStatus(s.status), err
+	}
+	if s.exit {
+		return ExitStatus(s.status), ErrExited
+	}
+	return ExitStatus(s.status), nil
+}
@@ -0,0 +1,145 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestExec тестирует выполнение команд встроенного shell'а: состояние сохраняется
+// между вызовами, вывод и ошибки разделены, настройки Config применяются
+func TestExec(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+	shell, err := New(Config{
+		Env:    []string{"GREETING=hello", "PATH=/usr/bin:/bin"},
+		Dir:    dir,
+		Stdin:  strings.NewReader("from stdin\n"),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Builtins: map[string]Builtin{
+			"greet": BuiltinFunc(func(_ *Shell, args []string, _ io.Reader, stdout, _ io.Writer) int {
+				fmt.Fprintln(stdout, "greet", strings.Join(args[1:], " "))
+				return 3
+			}),
+			"hash": nil,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	steps := []struct {
+		line string
+		want ExitStatus
+	}{
+		{"x=1; f() { echo f $x $GREETING; }", 0},
+		{"f; cat", 0},
+		{"cd sub 2>/dev/null || mkdir sub && cd sub; pwd", 0},
+		{"greet a b", 3},
+		{"echo $?; nosuchcmd", 127},
+		{"type hash", 1},
+	}
+	for _, step := range steps {
+		status, err := shell.Exec(ctx, step.line)
+		if err != nil || status != step.want {
+			t.Errorf("Exec(%q): expected %d, got %d, %v", step.line, step.want, status, err)
+		}
+	}
+
+	want := "f 1 hello\nfrom stdin\n" + dir + "/sub\ngreet a b\n3\n"
+	if stdout.String() != want {
+		t.Errorf("Exec: expected output %q, got %q", want, stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "nosuchcmd: команда не найдена") {
+		t.Errorf("Exec: expected error in stderr, got %q", stderr.String())
+	}
+
+	var syntaxErr *SyntaxError
+	if status, err := shell.Exec(ctx, "echo | | wc"); !errors.As(err, &syntaxErr) || status != 2 {
+		t.Errorf("Exec: expected syntax error, got %d, %v", status, err)
+	}
+	if status, err := shell.Exec(ctx, "exit 4; echo never"); !errors.Is(err, ErrExited) || status != 4 {
+		t.Errorf("Exec: expected exit 4, got %d, %v", status, err)
+	}
+	if _, err := shell.Exec(ctx, "echo after exit"); !errors.Is(err, ErrExited) {
+		t.Errorf("Exec: expected ErrExited after exit, got %v", err)
+	}
+	if strings.Contains(stdout.String(), "never") || strings.Contains(stdout.String(), "after exit") {
+		t.Errorf("Exec: commands after exit were executed: %q", stdout.String())
+	}
+
+	if _, err := New(Config{Dir: dir + "/missing"}); err == nil {
+		t.Error("New: expected error for missing directory")
+	}
+}
+
+// TestExecCancel тестирует, что отмена контекста убивает задание вместе с процессами,
+// которые оно запустило, и прерывает циклы
+func TestExecCancel(t *testing.T) {
+	t.Parallel()
+	var stdout, stderr bytes.Buffer
+	shell, err := New(Config{Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		// Внук sleep держит трубу вывода открытой: Exec вернется, только если убита вся группа
+		"sh -c 'sleep 10 & sleep 10; echo not killed'",
+		"while true; do :; done; echo not stopped",
+		"for i in 1 2 3; do sleep 10; done; echo not stopped",
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		start := time.Now()
+		_, err := shell.Exec(ctx, line)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Exec(%q): expected deadline exceeded, got %v", line, err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("Exec(%q): cancellation took %v", line, elapsed)
+		}
+	}
+	if stdout.Len() != 0 || stderr.Len() != 0 {
+		t.Errorf("Exec: unexpected output %q, errors %q", stdout.String(), stderr.String())
+	}
+
+	// После отмены shell продолжает работать
+	if status, err := shell.Exec(context.Background(), "echo ok"); status != 0 || err != nil || stdout.String() != "ok\n" {
+		t.Errorf("Exec after cancel: got %d, %v, %q", status, err, stdout.String())
+	}
+}
+
+// TestExecConcurrent тестирует, что одновременные вызовы Exec одного shell'а сериализуются
+func TestExecConcurrent(t *testing.T) {
+	t.Parallel()
+	var stdout bytes.Buffer
+	shell, err := New(Config{Stdout: &stdout})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			shell.Exec(context.Background(), "n=$((n + 1)); echo $n | cat > /dev/null")
+		}()
+	}
+	wg.Wait()
+
+	shell.Exec(context.Background(), "echo $n")
+	if stdout.String() != "20\n" {
+		t.Errorf("concurrent Exec: expected n=20, got %q", stdout.String())
+	}
+}
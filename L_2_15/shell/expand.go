@@ -0,0 +1,639 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// expander раскрывает одно слово: снимает кавычки и экранирование,
+// подставляет переменные и результаты команд, разбивает результат подстановки на поля
+// и раскрывает шаблоны имен файлов
+type expander struct {
+	shell   *Shell
+	fields  []string
+	cur     strings.Builder // Текущее поле
+	pat     strings.Builder // Текущее поле как шаблон: символы в кавычках экранированы
+	hasCur  bool            // Текущее поле начато (нужно, чтобы "" давало пустой аргумент)
+	hasGlob bool            // В текущем поле есть символы шаблона вне кавычек
+	noSplit bool            // Не разбивать подстановки на поля (присваивания, значения по умолчанию)
+	pattern bool            // Результат - шаблон (например, для case), а не строка
+	glob    bool            // Раскрывать шаблоны имен файлов (*, ?, [...])
+}
+
+// expandWords раскрывает список слов в аргументы команды
+func (s *Shell) expandWords(words []Word) []string {
+	var result []string
+	for _, w := range words {
+		result = append(result, s.expandWord(w)...)
+	}
+	return result
+}
+
+// expandWord раскрывает слово в ноль или более полей:
+// фигурные скобки, тильда, подстановки, разбиение на поля и шаблоны имен файлов
+func (s *Shell) expandWord(w Word) []string {
+	var fields []string
+	for _, raw := range braceExpand(w.Raw) {
+		e := &expander{shell: s, glob: true}
+		e.expand(e.expandTilde(raw))
+		e.flush()
+		fields = append(fields, e.fields...)
+	}
+	return fields
+}
+
+// expandString раскрывает текст в одну строку без разбиения на поля.
+// Используется для значений присваиваний NAME=value и слов в ${NAME:-word}
+func (s *Shell) expandString(raw string) string {
+	e := &expander{shell: s, noSplit: true}
+	e.expand(e.expandTilde(raw))
+	e.flush()
+	return strings.Join(e.fields, " ")
+}
+
+// expandPattern раскрывает шаблон (например, в ветке case): результат - одна строка,
+// в которой экранированы символы шаблона, взятые в кавычки или экранированные в исходном слове
+func (s *Shell) expandPattern(raw string) string {
+	e := &expander{shell: s, noSplit: true, pattern: true}
+	e.expand(raw)
+	e.flush()
+	return strings.Join(e.fields, " ")
+}
+
+// expandHeredoc раскрывает тело here-document: подставляются параметры и результаты команд,
+// а \ экранирует только $, `, \ и перевод строки. Кавычки в теле остаются как есть
+func (s *Shell) expandHeredoc(body string) string {
+	var b strings.Builder
+	for i := 0; i < len(body); {
+		switch c := body[i]; c {
+		case '\\':
+			if i+1 < len(body) {
+				switch next := body[i+1]; next {
+				case '$', '`', '\\':
+					b.WriteByte(next)
+					i += 2
+					continue
+				case '\n':
+					i += 2
+					continue
+				}
+			}
+			b.WriteByte(c)
+			i++
+
+		case '$':
+			value, next, ok := s.substitute(body, i)
+			if !ok {
+				b.WriteByte(c)
+				i++
+				continue
+			}
+			b.WriteString(value)
+			i = next
+
+		case '`':
+			value, next, ok := s.backquoteSubst(body, i)
+			if !ok {
+				b.WriteByte(c)
+				i++
+				continue
+			}
+			b.WriteString(value)
+			i = next
+
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// expandTilde раскрывает ~ и ~user в начале слова в домашнюю директорию
+// и возвращает остаток слова. Тильда в кавычках не раскрывается
+func (e *expander) expandTilde(raw string) string {
+	if !strings.HasPrefix(raw, "~") {
+		return raw
+	}
+	end := strings.IndexByte(raw, '/')
+	if end < 0 {
+		end = len(raw)
+	}
+	name := raw[1:end]
+	if strings.ContainsAny(name, "'\"\\$`") {
+		return raw
+	}
+
+	home := e.shell.homeDir()
+	if name != "" {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return raw
+		}
+		home = u.HomeDir
+	}
+
+	// Домашняя директория не разбивается на поля и не считается шаблоном
+	e.addQuoted(home)
+	return raw[end:]
+}
+
+// add добавляет к текущему полю текст вне кавычек: символы шаблона в нем действуют
+func (e *expander) add(text string) {
+	e.cur.WriteString(text)
+	e.pat.WriteString(text)
+	if strings.ContainsAny(text, "*?[") {
+		e.hasGlob = true
+	}
+	e.hasCur = true
+}
+
+// addQuoted добавляет текст, взятый в кавычки или экранированный:
+// в шаблоне он должен совпадать буквально
+func (e *expander) addQuoted(text string) {
+	e.cur.WriteString(text)
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c == '*' || c == '?' || c == '[' || c == '\\' {
+			e.pat.WriteByte('\\')
+		}
+		e.pat.WriteByte(text[i])
+	}
+	e.hasCur = true
+}
+
+// flush завершает текущее поле. Поле с символами шаблона заменяется списком
+// подходящих файлов; если таких нет, поле остается как есть
+func (e *expander) flush() {
+	if !e.hasCur {
+		return
+	}
+
+	switch {
+	case e.pattern:
+		e.fields = append(e.fields, e.pat.String())
+	case e.glob && e.hasGlob:
+		if matches := e.shell.glob(e.pat.String()); len(matches) > 0 {
+			e.fields = append(e.fields, matches...)
+			break
+		}
+		e.fields = append(e.fields, e.cur.String())
+	default:
+		e.fields = append(e.fields, e.cur.String())
+	}
+
+	e.cur.Reset()
+	e.pat.Reset()
+	e.hasCur = false
+	e.hasGlob = false
+}
+
+// addSplit добавляет результат подстановки вне кавычек, разбивая его по пробельным символам
+func (e *expander) addSplit(value string) {
+	if e.noSplit {
+		e.add(value)
+		return
+	}
+	for value != "" {
+		end := strings.IndexAny(value, " \t\n")
+		if end < 0 {
+			e.add(value)
+			return
+		}
+		if end > 0 {
+			e.add(value[:end])
+		}
+		e.flush()
+		value = value[end+1:]
+	}
+}
+
+// expand обрабатывает исходный текст слова
+func (e *expander) expand(raw string) {
+	for i := 0; i < len(raw); {
+		switch c := raw[i]; c {
+		case '\\':
+			// \ + перевод строки - продолжение строки, оно не входит в слово
+			if i+1 < len(raw) && raw[i+1] != '\n' {
+				e.addQuoted(raw[i+1 : i+2])
+			}
+			i += 2
+
+		case '\'':
+			end := strings.IndexByte(raw[i+1:], '\'')
+			e.addQuoted(raw[i+1 : i+1+end])
+			i += end + 2
+
+		case '"':
+			i = e.expandDoubleQuoted(raw, i+1)
+
+		case '$':
+			value, next, ok := e.shell.substitute(raw, i)
+			if !ok {
+				e.add("$")
+				i++
+				continue
+			}
+			e.addSplit(value)
+			i = next
+
+		case '`':
+			value, next, ok := e.shell.backquoteSubst(raw, i)
+			if !ok {
+				e.add("`")
+				i++
+				continue
+			}
+			e.addSplit(value)
+			i = next
+
+		default:
+			e.add(raw[i : i+1])
+			i++
+		}
+	}
+}
+
+// substitute раскрывает подстановку, начинающуюся с $ в позиции i:
+// $((...)) - арифметическое выражение, $(...) - вывод команды, иначе - параметр
+func (s *Shell) substitute(raw string, i int) (value string, next int, ok bool) {
+	if !strings.HasPrefix(raw[i:], "$(") {
+		return s.expandParam(raw, i)
+	}
+
+	l := &Lexer{src: raw, off: i}
+	if err := l.skipParam(); err != nil {
+		return "", i, false
+	}
+	text := raw[i:l.off]
+
+	if strings.HasPrefix(text, "$((") && strings.HasSuffix(text, "))") {
+		return s.arithSubst(text[3 : len(text)-2]), l.off, true
+	}
+	return s.commandSubst(text[2 : len(text)-1]), l.off, true
+}
+
+// backquoteSubst раскрывает подстановку команды `...`, начинающуюся в позиции i.
+// Внутри обратных кавычек \ экранирует только $, ` и \
+func (s *Shell) backquoteSubst(raw string, i int) (value string, next int, ok bool) {
+	l := &Lexer{src: raw, off: i}
+	if err := l.skipBackquote(); err != nil {
+		return "", i, false
+	}
+
+	var src strings.Builder
+	body := raw[i+1 : l.off-1]
+	for k := 0; k < len(body); k++ {
+		if body[k] == '\\' && k+1 < len(body) && strings.IndexByte("$`\\", body[k+1]) >= 0 {
+			k++
+		}
+		src.WriteByte(body[k])
+	}
+	return s.commandSubst(src.String()), l.off, true
+}
+
+// expandDoubleQuoted обрабатывает содержимое двойных кавычек, начиная с позиции i
+// (сразу после открывающей кавычки), и возвращает позицию после закрывающей кавычки.
+// Внутри кавычек подстановки не разбиваются на поля, кроме "$@"
+func (e *expander) expandDoubleQuoted(raw string, i int) int {
+	// Кавычки дают поле, даже пустое (""), но "$@" без параметров не дает ничего
+	onlyEmptyAt := false
+	defer func() {
+		if !onlyEmptyAt {
+			e.hasCur = true
+		}
+	}()
+
+	start := i
+	for i < len(raw) {
+		switch c := raw[i]; c {
+		case '"':
+			return i + 1
+
+		case '\\':
+			// В двойных кавычках \ экранирует только $ ` " \ и перевод строки
+			if i+1 < len(raw) {
+				switch next := raw[i+1]; next {
+				case '$', '`', '"', '\\':
+					e.addQuoted(raw[i+1 : i+2])
+				case '\n':
+				default:
+					e.addQuoted(raw[i : i+2])
+				}
+			}
+			i += 2
+
+		case '$':
+			if next, ok := allParamsAt(raw, i); ok {
+				// "$@": каждый позиционный параметр - отдельное поле
+				params := e.shell.params
+				for k, param := range params {
+					if k > 0 {
+						e.flush()
+					}
+					e.addQuoted(param)
+				}
+				onlyEmptyAt = len(params) == 0 && i == start && next < len(raw) && raw[next] == '"' && !e.hasCur
+				i = next
+				continue
+			}
+
+			value, next, ok := e.shell.substitute(raw, i)
+			if !ok {
+				e.addQuoted("$")
+				i++
+				continue
+			}
+			e.addQuoted(value)
+			i = next
+
+		case '`':
+			value, next, ok := e.shell.backquoteSubst(raw, i)
+			if !ok {
+				e.addQuoted("`")
+				i++
+				continue
+			}
+			e.addQuoted(value)
+			i = next
+
+		default:
+			e.addQuoted(raw[i : i+1])
+			i++
+		}
+	}
+
+	return i
+}
+
+// expandParam раскрывает $NAME, ${...} или специальный параметр ($?, $#, $$, $!, $@, $*, $0..$9),
+// начиная с символа $ в позиции i.
+// Возвращает значение и позицию после подстановки; ok=false, если за $ не следует имя
+func (s *Shell) expandParam(raw string, i int) (value string, next int, ok bool) {
+	if i+1 >= len(raw) {
+		return "", i, false
+	}
+
+	if raw[i+1] == '{' {
+		end := matchBrace(raw, i+1)
+		if end < 0 {
+			return "", i, false
+		}
+		return s.expandBraced(raw[i+2 : end]), end + 1, true
+	}
+
+	// Специальные параметры и позиционные $0..$9 состоят из одного символа
+	if c := raw[i+1]; isSpecialParam(c) || c >= '0' && c <= '9' {
+		value, set := s.lookupParam(raw[i+1 : i+2])
+		if !set {
+			s.unboundParam(raw[i+1 : i+2])
+		}
+		return value, i + 2, true
+	}
+
+	j := i + 1
+	for j < len(raw) && isNameChar(raw[j], j == i+1) {
+		j++
+	}
+	if j == i+1 {
+		return "", i, false
+	}
+	value, set := s.lookupParam(raw[i+1 : j])
+	if !set {
+		s.unboundParam(raw[i+1 : j])
+	}
+	return value, j, true
+}
+
+// unboundParam сообщает о подстановке незаданного параметра при set -u: команда
+// не выполняется, а скрипт, как в bash, завершается. $@ и $* без параметров - не ошибка
+func (s *Shell) unboundParam(name string) {
+	if !s.options["nounset"] || name == "@" || name == "*" {
+		return
+	}
+	s.expandFailed(fmt.Errorf("%s: переменная не задана", name))
+	switch {
+	case s.sourceDepth > 0:
+		s.abortInput = true
+	case !s.interactive:
+		s.exit = true
+	}
+}
+
+// matchBrace возвращает индекс }, парной к { в позиции i, с учетом вложенности и кавычек
+func matchBrace(raw string, i int) int {
+	depth := 0
+	for ; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '\'':
+			end := strings.IndexByte(raw[i+1:], '\'')
+			if end < 0 {
+				return -1
+			}
+			i += end + 1
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// expandBraced раскрывает содержимое ${...}:
+//
+//	${NAME}          значение
+//	${#NAME}         длина значения в символах
+//	${NAME:-word}    word, если NAME не задана или пуста (${NAME-word} - только если не задана)
+//	${NAME:=word}    то же, но еще и присваивает word переменной
+//	${NAME:+word}    word, если NAME задана и не пуста
+//	${NAME[i]}       элемент массива (${NAME[@]} - все элементы, ${#NAME[@]} - их количество)
+func (s *Shell) expandBraced(expr string) string {
+	if len(expr) > 1 && expr[0] == '#' {
+		// ${#NAME[@]} - количество элементов массива
+		if name, ok := strings.CutSuffix(expr[1:], "[@]"); ok || strings.HasSuffix(expr, "[*]") {
+			return strconv.Itoa(len(s.arrayParam(strings.TrimSuffix(name, "[*]"))))
+		}
+		value, set := s.lookupParam(expr[1:])
+		if !set {
+			s.unboundParam(expr[1:])
+		}
+		return strconv.Itoa(utf8.RuneCountInString(value))
+	}
+
+	// Имя: специальный параметр, номер позиционного параметра или имя переменной
+	n := 0
+	switch {
+	case expr != "" && isSpecialParam(expr[0]):
+		n = 1
+	case expr != "" && expr[0] >= '0' && expr[0] <= '9':
+		for n < len(expr) && expr[n] >= '0' && expr[n] <= '9' {
+			n++
+		}
+	default:
+		for n < len(expr) && isNameChar(expr[n], n == 0) {
+			n++
+		}
+	}
+	name, rest := expr[:n], expr[n:]
+	value, set := s.lookupParam(name)
+	if strings.HasPrefix(rest, "[") {
+		if end := strings.IndexByte(rest, ']'); end > 0 {
+			value, set = s.lookupIndexed(name, rest[1:end])
+			rest = rest[end+1:]
+		}
+	}
+
+	if rest == "" {
+		if !set {
+			s.unboundParam(name)
+		}
+		return value
+	}
+
+	// Оператор с двоеточием проверяет и пустое значение, без двоеточия - только наличие
+	colon := strings.HasPrefix(rest, ":")
+	if colon {
+		rest = rest[1:]
+	}
+	if rest == "" {
+		s.expandFailed(fmt.Errorf("${%s}: неверная подстановка", expr))
+		return ""
+	}
+	op, word := rest[0], rest[1:]
+	empty := !set || colon && value == ""
+
+	switch op {
+	case '-':
+		if empty {
+			return s.expandString(word)
+		}
+	case '=':
+		if empty {
+			value = s.expandString(word)
+			if isName(name) {
+				s.setVar(name, value)
+			}
+		}
+	case '+':
+		if empty {
+			return ""
+		}
+		return s.expandString(word)
+	default:
+		// ${NAME#шаблон}, ${NAME/a/b}, ${NAME:?msg} и другие формы не поддерживаются:
+		// команда не выполняется, а не получает значение без изменений
+		s.expandFailed(fmt.Errorf("${%s}: неверная подстановка", expr))
+		return ""
+	}
+	return value
+}
+
+// isSpecialParam сообщает, является ли символ именем специального параметра
+func isSpecialParam(c byte) bool {
+	return c == '?' || c == '#' || c == '@' || c == '*' || c == '$' || c == '!'
+}
+
+// lookupParam возвращает значение параметра (специального, позиционного или переменной shell'а)
+// и признак того, что параметр задан
+func (s *Shell) lookupParam(name string) (string, bool) {
+	switch name {
+	case "?":
+		return strconv.Itoa(s.status), true
+	case "#":
+		return strconv.Itoa(len(s.params)), true
+	case "@", "*":
+		return strings.Join(s.params, " "), true
+	case "0":
+		return s.name, true
+	case "$":
+		return strconv.Itoa(os.Getpid()), true
+	case "!":
+		if s.lastBgPid == 0 {
+			return "", false
+		}
+		return strconv.Itoa(s.lastBgPid), true
+	case "PIPESTATUS":
+		// $PIPESTATUS без индекса - первый элемент
+		return s.lookupIndexed(name, "0")
+	}
+
+	if n, err := strconv.Atoi(name); err == nil && n > 0 {
+		if n <= len(s.params) {
+			return s.params[n-1], true
+		}
+		return "", false
+	}
+
+	return s.lookupVar(name)
+}
+
+// arrayParam возвращает элементы параметра как массива. Массив в shell'е один - $PIPESTATUS;
+// обычная переменная - массив из одного элемента, незаданная - пустой массив
+func (s *Shell) arrayParam(name string) []string {
+	if name == "PIPESTATUS" {
+		values := make([]string, len(s.pipeStatus))
+		for i, code := range s.pipeStatus {
+			values[i] = strconv.Itoa(code)
+		}
+		return values
+	}
+	if value, ok := s.lookupParam(name); ok {
+		return []string{value}
+	}
+	return nil
+}
+
+// lookupIndexed возвращает элемент массива ${NAME[index]}: index - номер (отрицательный - с конца),
+// @ или * - все элементы через пробел
+func (s *Shell) lookupIndexed(name, index string) (string, bool) {
+	values := s.arrayParam(name)
+	if index == "@" || index == "*" {
+		return strings.Join(values, " "), len(values) > 0
+	}
+
+	i, err := strconv.Atoi(s.expandString(index))
+	if err != nil {
+		i = 0
+	}
+	if i < 0 {
+		i += len(values)
+	}
+	if i < 0 || i >= len(values) {
+		return "", false
+	}
+	return values[i], true
+}
+
+// allParamsAt сообщает, начинается ли в позиции i подстановка "$@" (или "${@}"),
+// которая внутри двойных кавычек дает отдельное поле на каждый параметр.
+// Возвращает позицию после подстановки
+func allParamsAt(raw string, i int) (int, bool) {
+	switch {
+	case strings.HasPrefix(raw[i:], "$@"):
+		return i + 2, true
+	case strings.HasPrefix(raw[i:], "${@}"):
+		return i + 4, true
+	}
+	return i, false
+}
+
+// isNameChar сообщает, может ли символ входить в имя переменной
+// (первый символ имени не может быть цифрой)
+func isNameChar(c byte, first bool) bool {
+	switch {
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestCommandSubstitution тестирует $(...) и `...`
+func TestCommandSubstitution(t *testing.T) {
+	t.Parallel()
+	script := "echo $(echo a   b) \"$(echo '  c  ')\"\n" +
+		"x=`echo back`; echo $x\n" +
+		"echo $(echo $(echo nested) `echo \\`echo inner\\``)\n" +
+		"y=$(false); echo status=$?\n" +
+		"z=1; f() { z=2; echo in-f; }; echo $(f) z=$z\n" +
+		"echo \"$(printf 'one\\n\\n')\"end\n"
+	result, _ := runScript(t, script)
+
+	want := "a b   c  \nback\nnested inner\nstatus=1\nin-f z=1\noneend\n"
+	if result != want {
+		t.Errorf("command substitution: expected %q, got %q", want, result)
+	}
+}
+
+// TestGlob тестирует раскрытие шаблонов имен файлов относительно текущей директории shell'а
+func TestGlob(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go", "c.txt", ".hidden.go", "sub/x.go"} {
+		path := filepath.Join(tmpDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, nil, 0644)
+	}
+
+	script := "cd " + tmpDir + "\n" +
+		"echo *.go\necho ?.txt [ab].go\necho sub/*\necho */\necho .*.go\n" +
+		"echo '*.go' \"*\".go \\*.go none*\n" +
+		"for f in *.txt; do echo f=$f; done\n" +
+		"ls *.txt\n"
+	result, _ := runScript(t, script)
+
+	want := "a.go b.go\nc.txt a.go b.go\nsub/x.go\nsub/\n.hidden.go\n*.go *.go *.go none*\nf=c.txt\nc.txt\n"
+	if result != want {
+		t.Errorf("glob: expected %q, got %q", want, result)
+	}
+}
+
+// TestBraceExpand тестирует раскрытие фигурных скобок
+func TestBraceExpand(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{raw: "a{b,c}d", want: []string{"abd", "acd"}},
+		{raw: "{1..3}", want: []string{"1", "2", "3"}},
+		{raw: "{3..1}", want: []string{"3", "2", "1"}},
+		{raw: "{a..c}", want: []string{"a", "b", "c"}},
+		{raw: "{0..10..5}", want: []string{"0", "5", "10"}},
+		{raw: "{a,b}{1,2}", want: []string{"a1", "a2", "b1", "b2"}},
+		{raw: "x{a,b{1,2}}", want: []string{"xa", "xb1", "xb2"}},
+		{raw: "{,a}", want: []string{"", "a"}},
+		{raw: "{single}", want: []string{"{single}"}},
+		{raw: "{}", want: []string{"{}"}},
+		{raw: "'{a,b}'", want: []string{"'{a,b}'"}},
+		{raw: "${x}{a,b}", want: []string{"${x}a", "${x}b"}},
+		{raw: "{a,b", want: []string{"{a,b"}},
+	}
+
+	for _, tt := range tests {
+		if got := braceExpand(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("braceExpand(%q): expected %q, got %q", tt.raw, tt.want, got)
+		}
+	}
+}
+
+// TestTildeExpand тестирует раскрытие ~ во всех словах, а не только в аргументе cd
+func TestTildeExpand(t *testing.T) {
+	t.Parallel()
+	result, _ := runScript(t, "HOME=/home/test\necho ~ ~/dir a~ \"~\" '~/x' x=~\nv=~/bin; echo $v\n")
+
+	want := "/home/test /home/test/dir a~ ~ ~/x x=~\n/home/test/bin\n"
+	if result != want {
+		t.Errorf("tilde: expected %q, got %q", want, result)
+	}
+}
+
+// TestCdKeepsProcessDir тестирует, что cd меняет только директорию shell'а,
+// а внешние команды и редиректы используют ее
+func TestCdKeepsProcessDir(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	before, _ := os.Getwd()
+
+	output := &bytes.Buffer{}
+	shell := NewShell(bufio.NewReader(strings.NewReader("cd "+tmpDir+"\necho data > rel.txt\ncat rel.txt\nsh -c pwd\ncd rel.txt\ncd missing\n")), output, output)
+	shell.Run()
+	result := output.String()
+
+	if after, _ := os.Getwd(); after != before {
+		t.Errorf("cd: process directory changed from %q to %q", before, after)
+	}
+	if shell.cwd != tmpDir {
+		t.Errorf("cd: expected shell directory %q, got %q", tmpDir, shell.cwd)
+	}
+	if !strings.HasPrefix(result, "data\n"+tmpDir+"\ncd: rel.txt: не директория\ncd: ") {
+		t.Errorf("cd: unexpected output %q", result)
+	}
+	if content, _ := os.ReadFile(filepath.Join(tmpDir, "rel.txt")); string(content) != "data\n" {
+		t.Errorf("cd: expected redirect relative to shell directory, got %q", content)
+	}
+}
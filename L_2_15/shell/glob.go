@@ -0,0 +1,253 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// glob возвращает отсортированный список файлов, подходящих под шаблон.
+// Шаблон разбивается на компоненты пути; компоненты с *, ? или [...] сопоставляются
+// с содержимым директорий, остальные добавляются к пути как есть.
+// Относительные пути считаются от текущей директории shell'а, а не процесса
+func (s *Shell) glob(pattern string) []string {
+	parts := strings.Split(pattern, "/")
+	paths := []string{""}
+	if parts[0] == "" {
+		// Абсолютный путь
+		paths = []string{"/"}
+		parts = parts[1:]
+	}
+
+	for _, part := range parts {
+		var next []string
+		for _, dir := range paths {
+			if !hasGlobMeta(part) {
+				next = append(next, joinGlob(dir, unescapePattern(part)))
+				continue
+			}
+
+			entries, err := os.ReadDir(s.resolvePath(dir))
+			if err != nil {
+				continue
+			}
+			for _, entry := range entries {
+				name := entry.Name()
+				// Скрытые файлы подходят, только если шаблон явно начинается с точки
+				if strings.HasPrefix(name, ".") && !strings.HasPrefix(unescapePattern(part), ".") {
+					continue
+				}
+				if matchPattern(part, name) {
+					next = append(next, joinGlob(dir, name))
+				}
+			}
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		paths = next
+	}
+
+	// Компоненты без символов шаблона не проверялись: оставляем только существующие пути
+	var matches []string
+	for _, path := range paths {
+		resolved := s.resolvePath(path)
+		if strings.HasSuffix(path, "/") {
+			resolved += "/" // Шаблон "dir/" подходит только к директориям
+		}
+		if _, err := os.Lstat(resolved); err == nil {
+			matches = append(matches, path)
+		}
+	}
+	slices.Sort(matches)
+	return matches
+}
+
+// joinGlob добавляет имя к пути, найденному glob
+func joinGlob(dir, name string) string {
+	if dir == "" || strings.HasSuffix(dir, "/") {
+		return dir + name
+	}
+	return dir + "/" + name
+}
+
+// hasGlobMeta сообщает, есть ли в шаблоне неэкранированные *, ? или [
+func hasGlobMeta(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+// unescapePattern убирает экранирование из шаблона без символов шаблона
+func unescapePattern(pattern string) string {
+	if !strings.Contains(pattern, "\\") {
+		return pattern
+	}
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+		}
+		b.WriteByte(pattern[i])
+	}
+	return b.String()
+}
+
+// resolvePath возвращает путь относительно текущей директории shell'а
+func (s *Shell) resolvePath(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(s.cwd, name)
+}
+
+// braceExpand раскрывает фигурные скобки в исходном тексте слова:
+// a{b,c}d дает abd и acd, {1..3} - 1 2 3, {a..c} - a b c.
+// Скобки в кавычках, в ${...} и без запятой или диапазона остаются как есть
+func braceExpand(raw string) []string {
+	for i := 0; i < len(raw); {
+		if raw[i] != '{' {
+			i = skipQuoted(raw, i)
+			continue
+		}
+
+		end, commas := scanBrace(raw, i)
+		if end < 0 {
+			break
+		}
+
+		var alternatives []string
+		if len(commas) > 0 {
+			start := i + 1
+			for _, comma := range commas {
+				alternatives = append(alternatives, raw[start:comma])
+				start = comma + 1
+			}
+			alternatives = append(alternatives, raw[start:end])
+		} else {
+			alternatives = braceSequence(raw[i+1 : end])
+		}
+		if alternatives == nil {
+			i++
+			continue
+		}
+
+		// Вложенные скобки и скобки после текущих раскрываются рекурсивно
+		var result []string
+		for _, alt := range alternatives {
+			for _, rest := range braceExpand(alt + raw[end+1:]) {
+				result = append(result, raw[:i]+rest)
+			}
+		}
+		return result
+	}
+	return []string{raw}
+}
+
+// skipQuoted возвращает позицию после символа, кавычек или подстановки, начинающихся в позиции i
+func skipQuoted(raw string, i int) int {
+	l := &Lexer{src: raw, off: i}
+	var err error
+	switch raw[i] {
+	case '\\':
+		l.off += 2
+	case '\'':
+		end := strings.IndexByte(raw[i+1:], '\'')
+		if end < 0 {
+			return len(raw)
+		}
+		l.off += end + 2
+	case '"':
+		err = l.skipDoubleQuoted()
+	case '$':
+		err = l.skipParam()
+	case '`':
+		err = l.skipBackquote()
+	default:
+		l.off++
+	}
+	if err != nil || l.off > len(raw) {
+		return len(raw)
+	}
+	return l.off
+}
+
+// scanBrace находит }, парную к { в позиции i, и запятые между ними на верхнем уровне вложенности.
+// Возвращает -1, если парной скобки нет
+func scanBrace(raw string, i int) (end int, commas []int) {
+	depth := 0
+	for j := i; j < len(raw); {
+		switch raw[j] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return j, commas
+			}
+		case ',':
+			if depth == 1 {
+				commas = append(commas, j)
+			}
+		default:
+			j = skipQuoted(raw, j)
+			continue
+		}
+		j++
+	}
+	return -1, nil
+}
+
+// braceSequence раскрывает диапазон {from..to} или {from..to..step} из чисел или одиночных букв.
+// Возвращает nil, если текст - не диапазон
+func braceSequence(text string) []string {
+	bounds := strings.Split(text, "..")
+	if len(bounds) != 2 && len(bounds) != 3 {
+		return nil
+	}
+
+	step := 1
+	if len(bounds) == 3 {
+		n, err := strconv.Atoi(bounds[2])
+		if err != nil {
+			return nil
+		}
+		step = max(n, -n, 1)
+	}
+
+	from, errFrom := strconv.Atoi(bounds[0])
+	to, errTo := strconv.Atoi(bounds[1])
+	letters := false
+	if errFrom != nil || errTo != nil {
+		if len(bounds[0]) != 1 || len(bounds[1]) != 1 || !isLetter(bounds[0][0]) || !isLetter(bounds[1][0]) {
+			return nil
+		}
+		from, to, letters = int(bounds[0][0]), int(bounds[1][0]), true
+	}
+
+	if to < from {
+		step = -step
+	}
+	var result []string
+	for n := from; step > 0 && n <= to || step < 0 && n >= to; n += step {
+		if letters {
+			result = append(result, string(rune(n)))
+		} else {
+			result = append(result, strconv.Itoa(n))
+		}
+	}
+	return result
+}
+
+// isLetter сообщает, является ли символ латинской буквой
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,169 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Ошибки поиска исполняемого файла команды
+var (
+	errCommandNotFound = errors.New("команда не найдена")
+	errIsDirectory     = errors.New("это каталог")
+)
+
+// hashEntry - команда, найденная в $PATH: путь к файлу и количество запусков
+type hashEntry struct {
+	path string
+	hits int
+}
+
+// lookPath находит исполняемый файл команды name. Имя с / - это путь (относительно
+// текущей директории), иначе файл ищется в каталогах $PATH. Найденный путь запоминается
+// в хэш-таблице, и при следующих запусках $PATH не просматривается
+func (s *Shell) lookPath(name string) (string, error) {
+	if strings.Contains(name, "/") {
+		path := s.resolvePath(name)
+		return path, checkExecutable(path)
+	}
+
+	// Запомненный файл могли удалить: тогда ищем заново
+	if e, ok := s.hash[name]; ok && checkExecutable(e.path) == nil {
+		e.hits++
+		s.hash[name] = e
+		return e.path, nil
+	}
+	found := s.searchPath(name, false)
+	if len(found) == 0 {
+		delete(s.hash, name)
+		return "", errCommandNotFound
+	}
+	s.hash[name] = hashEntry{path: found[0], hits: 1}
+	return found[0], nil
+}
+
+// searchPath ищет исполняемый файл name в каталогах $PATH и возвращает первый найденный
+// или, если all, все найденные. Пустой элемент $PATH - текущая директория
+func (s *Shell) searchPath(name string, all bool) []string {
+	pathVar, _ := s.lookupVar("PATH")
+	var found []string
+	for _, dir := range filepath.SplitList(pathVar) {
+		if dir == "" {
+			dir = "."
+		}
+		path := filepath.Join(s.resolvePath(dir), name)
+		if checkExecutable(path) != nil {
+			continue
+		}
+		found = append(found, path)
+		if !all {
+			break
+		}
+	}
+	return found
+}
+
+// checkExecutable проверяет, что path - исполняемый файл
+func checkExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return errIsDirectory
+	}
+	if info.Mode()&0111 == 0 {
+		return os.ErrPermission
+	}
+	return nil
+}
+
+// commandError возвращает сообщение и код завершения (127 или 126, как в bash)
+// для команды, которую не удалось найти или запустить
+func commandError(name string, err error) (string, int) {
+	switch {
+	case errors.Is(err, errCommandNotFound):
+		return fmt.Sprintf("%s: команда не найдена", name), 127
+	case errors.Is(err, os.ErrNotExist):
+		return fmt.Sprintf("%s: нет такого файла или каталога", name), 127
+	case errors.Is(err, errIsDirectory):
+		return fmt.Sprintf("%s: это каталог", name), 126
+	case errors.Is(err, os.ErrPermission):
+		return fmt.Sprintf("%s: отказано в доступе", name), 126
+	}
+	return fmt.Sprintf("%s: %v", name, err), 126
+}
+
+// builtinHash управляет хэш-таблицей найденных в $PATH команд:
+//
+//	hash             вывести таблицу: количество запусков и путь
+//	hash -r          очистить таблицу
+//	hash -d имя ...  забыть команды
+//	hash -t имя ...  вывести запомненные пути
+//	hash имя ...     найти команды в $PATH и запомнить
+func (s *Shell) builtinHash(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	args = args[1:]
+	mode := ""
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		mode, args = args[0], args[1:]
+	}
+
+	switch mode {
+	case "-r":
+		clear(s.hash)
+		return 0
+	case "", "-d", "-t":
+	default:
+		fmt.Fprintf(stderr, "hash: %s: неверный параметр\n", mode)
+		fmt.Fprintln(stderr, "hash: использование: hash [-r] [-d | -t] [имя ...]")
+		return 2
+	}
+
+	if len(args) == 0 {
+		if mode != "" {
+			fmt.Fprintf(stderr, "hash: %s: требуется аргумент\n", mode)
+			return 2
+		}
+		if len(s.hash) == 0 {
+			fmt.Fprintln(stdout, "hash: хэш-таблица пуста")
+			return 0
+		}
+		fmt.Fprintln(stdout, "запусков\tкоманда")
+		for _, name := range slices.Sorted(maps.Keys(s.hash)) {
+			e := s.hash[name]
+			fmt.Fprintf(stdout, "%8d\t%s\n", e.hits, e.path)
+		}
+		return 0
+	}
+
+	exitCode := 0
+	for _, name := range args {
+		e, ok := s.hash[name]
+		switch {
+		case mode == "-d" && ok:
+			delete(s.hash, name)
+		case mode == "-t" && ok:
+			if len(args) > 1 {
+				fmt.Fprintf(stdout, "%s\t", name)
+			}
+			fmt.Fprintln(stdout, e.path)
+		case mode == "" && (strings.Contains(name, "/") || s.builtins[name] != nil):
+			// Пути и встроенные команды не запоминаются
+		case mode == "":
+			if found := s.searchPath(name, false); len(found) > 0 {
+				s.hash[name] = hashEntry{path: found[0]}
+				continue
+			}
+			fallthrough
+		default:
+			fmt.Fprintf(stderr, "hash: %s: не найден\n", name)
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
@@ -0,0 +1,70 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLookPathHash тестирует, что найденные в $PATH команды запоминаются,
+// а смена $PATH и hash -r очищают таблицу
+func TestLookPathHash(t *testing.T) {
+	t.Parallel()
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	for _, dir := range []string{dir1, dir2} {
+		os.WriteFile(filepath.Join(dir, "tool"), []byte("#!/bin/sh\necho "+dir+"\n"), 0755)
+	}
+
+	shell := NewShell(nil, nil, nil)
+	shell.setVar("PATH", dir1+":"+dir2)
+	for range 2 {
+		if path, err := shell.lookPath("tool"); err != nil || path != filepath.Join(dir1, "tool") {
+			t.Fatalf("lookPath: expected %s/tool, got %q, %v", dir1, path, err)
+		}
+	}
+	if e := shell.hash["tool"]; e.hits != 2 {
+		t.Errorf("lookPath: expected 2 hits, got %d", e.hits)
+	}
+
+	// Запомненный файл удален - команда ищется заново
+	os.Remove(filepath.Join(dir1, "tool"))
+	if path, _ := shell.lookPath("tool"); path != filepath.Join(dir2, "tool") {
+		t.Errorf("lookPath after remove: expected %s/tool, got %q", dir2, path)
+	}
+
+	shell.setVar("PATH", dir1)
+	if len(shell.hash) != 0 {
+		t.Errorf("PATH change: expected empty hash, got %v", shell.hash)
+	}
+	if _, err := shell.lookPath("tool"); err != errCommandNotFound {
+		t.Errorf("lookPath: expected errCommandNotFound, got %v", err)
+	}
+}
+
+// TestBuiltinHash тестирует вывод и очистку хэш-таблицы
+func TestBuiltinHash(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "tool"), []byte("#!/bin/sh\n"), 0755)
+
+	script := "PATH=" + dir + "\n" +
+		"hash\n" +
+		"tool; tool\n" +
+		"hash\n" +
+		"hash -t tool\n" +
+		"hash -r; hash\n" +
+		"hash tool nosuch 2>/dev/null; echo st=$?\n" +
+		"hash -d tool; hash\n"
+	result, _ := runScript(t, script)
+
+	path := filepath.Join(dir, "tool")
+	want := "hash: хэш-таблица пуста\n" +
+		"запусков\tкоманда\n       2\t" + path + "\n" +
+		path + "\n" +
+		"hash: хэш-таблица пуста\n" +
+		"st=1\n" +
+		"hash: хэш-таблица пуста\n"
+	if result != want {
+		t.Errorf("hash: expected %q, got %q", want, result)
+	}
+}
@@ -0,0 +1,244 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// historyFile - имя файла истории в домашней директории
+const historyFile = ".myshell_history"
+
+// historySize - сколько последних команд хранится в истории
+const historySize = 1000
+
+// History - история команд интерактивного shell'а.
+// Каждая команда дописывается в файл сразу после ввода, поэтому история
+// сохраняется и при выходе по Ctrl+D, и при завершении по exit
+type History struct {
+	entries []string
+	path    string // Файл истории ("" - история только в памяти)
+}
+
+// loadHistory читает историю из файла. Если файла нет, история пуста
+func loadHistory(path string) *History {
+	h := &History{path: path}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return h
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			h.entries = append(h.entries, unescapeHistory(line))
+		}
+	}
+	file.Close()
+
+	// Файл разросся: оставляем только последние historySize команд
+	if len(h.entries) > historySize {
+		h.entries = h.entries[len(h.entries)-historySize:]
+		h.rewrite()
+	}
+	return h
+}
+
+// Add добавляет команду в историю и в файл. Повтор предыдущей команды не сохраняется
+func (h *History) Add(line string) {
+	if line == "" || len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return
+	}
+	h.entries = append(h.entries, line)
+	if len(h.entries) > historySize {
+		h.entries = h.entries[1:]
+	}
+
+	if h.path == "" {
+		return
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(file, escapeHistory(line))
+	file.Close()
+}
+
+// Len возвращает количество команд в истории
+func (h *History) Len() int {
+	return len(h.entries)
+}
+
+// Entry возвращает команду с индексом i (с нуля, от самой старой)
+func (h *History) Entry(i int) string {
+	return h.entries[i]
+}
+
+// Clear очищает историю и файл истории
+func (h *History) Clear() {
+	h.entries = nil
+	h.rewrite()
+}
+
+// rewrite перезаписывает файл истории текущим списком команд
+func (h *History) rewrite() {
+	if h.path == "" {
+		return
+	}
+	var b strings.Builder
+	for _, entry := range h.entries {
+		b.WriteString(escapeHistory(entry))
+		b.WriteByte('\n')
+	}
+	os.WriteFile(h.path, []byte(b.String()), 0600)
+}
+
+// escapeHistory записывает многострочную команду одной строкой файла: \ -> \\, перевод строки -> \n
+func escapeHistory(line string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(line)
+}
+
+// unescapeHistory восстанавливает команду, записанную escapeHistory
+func unescapeHistory(line string) string {
+	if !strings.Contains(line, `\`) {
+		return line
+	}
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			i++
+			if line[i] == 'n' {
+				b.WriteByte('\n')
+				continue
+			}
+		}
+		b.WriteByte(line[i])
+	}
+	return b.String()
+}
+
+// historyPath возвращает путь к файлу истории в домашней директории
+func (s *Shell) historyPath() string {
+	return filepath.Join(s.homeDir(), historyFile)
+}
+
+// expandHistory раскрывает ссылки на историю в строке ввода:
+//
+//	!!       предыдущая команда
+//	!n       команда с номером n (номера выводит history)
+//	!-n      n-я команда с конца
+//	!prefix  последняя команда, начинающаяся с prefix
+//
+// Ссылки в одинарных кавычках, после \ и $ (как в $!) не раскрываются
+func (h *History) expandHistory(line string) (string, bool, error) {
+	if !strings.Contains(line, "!") {
+		return line, false, nil
+	}
+
+	var b strings.Builder
+	expanded := false
+	inSingle := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\'':
+			inSingle = !inSingle
+		case c == '\\' && !inSingle && i+1 < len(line):
+			b.WriteByte(c)
+			i++
+			c = line[i]
+		case c == '!' && !inSingle && i+1 < len(line) && (i == 0 || line[i-1] != '$' && line[i-1] != '['):
+			end, entry, ok, err := h.historyRef(line, i)
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				b.WriteString(entry)
+				expanded = true
+				i = end - 1
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), expanded, nil
+}
+
+// historyRef разбирает ссылку на историю, начинающуюся с ! в позиции i.
+// Возвращает позицию после ссылки и команду; ok=false, если это не ссылка
+func (h *History) historyRef(line string, i int) (end int, entry string, ok bool, err error) {
+	rest := line[i+1:]
+	switch {
+	case rest[0] == '!':
+		if len(h.entries) == 0 {
+			return 0, "", false, fmt.Errorf("!!: событие не найдено")
+		}
+		return i + 2, h.entries[len(h.entries)-1], true, nil
+
+	case rest[0] == '-' || rest[0] >= '0' && rest[0] <= '9':
+		n := 1
+		for n < len(rest) && rest[n] >= '0' && rest[n] <= '9' {
+			n++
+		}
+		num, convErr := strconv.Atoi(rest[:n])
+		if convErr != nil {
+			return 0, "", false, nil // Одиночный "!-" - не ссылка
+		}
+		index := num - 1
+		if num < 0 {
+			index = len(h.entries) + num
+		}
+		if num == 0 || index < 0 || index >= len(h.entries) {
+			return 0, "", false, fmt.Errorf("!%s: событие не найдено", rest[:n])
+		}
+		return i + 1 + n, h.entries[index], true, nil
+
+	case strings.IndexByte(" \t\n=(\"", rest[0]) >= 0:
+		return 0, "", false, nil
+	}
+
+	n := strings.IndexAny(rest, " \t\n;|&<>()\"'")
+	if n < 0 {
+		n = len(rest)
+	}
+	prefix := rest[:n]
+	for k := len(h.entries) - 1; k >= 0; k-- {
+		if strings.HasPrefix(h.entries[k], prefix) {
+			return i + 1 + n, h.entries[k], true, nil
+		}
+	}
+	return 0, "", false, fmt.Errorf("!%s: событие не найдено", prefix)
+}
+
+// builtinHistory выводит историю команд: history [N] - последние N команд, history -c - очистить
+func (s *Shell) builtinHistory(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	args = args[1:]
+	if s.history == nil {
+		return 0
+	}
+
+	start := 0
+	if len(args) > 0 {
+		if args[0] == "-c" {
+			s.history.Clear()
+			return 0
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			fmt.Fprintf(stderr, "history: %s: требуется числовой аргумент\n", args[0])
+			return 2
+		}
+		start = max(s.history.Len()-n, 0)
+	}
+
+	for i := start; i < s.history.Len(); i++ {
+		fmt.Fprintf(stdout, "%5d  %s\n", i+1, s.history.Entry(i))
+	}
+	return 0
+}
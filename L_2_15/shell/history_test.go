@@ -0,0 +1,84 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHistoryFile тестирует сохранение истории в файл, в том числе многострочных команд
+func TestHistoryFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), historyFile)
+
+	h := loadHistory(path)
+	h.Add("echo one")
+	h.Add("echo one") // Повтор не сохраняется
+	h.Add("for x in a\ndo echo 'a\\nb'\ndone")
+
+	loaded := loadHistory(path)
+	if loaded.Len() != 2 || loaded.Entry(1) != "for x in a\ndo echo 'a\\nb'\ndone" {
+		t.Errorf("history file: unexpected entries %q", loaded.entries)
+	}
+
+	loaded.Clear()
+	if content, _ := os.ReadFile(path); len(content) != 0 {
+		t.Errorf("history -c: expected empty file, got %q", content)
+	}
+}
+
+// TestExpandHistory тестирует раскрытие !!, !n, !-n и !prefix
+func TestExpandHistory(t *testing.T) {
+	t.Parallel()
+	h := &History{entries: []string{"echo one", "ls -l", "echo two"}}
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "!!", want: "echo two"},
+		{line: "!1 more", want: "echo one more"},
+		{line: "!-2", want: "ls -l"},
+		{line: "!ls | wc", want: "ls -l | wc"},
+		{line: "sudo !!", want: "sudo echo two"},
+		{line: "echo '!!' \\!! $! x!", want: "echo '!!' \\!! $! x!"},
+		{line: "[ ! -f x ] && echo [!a]*", want: "[ ! -f x ] && echo [!a]*"},
+	}
+
+	for _, tt := range tests {
+		got, _, err := h.expandHistory(tt.line)
+		if err != nil {
+			t.Errorf("expandHistory(%q): unexpected error: %v", tt.line, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expandHistory(%q): expected %q, got %q", tt.line, tt.want, got)
+		}
+	}
+
+	for _, line := range []string{"!9", "!-5", "!missing"} {
+		if _, _, err := h.expandHistory(line); err == nil || !strings.Contains(err.Error(), "событие не найдено") {
+			t.Errorf("expandHistory(%q): expected event not found, got %v", line, err)
+		}
+	}
+}
+
+// TestHistoryBuiltin тестирует команду history и запись введенных команд в историю
+func TestHistoryBuiltin(t *testing.T) {
+	t.Parallel()
+	output := &bytes.Buffer{}
+	shell := NewShell(bufio.NewReader(strings.NewReader("echo a\n!!\nhistory\nhistory 1\n!42\n")), output, output)
+	shell.history = &History{}
+	shell.Run()
+	result := output.String()
+
+	want := "a\necho a\na\n    1  echo a\n    2  history\n    3  history 1\n!42: событие не найдено\n"
+	if result != want {
+		t.Errorf("history: expected %q, got %q", want, result)
+	}
+	if shell.status != 1 {
+		t.Errorf("history: expected status 1 after missing event, got %d", shell.status)
+	}
+}
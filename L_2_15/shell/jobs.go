@@ -0,0 +1,699 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// jobState - состояние задания
+type jobState int
+
+const (
+	jobRunning jobState = iota // Выполняется
+	jobStopped                 // Остановлено (Ctrl+Z)
+	jobDone                    // Все процессы завершились
+)
+
+func (st jobState) String() string {
+	switch st {
+	case jobRunning:
+		return "Выполняется"
+	case jobStopped:
+		return "Остановлено"
+	default:
+		return "Завершено"
+	}
+}
+
+// process - одна команда конвейера: внешний процесс или встроенная команда (функция),
+// которая выполняется горутиной
+type process struct {
+	cmd     *exec.Cmd
+	pid     int
+	status  syscall.WaitStatus
+	done    bool
+	stopped bool
+
+	internal bool          // Встроенная команда или функция
+	finished chan struct{} // Закрывается по завершении горутины встроенной команды (nil - еще не запущена)
+	code     int           // Код завершения встроенной команды
+}
+
+// exitCode возвращает код завершения команды
+func (p *process) exitCode() int {
+	if p.internal {
+		return p.code
+	}
+	return waitStatusCode(p.status)
+}
+
+// Job - задание: конвейер, запущенный в фоне или на переднем плане.
+// Процессы задания дожидаются через wait4, а не cmd.Wait: только так можно
+// узнать об остановке процесса (Ctrl+Z), а не только о его завершении
+type Job struct {
+	ID      int
+	Pgid    int    // Группа процессов задания (0, если управление заданиями выключено)
+	Command string // Текст команды для вывода в jobs
+	procs   []*process
+	state   jobState
+	copiers sync.WaitGroup // Горутины, копирующие вывод процессов в не-файловые writer'ы
+	cleanup []func()       // Закрытие файлов редиректов после завершения задания
+}
+
+// pipeOutput возвращает файл, в который процесс может писать напрямую.
+// Если w - не файл, создается труба, а ее содержимое копируется в w отдельной горутиной
+// (exec.Cmd делает то же самое, но дожидается копирования только в cmd.Wait).
+// Записывающий конец трубы нужно закрыть в родительском процессе после запуска
+func (job *Job) pipeOutput(w io.Writer) (io.Writer, io.Closer, error) {
+	if _, ok := w.(*os.File); ok || w == nil {
+		return w, nil, nil
+	}
+
+	r, pw, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	job.copiers.Add(1)
+	go func() {
+		defer job.copiers.Done()
+		io.Copy(w, r)
+		r.Close()
+	}()
+
+	return pw, pw, nil
+}
+
+// pipeInput - то же, что pipeOutput, для ввода: если r - не файл, процесс читает трубу,
+// в которую r копируется отдельной горутиной. Читающий конец трубы нужно закрыть
+// в родительском процессе после запуска
+func (job *Job) pipeInput(r io.Reader) (io.Reader, io.Closer, error) {
+	if _, ok := r.(*os.File); ok || r == nil {
+		return r, nil, nil
+	}
+
+	pr, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	job.copiers.Add(1)
+	go func() {
+		defer job.copiers.Done()
+		// Процесс может завершиться, не дочитав ввод: запись в трубу тогда вернет ошибку
+		io.Copy(w, r)
+		w.Close()
+	}()
+
+	return pr, pr, nil
+}
+
+// wait собирает события о процессах задания.
+// При block=true ждет, пока задание не завершится или не остановится
+func (job *Job) wait(block bool) {
+	for job.state == jobRunning {
+		if !job.waitOnce(block) {
+			return
+		}
+	}
+}
+
+// waitOnce получает одно событие wait4; возвращает false, если событий нет
+func (job *Job) waitOnce(block bool) bool {
+	options := 0
+	if job.Pgid != 0 {
+		// Остановки отслеживаем только в режиме управления заданиями:
+		// без своей группы процессов Ctrl+Z останавливает и сам shell
+		options |= syscall.WUNTRACED
+	}
+	if !block {
+		options |= syscall.WNOHANG
+	}
+
+	targets := []int{-job.Pgid}
+	if job.Pgid == 0 {
+		targets = targets[:0]
+		for _, p := range job.procs {
+			if !p.internal && !p.done {
+				targets = append(targets, p.pid)
+			}
+		}
+		if len(targets) == 0 {
+			// Остались только встроенные команды
+			if job.waitBuiltins(block) {
+				job.finish()
+			}
+			return false
+		}
+		if block {
+			targets = targets[:1]
+		}
+	}
+
+	changed := false
+	for _, target := range targets {
+		var ws syscall.WaitStatus
+		pid, err := syscall.Wait4(target, &ws, options, nil)
+		for err == syscall.EINTR {
+			pid, err = syscall.Wait4(target, &ws, options, nil)
+		}
+
+		if err != nil {
+			// ECHILD: процессов больше нет, задание завершено, когда завершатся встроенные команды
+			if job.waitBuiltins(block) {
+				job.finish()
+			}
+			return false
+		}
+		if pid > 0 {
+			job.update(pid, ws)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// update применяет результат wait4 к процессу pid
+func (job *Job) update(pid int, ws syscall.WaitStatus) {
+	for _, p := range job.procs {
+		if p.pid != pid {
+			continue
+		}
+		if ws.Stopped() {
+			p.stopped = true
+		} else {
+			p.status = ws
+			p.done = true
+			p.cmd.Process.Release()
+		}
+	}
+
+	allDone := true
+	allStopped := true
+	for _, p := range job.procs {
+		if !p.internal && !p.done {
+			allDone = false
+			if !p.stopped {
+				allStopped = false
+			}
+		}
+	}
+
+	switch {
+	case allDone:
+		if job.waitBuiltins(false) {
+			job.finish()
+		}
+	case allStopped:
+		job.state = jobStopped
+	}
+}
+
+// waitBuiltins отмечает завершившиеся встроенные команды задания и сообщает, все ли они завершились.
+// При block=true ждет их завершения
+func (job *Job) waitBuiltins(block bool) bool {
+	for _, p := range job.procs {
+		if !p.internal || p.done || p.finished == nil {
+			continue
+		}
+		if block {
+			<-p.finished
+		} else {
+			select {
+			case <-p.finished:
+			default:
+				return false
+			}
+		}
+		p.done = true
+	}
+	return true
+}
+
+// finish помечает задание завершенным и освобождает его ресурсы
+func (job *Job) finish() {
+	for _, p := range job.procs {
+		if p.finished != nil {
+			<-p.finished
+		}
+		p.done = true
+	}
+	job.state = jobDone
+	job.copiers.Wait()
+	for _, fn := range job.cleanup {
+		fn()
+	}
+	job.cleanup = nil
+}
+
+// abort завершает задание, которое не удалось запустить целиком:
+// уже запущенные процессы убиваются и дожидаются, чтобы не оставлять зомби
+func (job *Job) abort() {
+	for _, p := range job.procs {
+		if p.internal || p.pid == 0 || p.done {
+			continue
+		}
+		syscall.Kill(p.pid, syscall.SIGKILL)
+		var ws syscall.WaitStatus
+		for {
+			if _, err := syscall.Wait4(p.pid, &ws, 0, nil); err != syscall.EINTR {
+				break
+			}
+		}
+		p.status = ws
+		p.done = true
+		p.cmd.Process.Release()
+	}
+	job.finish()
+}
+
+// resume отправляет заданию SIGCONT
+func (job *Job) resume() error {
+	for _, p := range job.procs {
+		p.stopped = false
+	}
+	job.state = jobRunning
+	return job.signal(syscall.SIGCONT)
+}
+
+// signalTarget - процессы задания для пересылки сигналов из горутины handleSignals.
+// Это снимок: сами процессы задания меняются при ожидании без синхронизации
+type signalTarget struct {
+	pgid int
+	pids []int
+}
+
+// signalTarget возвращает снимок процессов задания для пересылки сигналов
+func (job *Job) signalTarget() *signalTarget {
+	target := &signalTarget{pgid: job.Pgid}
+	for _, p := range job.procs {
+		if !p.internal && !p.done {
+			target.pids = append(target.pids, p.pid)
+		}
+	}
+	return target
+}
+
+// signal посылает сигнал группе процессов задания или, без управления заданиями, каждому процессу
+func (t *signalTarget) signal(sig syscall.Signal) {
+	if t.pgid != 0 {
+		syscall.Kill(-t.pgid, sig)
+		return
+	}
+	for _, pid := range t.pids {
+		syscall.Kill(pid, sig)
+	}
+}
+
+// killedBy возвращает результат wait4 последнего процесса задания, завершенного сигналом
+func (job *Job) killedBy() (syscall.WaitStatus, bool) {
+	for i := len(job.procs) - 1; i >= 0; i-- {
+		p := job.procs[i]
+		if !p.internal && p.done && p.status.Signaled() {
+			return p.status, true
+		}
+	}
+	return 0, false
+}
+
+// signal посылает сигнал всем процессам задания: группе процессов или, если
+// управление заданиями выключено, каждому еще не завершившемуся процессу
+func (job *Job) signal(sig syscall.Signal) error {
+	if job.Pgid != 0 {
+		return syscall.Kill(-job.Pgid, sig)
+	}
+	var firstErr error
+	for _, p := range job.procs {
+		if p.internal || p.done {
+			continue
+		}
+		if err := syscall.Kill(p.pid, sig); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// statuses возвращает коды завершения команд задания по порядку ($PIPESTATUS)
+func (job *Job) statuses() []int {
+	codes := make([]int, len(job.procs))
+	for i, p := range job.procs {
+		if job.state == jobStopped {
+			codes[i] = 128 + int(syscall.SIGTSTP)
+		} else {
+			codes[i] = p.exitCode()
+		}
+	}
+	return codes
+}
+
+// exitCode возвращает код завершения задания, как в bash: код последней команды конвейера,
+// а при pipefail - код последней неуспешной команды (0, если все успешны)
+func (job *Job) exitCode(pipefail bool) int {
+	codes := job.statuses()
+	if !pipefail {
+		return codes[len(codes)-1]
+	}
+	for i := len(codes) - 1; i >= 0; i-- {
+		if codes[i] != 0 {
+			return codes[i]
+		}
+	}
+	return 0
+}
+
+// waitStatusCode переводит результат wait4 в код завершения в стиле shell:
+// при завершении по сигналу код равен 128 + номер сигнала
+func waitStatusCode(ws syscall.WaitStatus) int {
+	if ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return ws.ExitStatus()
+}
+
+// enableJobControl включает управление заданиями для терминала fd:
+// каждый конвейер получает свою группу процессов, а на переднем плане - и терминал
+func (s *Shell) enableJobControl(fd int) {
+	s.terminal = fd
+	s.shellPgid = syscall.Getpgrp()
+	s.jobControl = true
+
+	// Shell не должен останавливаться от Ctrl+Z и от работы с терминалом из фона.
+	// Сигналы перехватываются, а не игнорируются: игнорирование унаследовали бы дочерние процессы
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTSTP, syscall.SIGTTIN, syscall.SIGTTOU)
+	go func() {
+		for range sigChan {
+		}
+	}()
+
+	s.grabTerminal()
+}
+
+// grabTerminal возвращает терминал shell'у
+func (s *Shell) grabTerminal() {
+	if s.jobControl {
+		tcsetpgrp(s.terminal, s.shellPgid)
+	}
+}
+
+// addJob добавляет задание в таблицу и назначает ему номер
+func (s *Shell) addJob(job *Job) {
+	job.ID = 1
+	for _, j := range s.jobs {
+		if j.ID >= job.ID {
+			job.ID = j.ID + 1
+		}
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// removeJob удаляет задание из таблицы
+func (s *Shell) removeJob(job *Job) {
+	for i, j := range s.jobs {
+		if j == job {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// waitForeground ждет задание переднего плана и возвращает его код завершения.
+// Остановленное задание остается в таблице заданий
+func (s *Shell) waitForeground(job *Job) int {
+	target := job.signalTarget()
+	s.foreground.Store(target)
+	if s.ctx != nil {
+		// Отмена контекста Exec убивает задание
+		stop := context.AfterFunc(s.ctx, func() { target.signal(syscall.SIGKILL) })
+		defer stop()
+	}
+	job.wait(true)
+	s.foreground.Store(nil)
+	s.grabTerminal()
+
+	if job.state == jobStopped {
+		if job.ID == 0 {
+			s.addJob(job)
+		}
+		fmt.Fprintf(s.errWriter, "\n%s\n", s.formatJob(job))
+		return job.exitCode(s.options["pipefail"])
+	}
+
+	s.removeJob(job)
+
+	// Сообщение о завершении по сигналу, как в bash: Ctrl+C дает только перевод строки,
+	// а SIGPIPE - обычное завершение команды, у которой закрыли вывод
+	// О задании, убитом при отмене Exec, не сообщаем: об отмене сообщает ошибка Exec
+	if ws, ok := job.killedBy(); ok && !s.cancelled() {
+		switch ws.Signal() {
+		case syscall.SIGINT:
+			// При управлении заданиями терминал посылает Ctrl+C только группе задания,
+			// и до самого shell'а он не доходит: команда, убитая им, прерывает и цикл
+			// или список, как в bash. Без управления заданиями это решает handleSignals
+			if s.jobControl {
+				s.sigint.Store(true)
+			}
+			if s.interactive {
+				fmt.Fprintln(s.errWriter)
+			}
+		case syscall.SIGPIPE:
+		default:
+			fmt.Fprintln(s.errWriter, signalMessage(ws))
+		}
+	}
+	return job.exitCode(s.options["pipefail"])
+}
+
+// formatJob форматирует строку задания для вывода jobs
+func (s *Shell) formatJob(job *Job) string {
+	marker := " "
+	if n := len(s.jobs); n > 0 && s.jobs[n-1] == job {
+		marker = "+"
+	} else if n > 1 && s.jobs[n-2] == job {
+		marker = "-"
+	}
+
+	command := job.Command
+	if job.state == jobRunning {
+		command += " &"
+	}
+	state := job.state.String()
+	if ws, ok := job.killedBy(); ok && job.state == jobDone {
+		state = signalMessage(ws)
+	}
+	return fmt.Sprintf("[%d]%s  %-12s %s", job.ID, marker, state, command)
+}
+
+// notifyJobs собирает завершившиеся фоновые задания и сообщает о них
+func (s *Shell) notifyJobs() {
+	for _, job := range append([]*Job(nil), s.jobs...) {
+		if job.state == jobRunning {
+			job.wait(false)
+		}
+		if job.state == jobDone {
+			fmt.Fprintln(s.errWriter, s.formatJob(job))
+			s.removeJob(job)
+		}
+	}
+}
+
+// reapJobs собирает завершившиеся фоновые задания, не сообщая о них, чтобы их процессы
+// не оставались зомби. Задания остаются в таблице, пока их код завершения не получит wait или jobs
+func (s *Shell) reapJobs() {
+	for _, job := range s.jobs {
+		if job.state == jobRunning {
+			job.wait(false)
+		}
+	}
+}
+
+// findJob находит задание по спецификации: %n, %%, %+, %-, %строка или пусто (текущее задание)
+func (s *Shell) findJob(spec string) (*Job, error) {
+	if len(s.jobs) == 0 {
+		return nil, fmt.Errorf("нет такого задания")
+	}
+
+	switch spec {
+	case "", "%", "%%", "%+":
+		return s.jobs[len(s.jobs)-1], nil
+	case "%-":
+		if len(s.jobs) < 2 {
+			return nil, fmt.Errorf("%s: нет такого задания", spec)
+		}
+		return s.jobs[len(s.jobs)-2], nil
+	}
+
+	if !strings.HasPrefix(spec, "%") {
+		return nil, fmt.Errorf("%s: нет такого задания", spec)
+	}
+	name := spec[1:]
+
+	if id, err := strconv.Atoi(name); err == nil {
+		for _, job := range s.jobs {
+			if job.ID == id {
+				return job, nil
+			}
+		}
+		return nil, fmt.Errorf("%s: нет такого задания", spec)
+	}
+
+	for i := len(s.jobs) - 1; i >= 0; i-- {
+		if strings.HasPrefix(s.jobs[i].Command, name) {
+			return s.jobs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%s: нет такого задания", spec)
+}
+
+// findJobByPid находит задание, в которое входит процесс pid
+func (s *Shell) findJobByPid(pid int) (*Job, error) {
+	for _, job := range s.jobs {
+		for _, p := range job.procs {
+			if !p.internal && p.pid == pid {
+				return job, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("pid %d не является дочерним процессом этого shell'а", pid)
+}
+
+// waitPollInterval - как часто wait проверяет фоновое задание. wait4 не прерывается
+// сигналами, поэтому задание опрашивается без блокировки, чтобы Ctrl+C прервал ожидание
+const waitPollInterval = 10 * time.Millisecond
+
+// waitJob ждет завершения фонового задания и удаляет его из таблицы.
+// Ctrl+C и отмена Exec прерывают ожидание (код 130), а задание продолжает работать
+func (s *Shell) waitJob(job *Job) int {
+	for {
+		job.wait(false)
+		if job.state != jobRunning {
+			break
+		}
+		if s.interrupted() {
+			return 130
+		}
+		time.Sleep(waitPollInterval)
+	}
+
+	if job.state == jobDone {
+		s.removeJob(job)
+	}
+	return job.exitCode(s.options["pipefail"])
+}
+
+// builtinWait ждет фоновые задания: без аргументов - все, иначе - указанные задания (%n)
+// и процессы (pid). Код завершения - код последнего указанного задания
+func (s *Shell) builtinWait(args []string, _ io.Reader, _, stderr io.Writer) int {
+	if len(args) == 1 {
+		for _, job := range append([]*Job(nil), s.jobs...) {
+			// Остановленное задание не завершится само, его не ждем
+			if job.state == jobStopped {
+				continue
+			}
+			s.waitJob(job)
+			if s.interrupted() {
+				return 130
+			}
+		}
+		return 0
+	}
+
+	exitCode := 0
+	for _, spec := range args[1:] {
+		var job *Job
+		var err error
+		if strings.HasPrefix(spec, "%") {
+			job, err = s.findJob(spec)
+		} else if pid, convErr := strconv.Atoi(spec); convErr == nil {
+			job, err = s.findJobByPid(pid)
+		} else {
+			fmt.Fprintf(stderr, "wait: %s: неверный идентификатор процесса или задания\n", spec)
+			exitCode = 2
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "wait: %v\n", err)
+			exitCode = 127
+			continue
+		}
+
+		exitCode = s.waitJob(job)
+		if s.interrupted() {
+			return exitCode
+		}
+	}
+	return exitCode
+}
+
+// builtinJobs выводит таблицу заданий
+func (s *Shell) builtinJobs(_ []string, _ io.Reader, stdout, _ io.Writer) int {
+	for _, job := range s.jobs {
+		if job.state == jobRunning {
+			job.wait(false)
+		}
+	}
+	for _, job := range append([]*Job(nil), s.jobs...) {
+		fmt.Fprintln(stdout, s.formatJob(job))
+		if job.state == jobDone {
+			s.removeJob(job)
+		}
+	}
+	return 0
+}
+
+// builtinFg продолжает задание на переднем плане
+func (s *Shell) builtinFg(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	spec := ""
+	if len(args) > 1 {
+		spec = args[1]
+	}
+	job, err := s.findJob(spec)
+	if err != nil {
+		fmt.Fprintf(stderr, "fg: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintln(stdout, job.Command)
+	if s.jobControl {
+		tcsetpgrp(s.terminal, job.Pgid)
+	}
+	if err := job.resume(); err != nil {
+		fmt.Fprintf(stderr, "fg: %v\n", err)
+	}
+	return s.waitForeground(job)
+}
+
+// builtinBg продолжает остановленное задание в фоне
+func (s *Shell) builtinBg(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	spec := ""
+	if len(args) > 1 {
+		spec = args[1]
+	}
+	job, err := s.findJob(spec)
+	if err != nil {
+		fmt.Fprintf(stderr, "bg: %v\n", err)
+		return 1
+	}
+	if job.state != jobStopped {
+		fmt.Fprintf(stderr, "bg: задание %d уже выполняется в фоне\n", job.ID)
+		return 0
+	}
+
+	if err := job.resume(); err != nil {
+		fmt.Fprintf(stderr, "bg: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "[%d]+ %s &\n", job.ID, job.Command)
+	return 0
+}
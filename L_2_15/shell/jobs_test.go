@@ -0,0 +1,250 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestBackgroundJob тестирует запуск в фоне, вывод jobs и сообщение о завершении задания
+func TestBackgroundJob(t *testing.T) {
+	t.Parallel()
+	input := bufio.NewReader(strings.NewReader("sleep 0.2 &\njobs\nsleep 0.4\nexit\n"))
+	output := &bytes.Buffer{}
+
+	// О завершении фоновых заданий shell сообщает только в интерактивном режиме
+	shell := NewShell(input, output, output)
+	shell.interactive = true
+	shell.Run()
+
+	result := output.String()
+	if !strings.Contains(result, "[1] ") {
+		t.Errorf("background job: expected job number in output, got %q", result)
+	}
+	if !strings.Contains(result, "Выполняется  sleep 0.2 &") {
+		t.Errorf("background job: expected running job in jobs output, got %q", result)
+	}
+	if !strings.Contains(result, "Завершено    sleep 0.2") {
+		t.Errorf("background job: expected done notification, got %q", result)
+	}
+}
+
+// TestFgWaitsForJob тестирует, что fg дожидается фонового задания и возвращает его вывод
+func TestFgWaitsForJob(t *testing.T) {
+	t.Parallel()
+	input := bufio.NewReader(strings.NewReader("sh -c 'sleep 0.1; echo finished' &\nfg %1\njobs\nexit\n"))
+	output := &bytes.Buffer{}
+
+	shell := NewShell(input, output, os.Stderr)
+	shell.Run()
+
+	if len(shell.jobs) != 0 {
+		t.Errorf("fg: expected empty job table, got %d jobs", len(shell.jobs))
+	}
+	if !strings.Contains(output.String(), "finished") {
+		t.Errorf("fg: expected job output, got %q", output.String())
+	}
+}
+
+// TestWait тестирует wait: коды завершения заданий и процессов, ожидание всех заданий
+// и ошибки для неизвестных заданий. Неинтерактивный shell не выводит номер задания
+func TestWait(t *testing.T) {
+	t.Parallel()
+	script := `sh -c 'exit 3' & p=$!
+sleep 0.1 &
+wait $p; echo pid:$?
+wait %2; echo job:$?
+(sleep 0.2; echo late) &
+wait; echo all:$?
+wait 999999; echo unknown:$?
+wait %5; echo nojob:$?
+`
+	result, errOutput, shell := runScriptStderr(t, script)
+
+	want := "pid:3\njob:0\nlate\nall:0\nunknown:127\nnojob:127\n"
+	if result != want {
+		t.Errorf("wait: expected %q, got %q", want, result)
+	}
+	if strings.Contains(errOutput, "[1]") || !strings.Contains(errOutput, "wait: pid 999999") {
+		t.Errorf("wait: unexpected errors %q", errOutput)
+	}
+	if len(shell.jobs) != 0 {
+		t.Errorf("wait: expected empty job table, got %d jobs", len(shell.jobs))
+	}
+}
+
+// TestReapBackgroundJobs тестирует, что неинтерактивный shell собирает завершившиеся
+// фоновые задания после каждой команды и они не остаются зомби
+func TestReapBackgroundJobs(t *testing.T) {
+	t.Parallel()
+	shell := NewShell(bufio.NewReader(strings.NewReader("sleep 0.05 &\nsleep 0.3\n")), &bytes.Buffer{}, &bytes.Buffer{})
+	shell.Run()
+
+	if len(shell.jobs) != 1 || shell.jobs[0].state != jobDone {
+		t.Fatalf("reap: expected one done job, got %d jobs", len(shell.jobs))
+	}
+	for _, p := range shell.jobs[0].procs {
+		// Собранный процесс уже не существует: kill возвращает ESRCH, а зомби принял бы сигнал
+		if err := syscall.Kill(p.pid, 0); err != syscall.ESRCH {
+			t.Errorf("reap: process %d was not reaped: %v", p.pid, err)
+		}
+	}
+}
+
+// TestFindJob тестирует разбор спецификаций заданий
+func TestFindJob(t *testing.T) {
+	t.Parallel()
+	shell := NewShell(nil, nil, nil)
+	first := &Job{Command: "sleep 10"}
+	second := &Job{Command: "make build"}
+	shell.addJob(first)
+	shell.addJob(second)
+
+	tests := []struct {
+		spec string
+		want *Job
+	}{
+		{spec: "", want: second},
+		{spec: "%%", want: second},
+		{spec: "%-", want: first},
+		{spec: "%1", want: first},
+		{spec: "%2", want: second},
+		{spec: "%sleep", want: first},
+		{spec: "%3", want: nil},
+		{spec: "1", want: nil},
+	}
+
+	for _, tt := range tests {
+		got, err := shell.findJob(tt.spec)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("findJob(%q): expected error, got job %d", tt.spec, got.ID)
+			}
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("findJob(%q): expected job %d, got %v (err %v)", tt.spec, tt.want.ID, got, err)
+		}
+	}
+}
+
+// TestForwardSignals тестирует пересылку SIGINT заданию переднего плана, код 128+номер сигнала
+// и завершение скрипта после команды, убитой Ctrl+C
+func TestForwardSignals(t *testing.T) {
+	t.Parallel()
+	output := &bytes.Buffer{}
+	shell := NewShell(nil, output, output)
+
+	shell.executeCommand("sh -c 'kill -KILL $$'; echo st=$?")
+	if got := output.String(); got != "Killed\nst=137\n" {
+		t.Errorf("forward signals: unexpected output %q", got)
+	}
+	output.Reset()
+
+	sigChan := make(chan os.Signal)
+	sent := make(chan struct{})
+	go shell.handleSignals(sigChan, output, false)
+	go func() {
+		defer close(sent)
+		// Ждем, пока sleep станет заданием переднего плана
+		for shell.foreground.Load() == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+		sigChan <- syscall.SIGINT
+	}()
+	defer func() {
+		<-sent
+		close(sigChan)
+	}()
+
+	start := time.Now()
+	shell.executeCommand("sleep 10; echo not-reached")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("forward signals: SIGINT was not forwarded, waited %v", elapsed)
+	}
+	if got := output.String(); got != "" {
+		t.Errorf("forward signals: unexpected output %q", got)
+	}
+	if !shell.exit || shell.status != 130 {
+		t.Errorf("forward signals: expected script to exit with 130, got exit %v, status %d", shell.exit, shell.status)
+	}
+}
+
+// TestChildSigint тестирует, что команда, завершенная SIGINT без Ctrl+C shell'у,
+// не прерывает скрипт и Exec
+func TestChildSigint(t *testing.T) {
+	t.Parallel()
+	result, shell := runScript(t, "sh -c 'kill -INT $$'; echo st=$?\necho after\n")
+	if result != "st=130\nafter\n" || shell.exit {
+		t.Errorf("child sigint: unexpected output %q, exit %v", result, shell.exit)
+	}
+
+	output := &bytes.Buffer{}
+	shell = NewShell(nil, output, nil)
+	status, err := shell.Exec(context.Background(), "sh -c 'kill -INT $$'; echo after")
+	if err != nil || status != 0 || output.String() != "after\n" {
+		t.Errorf("child sigint: Exec returned %d, %v, output %q", status, err, output.String())
+	}
+}
+
+// TestInterruptLoop тестирует, что Ctrl+C без задания переднего плана прерывает цикл
+// из встроенных команд и завершает скрипт с кодом 130
+func TestInterruptLoop(t *testing.T) {
+	t.Parallel()
+	output := &bytes.Buffer{}
+	shell := NewShell(nil, output, output)
+
+	sigChan := make(chan os.Signal)
+	defer close(sigChan)
+	go shell.handleSignals(sigChan, output, false)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		sigChan <- syscall.SIGINT
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		shell.executeCommand("while true; do x=1; done; echo not-reached")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("interrupt loop: loop was not interrupted by SIGINT")
+	}
+	if output.String() != "" || !shell.exit || shell.status != 130 {
+		t.Errorf("interrupt loop: unexpected output %q, exit %v, status %d", output.String(), shell.exit, shell.status)
+	}
+}
+
+// TestSignalMessage тестирует сообщения о завершении процесса сигналом
+func TestSignalMessage(t *testing.T) {
+	t.Parallel()
+	const coreDumped = 0x80 // Бит WCOREDUMP в статусе wait4
+
+	tests := []struct {
+		ws   syscall.WaitStatus
+		want string
+	}{
+		{ws: syscall.WaitStatus(syscall.SIGKILL), want: "Killed"},
+		{ws: syscall.WaitStatus(syscall.SIGTERM), want: "Terminated"},
+		{ws: syscall.WaitStatus(syscall.SIGSEGV) | coreDumped, want: "Segmentation fault (core dumped)"},
+	}
+	for _, tt := range tests {
+		if got := signalMessage(tt.ws); got != tt.want {
+			t.Errorf("signalMessage(%d): expected %q, got %q", tt.ws, tt.want, got)
+		}
+	}
+
+	job := &Job{ID: 1, Command: "sleep 5", state: jobDone,
+		procs: []*process{{done: true, status: syscall.WaitStatus(syscall.SIGKILL)}}}
+	shell := NewShell(nil, nil, nil)
+	if got := shell.formatJob(job); got != "[1]   Killed       sleep 5" {
+		t.Errorf("formatJob: unexpected line %q", got)
+	}
+}
@@ -0,0 +1,195 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// signalNames - имена сигналов Linux без префикса SIG, индекс - номер сигнала
+var signalNames = []string{
+	1: "HUP", 2: "INT", 3: "QUIT", 4: "ILL", 5: "TRAP", 6: "ABRT", 7: "BUS", 8: "FPE",
+	9: "KILL", 10: "USR1", 11: "SEGV", 12: "USR2", 13: "PIPE", 14: "ALRM", 15: "TERM", 16: "STKFLT",
+	17: "CHLD", 18: "CONT", 19: "STOP", 20: "TSTP", 21: "TTIN", 22: "TTOU", 23: "URG", 24: "XCPU",
+	25: "XFSZ", 26: "VTALRM", 27: "PROF", 28: "WINCH", 29: "IO", 30: "PWR", 31: "SYS",
+}
+
+// signalName возвращает имя сигнала без префикса SIG ("" для неизвестного номера)
+func signalName(sig syscall.Signal) string {
+	if sig <= 0 || int(sig) >= len(signalNames) {
+		return ""
+	}
+	return signalNames[sig]
+}
+
+// signalMessage возвращает сообщение о завершении процесса сигналом, как в bash:
+// "Killed", "Segmentation fault (core dumped)"
+func signalMessage(ws syscall.WaitStatus) string {
+	message := ws.Signal().String()
+	if message != "" {
+		message = strings.ToUpper(message[:1]) + message[1:]
+	}
+	if ws.CoreDump() {
+		message += " (core dumped)"
+	}
+	return message
+}
+
+// parseSignal разбирает сигнал, заданный номером (9), именем (KILL, kill) или именем с префиксом (SIGKILL)
+func parseSignal(spec string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(spec); err == nil {
+		if n < 0 || n >= len(signalNames) {
+			return 0, fmt.Errorf("%s: неверный номер сигнала", spec)
+		}
+		return syscall.Signal(n), nil
+	}
+
+	name := strings.TrimPrefix(strings.ToUpper(spec), "SIG")
+	for n, known := range signalNames {
+		if known != "" && known == name {
+			return syscall.Signal(n), nil
+		}
+	}
+	// Синонимы из bash
+	switch name {
+	case "IOT":
+		return syscall.SIGABRT, nil
+	case "POLL":
+		return syscall.SIGIO, nil
+	}
+	return 0, fmt.Errorf("%s: неверное указание сигнала", spec)
+}
+
+// builtinKill посылает сигнал процессам, группам процессов и заданиям:
+//
+//	kill [-s сигнал | -n номер | -сигнал] pid | -pgid | %задание ...
+//	kill -l [сигнал | код завершения ...]
+//
+// По умолчанию посылается SIGTERM. Ошибка для одной цели не мешает остальным,
+// код завершения 1, если сигнал не удалось послать хотя бы одной цели
+func (s *Shell) builtinKill(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	args = args[1:]
+	sig := syscall.SIGTERM
+
+	if len(args) > 0 {
+		switch arg := args[0]; {
+		case arg == "-l" || arg == "-L":
+			return listSignals(args[1:], stdout, stderr)
+		case arg == "-s" || arg == "-n":
+			if len(args) < 2 {
+				fmt.Fprintf(stderr, "kill: %s: требуется аргумент\n", arg)
+				return 2
+			}
+			parsed, err := parseSignal(args[1])
+			if err != nil {
+				fmt.Fprintf(stderr, "kill: %v\n", err)
+				return 1
+			}
+			sig, args = parsed, args[2:]
+		case arg == "--":
+			args = args[1:]
+		case len(arg) > 1 && arg[0] == '-':
+			parsed, err := parseSignal(arg[1:])
+			if err != nil {
+				fmt.Fprintf(stderr, "kill: %v\n", err)
+				return 1
+			}
+			sig, args = parsed, args[1:]
+		}
+	}
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "kill: использование: kill [-s сигнал | -сигнал] pid | %задание ... или kill -l [сигнал]")
+		return 2
+	}
+
+	exitCode := 0
+	for _, target := range args {
+		if err := s.killTarget(target, sig); err != nil {
+			fmt.Fprintf(stderr, "kill: %v\n", err)
+			exitCode = 1
+		}
+	}
+	return exitCode
+}
+
+// killTarget посылает сигнал одной цели: процессу, группе процессов (-pgid) или заданию (%n)
+func (s *Shell) killTarget(target string, sig syscall.Signal) error {
+	if strings.HasPrefix(target, "%") {
+		job, err := s.findJob(target)
+		if err != nil {
+			return err
+		}
+		if err := job.signal(sig); err != nil {
+			return fmt.Errorf("%s: %v", target, killError(err))
+		}
+		// Остановленное задание не обработает сигнал, пока его не продолжат
+		if job.state == jobStopped && sig != syscall.SIGKILL && sig != syscall.SIGCONT {
+			job.signal(syscall.SIGCONT)
+		}
+		return nil
+	}
+
+	pid, err := strconv.Atoi(target)
+	if err != nil || pid == 0 {
+		return fmt.Errorf("%s: аргументы должны быть идентификаторами процессов или заданий", target)
+	}
+	if err := syscall.Kill(pid, sig); err != nil {
+		return fmt.Errorf("(%d) - %v", pid, killError(err))
+	}
+	return nil
+}
+
+// killError переводит ошибку kill(2) в сообщение для пользователя
+func killError(err error) string {
+	switch {
+	case errors.Is(err, syscall.ESRCH):
+		return "нет такого процесса"
+	case errors.Is(err, syscall.EPERM):
+		return "операция не позволена"
+	}
+	return err.Error()
+}
+
+// listSignals выполняет kill -l: без аргументов выводит таблицу сигналов, с аргументами -
+// имя сигнала по номеру (или коду завершения 128+N) и номер по имени
+func listSignals(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		var b strings.Builder
+		for n := 1; n < len(signalNames); n++ {
+			fmt.Fprintf(&b, "%2d) SIG%s", n, signalNames[n])
+			if n%5 == 0 || n == len(signalNames)-1 {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\t")
+			}
+		}
+		io.WriteString(stdout, b.String())
+		return 0
+	}
+
+	exitCode := 0
+	for _, arg := range args {
+		if n, err := strconv.Atoi(arg); err == nil {
+			if n > 128 {
+				n -= 128 // Код завершения процесса, убитого сигналом
+			}
+			if name := signalName(syscall.Signal(n)); name != "" {
+				fmt.Fprintln(stdout, name)
+				continue
+			}
+		} else if sig, err := parseSignal(arg); err == nil {
+			fmt.Fprintln(stdout, int(sig))
+			continue
+		}
+		fmt.Fprintf(stderr, "kill: %s: неверное указание сигнала\n", arg)
+		exitCode = 1
+	}
+	return exitCode
+}
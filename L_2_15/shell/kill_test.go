@@ -0,0 +1,73 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// TestParseSignal тестирует разбор сигнала по номеру и имени
+func TestParseSignal(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		spec    string
+		want    syscall.Signal
+		wantErr bool
+	}{
+		{spec: "9", want: syscall.SIGKILL},
+		{spec: "KILL", want: syscall.SIGKILL},
+		{spec: "SIGTERM", want: syscall.SIGTERM},
+		{spec: "int", want: syscall.SIGINT},
+		{spec: "0", want: 0},
+		{spec: "IOT", want: syscall.SIGABRT},
+		{spec: "BOGUS", wantErr: true},
+		{spec: "64", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSignal(tt.spec)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseSignal(%q): got %v, %v", tt.spec, got, err)
+		}
+	}
+}
+
+// TestBuiltinKill тестирует kill с именами сигналов, заданиями, несколькими целями и кодом завершения
+func TestBuiltinKill(t *testing.T) {
+	t.Parallel()
+	script := "sleep 5 &\n" +
+		"kill -s TERM %1; echo job=$?\n" +
+		"sleep 5 &\n" +
+		"kill -9 $! 999999 || echo failed\n" +
+		"kill -0 $$ && echo alive\n" +
+		"kill -BOGUS 1; echo bogus=$?\n" +
+		"kill -l 15 137 HUP\n"
+	output := &bytes.Buffer{}
+	shell := NewShell(bufio.NewReader(strings.NewReader(script)), output, output)
+	shell.Run()
+
+	result := output.String()
+	for _, want := range []string{
+		"job=0\n",
+		"kill: (999999) - нет такого процесса\nfailed\n",
+		"alive\n",
+		"kill: BOGUS: неверное указание сигнала\nbogus=1\n",
+		"TERM\nKILL\n1\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("kill: expected %q in output, got %q", want, result)
+		}
+	}
+
+	if len(shell.jobs) != 2 {
+		t.Fatalf("kill: expected 2 jobs, got %d", len(shell.jobs))
+	}
+	for _, job := range shell.jobs {
+		job.wait(true)
+		if !job.procs[0].status.Signaled() {
+			t.Errorf("kill: job %d was not killed by a signal", job.ID)
+		}
+	}
+}
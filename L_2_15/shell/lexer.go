@@ -0,0 +1,346 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind определяет тип лексемы
+type tokenKind int
+
+const (
+	tokEOF      tokenKind = iota // конец ввода
+	tokWord                      // слово (аргумент, имя команды, имя файла)
+	tokOp                        // оператор: |, ||, &, &&, ;, ;;, (, ), а также перенаправления <, >, >> и т.д.
+	tokNewline                   // перевод строки
+	tokIONumber                  // номер дескриптора перед перенаправлением: 2 в "2>file"
+)
+
+// token представляет одну лексему командной строки
+type token struct {
+	kind tokenKind
+	val  string // Исходный текст лексемы (для слов - вместе с кавычками)
+	pos  int    // Смещение начала лексемы в исходной строке (в байтах)
+}
+
+// operators перечисляет операторы shell'а; более длинные идут раньше,
+// чтобы "&&" не распознавался как два "&"
+var operators = []string{
+	"<<<", "<<-", "&>>",
+	"&&", "||", ";;", "<<", ">>", "<&", ">&", "&>",
+	"|", "&", ";", "(", ")", "<", ">",
+}
+
+// Pos - позиция в исходном тексте (строка и столбец начинаются с 1)
+type Pos struct {
+	Line int
+	Col  int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// SyntaxError описывает синтаксическую ошибку с позицией в исходном тексте
+type SyntaxError struct {
+	Pos        Pos
+	Msg        string
+	Incomplete bool // Ввод оборвался посередине конструкции (незакрытая кавычка и т.п.)
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("синтаксическая ошибка (%s): %s", e.Pos, e.Msg)
+}
+
+// Lexer разбивает командную строку на лексемы с учетом кавычек и экранирования
+type Lexer struct {
+	src string
+	off int // Текущее смещение в src
+}
+
+// NewLexer создает лексер для строки src
+func NewLexer(src string) *Lexer {
+	return &Lexer{src: src}
+}
+
+// posAt переводит смещение в байтах в позицию строка:столбец
+func (l *Lexer) posAt(off int) Pos {
+	if off > len(l.src) {
+		off = len(l.src)
+	}
+	before := l.src[:off]
+	line := strings.Count(before, "\n") + 1
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return Pos{Line: line, Col: utf8.RuneCountInString(before[lineStart:]) + 1}
+}
+
+// errorf создает синтаксическую ошибку в позиции off
+func (l *Lexer) errorf(off int, format string, args ...any) *SyntaxError {
+	return &SyntaxError{Pos: l.posAt(off), Msg: fmt.Sprintf(format, args...)}
+}
+
+// Next возвращает следующую лексему
+func (l *Lexer) Next() (token, error) {
+	if err := l.skipBlanks(); err != nil {
+		return token{}, err
+	}
+
+	if l.off >= len(l.src) {
+		return token{kind: tokEOF, pos: l.off}, nil
+	}
+
+	start := l.off
+	c := l.src[l.off]
+
+	if c == '\n' {
+		l.off++
+		return token{kind: tokNewline, val: "\n", pos: start}, nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.off:], op) {
+			l.off += len(op)
+			return token{kind: tokOp, val: op, pos: start}, nil
+		}
+	}
+
+	return l.readWord()
+}
+
+// skipBlanks пропускает пробелы, табуляции, продолжения строк (\ + перевод строки) и комментарии
+func (l *Lexer) skipBlanks() error {
+	for l.off < len(l.src) {
+		switch c := l.src[l.off]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			l.off++
+		case l.continuesAt(l.off):
+			return l.incomplete(l.off, "продолжение строки в конце ввода")
+		case c == '\\' && l.off+1 < len(l.src) && l.src[l.off+1] == '\n':
+			l.off += 2
+		case c == '#':
+			// Комментарий до конца строки; сам перевод строки остается лексемой
+			for l.off < len(l.src) && l.src[l.off] != '\n' {
+				l.off++
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// continuesAt сообщает, стоит ли в позиции off обратная косая черта, которая
+// переносит команду за конец ввода: следующая строка еще не прочитана
+func (l *Lexer) continuesAt(off int) bool {
+	rest := l.src[off:]
+	return rest == "\\" || rest == "\\\n"
+}
+
+// incomplete создает ошибку об оборвавшемся вводе: команда продолжается на следующей строке
+func (l *Lexer) incomplete(off int, format string, args ...any) *SyntaxError {
+	err := l.errorf(off, format, args...)
+	err.Incomplete = true
+	return err
+}
+
+// isMeta сообщает, завершает ли символ слово
+func isMeta(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '|', '&', ';', '<', '>', '(', ')':
+		return true
+	}
+	return false
+}
+
+// readWord читает слово целиком, сохраняя кавычки и экранирование в исходном виде:
+// они снимаются позже, при раскрытии слова (см. expand.go)
+func (l *Lexer) readWord() (token, error) {
+	start := l.off
+
+	for l.off < len(l.src) {
+		c := l.src[l.off]
+		if isMeta(c) {
+			break
+		}
+
+		switch c {
+		case '\\':
+			if l.continuesAt(l.off) {
+				return token{}, l.incomplete(l.off, "продолжение строки в конце ввода")
+			}
+			l.off += 2 // Экранированный символ входит в слово как есть
+		case '\'':
+			end := strings.IndexByte(l.src[l.off+1:], '\'')
+			if end < 0 {
+				return token{}, l.incomplete(l.off, "незакрытая одинарная кавычка")
+			}
+			l.off += end + 2
+		case '"':
+			if err := l.skipDoubleQuoted(); err != nil {
+				return token{}, err
+			}
+		case '$':
+			if err := l.skipParam(); err != nil {
+				return token{}, err
+			}
+		case '`':
+			if err := l.skipBackquote(); err != nil {
+				return token{}, err
+			}
+		default:
+			l.off++
+		}
+	}
+
+	if l.off > len(l.src) {
+		l.off = len(l.src)
+	}
+
+	// Число, за которым сразу следует < или >, - номер перенаправляемого дескриптора
+	word := l.src[start:l.off]
+	if l.off < len(l.src) && (l.src[l.off] == '<' || l.src[l.off] == '>') && isDigits(word) {
+		return token{kind: tokIONumber, val: word, pos: start}, nil
+	}
+
+	return token{kind: tokWord, val: word, pos: start}, nil
+}
+
+// isDigits сообщает, состоит ли непустая строка только из цифр
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// skipDoubleQuoted пропускает строку в двойных кавычках, начиная с открывающей кавычки
+func (l *Lexer) skipDoubleQuoted() error {
+	start := l.off
+	l.off++ // открывающая кавычка
+
+	for l.off < len(l.src) {
+		switch l.src[l.off] {
+		case '\\':
+			l.off += 2
+		case '"':
+			l.off++
+			return nil
+		case '$':
+			if err := l.skipParam(); err != nil {
+				return err
+			}
+		case '`':
+			if err := l.skipBackquote(); err != nil {
+				return err
+			}
+		default:
+			l.off++
+		}
+	}
+
+	return l.incomplete(start, "незакрытая двойная кавычка")
+}
+
+// skipParam пропускает подстановку, начинающуюся с $. Подстановки ${...}, $(...) и $((...))
+// входят в слово целиком, даже если содержат пробелы и операторы: ${X:-a b}, $(ls | wc -l)
+func (l *Lexer) skipParam() error {
+	start := l.off
+	l.off++ // $
+
+	if l.off < len(l.src) && l.src[l.off] == '(' {
+		return l.skipParens(start)
+	}
+	if l.off >= len(l.src) || l.src[l.off] != '{' {
+		return nil
+	}
+
+	depth := 0
+	for l.off < len(l.src) {
+		switch l.src[l.off] {
+		case '\\':
+			l.off += 2
+			continue
+		case '\'':
+			end := strings.IndexByte(l.src[l.off+1:], '\'')
+			if end < 0 {
+				l.off = len(l.src)
+				continue
+			}
+			l.off += end + 2
+			continue
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				l.off++
+				return nil
+			}
+		}
+		l.off++
+	}
+
+	return l.incomplete(start, "незакрытая подстановка ${")
+}
+
+// skipParens пропускает $(...) или $((...)), начиная с открывающей скобки,
+// с учетом вложенных скобок, кавычек и подстановок
+func (l *Lexer) skipParens(start int) error {
+	depth := 0
+	for l.off < len(l.src) {
+		switch l.src[l.off] {
+		case '\\':
+			l.off += 2
+			continue
+		case '\'':
+			end := strings.IndexByte(l.src[l.off+1:], '\'')
+			if end < 0 {
+				return l.incomplete(l.off, "незакрытая одинарная кавычка")
+			}
+			l.off += end + 2
+			continue
+		case '"':
+			if err := l.skipDoubleQuoted(); err != nil {
+				return err
+			}
+			continue
+		case '`':
+			if err := l.skipBackquote(); err != nil {
+				return err
+			}
+			continue
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				l.off++
+				return nil
+			}
+		}
+		l.off++
+	}
+
+	return l.incomplete(start, "незакрытая подстановка $(")
+}
+
+// skipBackquote пропускает подстановку команды в обратных кавычках `...`
+func (l *Lexer) skipBackquote() error {
+	start := l.off
+	for l.off++; l.off < len(l.src); l.off++ {
+		switch l.src[l.off] {
+		case '\\':
+			l.off++
+		case '`':
+			l.off++
+			return nil
+		}
+	}
+	return l.incomplete(start, "незакрытая обратная кавычка")
+}
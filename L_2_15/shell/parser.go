@@ -0,0 +1,749 @@
+package shell
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Грамматика (упрощенное подмножество POSIX shell):
+//
+//	program  := linebreak [list] linebreak EOF
+//	list     := and_or ((';' | '&' | NEWLINE) linebreak and_or)* [';' | '&']
+//	and_or   := pipeline (('&&' | '||') linebreak pipeline)*
+//	pipeline := command ('|' linebreak command)*
+//	command  := simple | if | while | until | for | case | funcdef
+//	simple   := (ASSIGNMENT | redirect)* (WORD | redirect)*   (хотя бы один элемент)
+//	redirect := [IO_NUMBER] ('<' | '>' | '>>' | '<&' | '>&' | '&>' | '&>>' | '<<' | '<<-' | '<<<') WORD
+//
+// Тело here-document (<<) начинается со строки, следующей за командой,
+// и заканчивается строкой-ограничителем
+//
+//	if       := 'if' list 'then' list ('elif' list 'then' list)* ['else' list] 'fi'
+//	while    := ('while' | 'until') list 'do' list 'done'
+//	for      := 'for' NAME linebreak ['in' WORD* (';' | NEWLINE)] linebreak 'do' list 'done'
+//	case     := 'case' WORD linebreak 'in' linebreak (item ';;' linebreak)* [item] 'esac'
+//	item     := ['('] WORD ('|' WORD)* ')' linebreak [list]
+//	funcdef  := NAME '(' ')' linebreak '{' list '}'
+//
+// Зарезервированные слова (if, then, do, ...) распознаются только в начале команды
+// и только без кавычек: "echo if" и "'if'" - обычные слова
+
+// terminators - зарезервированные слова, которые завершают вложенный список команд
+var terminators = map[string]bool{
+	"then": true, "elif": true, "else": true, "fi": true,
+	"do": true, "done": true, "esac": true, "}": true,
+}
+
+// Parser строит синтаксическое дерево из лексем
+type Parser struct {
+	lex      *Lexer
+	tok      token         // Текущая (еще не разобранная) лексема
+	prevEnd  int           // Смещение конца предыдущей лексемы
+	heredocs []*IORedirect // Here-documents, тела которых начнутся со следующей строки
+
+	aliases       map[string]string       // Алиасы, раскрываемые в начале простых команд
+	activeAliases map[string]*activeAlias // Алиасы, значения которых сейчас разбираются
+}
+
+// Parse разбирает строку src целиком
+func Parse(src string) (*List, error) {
+	return ParseAliases(src, nil)
+}
+
+// ParseAliases разбирает строку src, раскрывая алиасы из aliases
+func ParseAliases(src string, aliases map[string]string) (*List, error) {
+	p := &Parser{lex: NewLexer(src), aliases: aliases, activeAliases: make(map[string]*activeAlias)}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return p.parseProgram()
+}
+
+// next переходит к следующей лексеме
+func (p *Parser) next() error {
+	tok, err := p.lex.Next()
+	if err != nil {
+		return err
+	}
+	p.prevEnd = p.tok.pos + len(p.tok.val)
+	p.tok = tok
+
+	if len(p.heredocs) > 0 {
+		switch tok.kind {
+		case tokNewline:
+			return p.readHeredocs()
+		case tokEOF:
+			return p.lex.incomplete(tok.pos, "here-document: ожидается строка %q", p.heredocs[0].HereDoc.Delim)
+		}
+	}
+	return nil
+}
+
+// readHeredocs читает тела отложенных here-documents; лексер стоит в начале строки
+// после команды. Строки тел пропускаются лексером
+func (p *Parser) readHeredocs() error {
+	l := p.lex
+	for _, r := range p.heredocs {
+		doc := r.HereDoc
+		var body strings.Builder
+		for {
+			if l.off >= len(l.src) {
+				return l.incomplete(l.off, "here-document: ожидается строка %q", doc.Delim)
+			}
+			end := strings.IndexByte(l.src[l.off:], '\n')
+			line, next := l.src[l.off:], len(l.src)
+			if end >= 0 {
+				line, next = l.src[l.off:l.off+end], l.off+end+1
+			}
+			if doc.StripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+			l.off = next
+
+			if line == doc.Delim {
+				break
+			}
+			if end < 0 {
+				// Последняя строка без перевода строки: ввод еще не дочитан
+				return l.incomplete(l.off, "here-document: ожидается строка %q", doc.Delim)
+			}
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+		doc.Body = body.String()
+	}
+	p.heredocs = nil
+	return nil
+}
+
+// isOp сообщает, является ли текущая лексема оператором op
+func (p *Parser) isOp(op string) bool {
+	return p.tok.kind == tokOp && p.tok.val == op
+}
+
+// skipNewlines пропускает переводы строк (linebreak в грамматике)
+func (p *Parser) skipNewlines() error {
+	for p.tok.kind == tokNewline {
+		if err := p.next(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unexpected возвращает ошибку о неожиданной текущей лексеме
+func (p *Parser) unexpected() error {
+	switch p.tok.kind {
+	case tokEOF:
+		err := p.lex.errorf(p.tok.pos, "неожиданный конец ввода")
+		err.Incomplete = true
+		return err
+	case tokNewline:
+		return p.lex.errorf(p.tok.pos, "неожиданный перевод строки")
+	default:
+		return p.lex.errorf(p.tok.pos, "неожиданный токен %q", p.tok.val)
+	}
+}
+
+// isWord сообщает, является ли текущая лексема словом word (без кавычек)
+func (p *Parser) isWord(word string) bool {
+	return p.tok.kind == tokWord && p.tok.val == word
+}
+
+// expect пропускает зарезервированное слово word или возвращает ошибку
+func (p *Parser) expect(word string) error {
+	if !p.isWord(word) {
+		return p.unexpected()
+	}
+	return p.next()
+}
+
+// atListEnd сообщает, завершается ли в текущей позиции список команд
+func (p *Parser) atListEnd() bool {
+	switch p.tok.kind {
+	case tokEOF:
+		return true
+	case tokOp:
+		return p.tok.val == ";;" || p.tok.val == ")"
+	case tokWord:
+		return terminators[p.tok.val]
+	}
+	return false
+}
+
+// parseProgram разбирает последовательность команд до конца ввода
+func (p *Parser) parseProgram() (*List, error) {
+	list, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.unexpected()
+	}
+	return list, nil
+}
+
+// parseList разбирает цепочки, разделенные ;, & и переводами строк,
+// до конца ввода или зарезервированного слова, завершающего составную команду
+func (p *Parser) parseList() (*List, error) {
+	list := &List{}
+
+	for {
+		if err := p.skipNewlines(); err != nil {
+			return nil, err
+		}
+		if p.atListEnd() {
+			return list, nil
+		}
+
+		item, err := p.parseAndOr()
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, item)
+
+		// Без разделителя список заканчивается; что допустимо дальше, решает вызывающий
+		switch {
+		case p.isOp("&"):
+			item.Background = true
+		case p.isOp(";") || p.tok.kind == tokNewline:
+		default:
+			return list, nil
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseCompoundList разбирает непустой список команд внутри составной команды
+func (p *Parser) parseCompoundList() (*List, error) {
+	list, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, p.unexpected()
+	}
+	return list, nil
+}
+
+// parseAndOr разбирает цепочку конвейеров, соединенных && и ||
+func (p *Parser) parseAndOr() (*AndOr, error) {
+	start := p.tok.pos
+	pipeline, err := p.parsePipeline()
+	if err != nil {
+		return nil, err
+	}
+	andOr := &AndOr{Pipelines: []*Pipeline{pipeline}}
+
+	for p.isOp("&&") || p.isOp("||") {
+		andOr.Ops = append(andOr.Ops, p.tok.val)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		// После && и || команда может продолжаться на следующей строке
+		if err := p.skipNewlines(); err != nil {
+			return nil, err
+		}
+
+		pipeline, err := p.parsePipeline()
+		if err != nil {
+			return nil, err
+		}
+		andOr.Pipelines = append(andOr.Pipelines, pipeline)
+	}
+
+	andOr.Text = p.lex.src[start:p.prevEnd]
+	return andOr, nil
+}
+
+// parsePipeline разбирает команды, соединенные через |, с необязательным ! в начале
+func (p *Parser) parsePipeline() (*Pipeline, error) {
+	start := p.tok.pos
+	pipeline := &Pipeline{}
+
+	if p.isWord("!") {
+		pipeline.Negated = true
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		pipeline.Commands = append(pipeline.Commands, cmd)
+
+		if !p.isOp("|") {
+			pipeline.Text = p.lex.src[start:p.prevEnd]
+			return pipeline, nil
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if err := p.skipNewlines(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isRedirectOp сообщает, является ли текущая лексема оператором перенаправления
+func (p *Parser) isRedirectOp() bool {
+	if p.tok.kind != tokOp {
+		return false
+	}
+	switch p.tok.val {
+	case "<", ">", ">>", "<&", ">&", "&>", "&>>", "<<", "<<-", "<<<":
+		return true
+	}
+	return false
+}
+
+// parseCommand разбирает составную или простую команду. После составной команды
+// могут идти перенаправления, которые действуют на всю команду
+func (p *Parser) parseCommand() (Command, error) {
+	if err := p.expandAlias(true); err != nil {
+		return nil, err
+	}
+
+	var cmd Command
+	var err error
+	switch {
+	case p.isOp("("):
+		cmd, err = p.parseSubshell()
+	case p.isWord("{"):
+		cmd, err = p.parseBraceGroup()
+	case p.isWord("if"):
+		cmd, err = p.parseIf()
+	case p.isWord("while") || p.isWord("until"):
+		cmd, err = p.parseWhile()
+	case p.isWord("for"):
+		cmd, err = p.parseFor()
+	case p.isWord("case"):
+		cmd, err = p.parseCase()
+	case p.tok.kind == tokWord && terminators[p.tok.val]:
+		return nil, p.unexpected()
+	default:
+		return p.parseSimpleCommand()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	redirected := &Redirected{Command: cmd}
+	for p.isRedirectOp() || p.tok.kind == tokIONumber {
+		redirect, err := p.parseRedirect()
+		if err != nil {
+			return nil, err
+		}
+		redirected.Redirects = append(redirected.Redirects, redirect)
+	}
+	if len(redirected.Redirects) == 0 {
+		return cmd, nil
+	}
+	return redirected, nil
+}
+
+// parseBraceGroup разбирает группу команд { LIST; }
+func (p *Parser) parseBraceGroup() (Command, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseCompoundList()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return &BraceGroup{Body: body}, nil
+}
+
+// parseSubshell разбирает команды в круглых скобках ( LIST )
+func (p *Parser) parseSubshell() (Command, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	body, err := p.parseCompoundList()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp(")") {
+		return nil, p.unexpected()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return &Subshell{Body: body}, nil
+}
+
+// parseSimpleCommand разбирает простую команду: слова вперемешку с перенаправлениями.
+// NAME() после первого слова начинает определение функции
+func (p *Parser) parseSimpleCommand() (Command, error) {
+	cmd := &SimpleCommand{}
+
+	for {
+		// Имя команды (в том числе после присваиваний) может быть алиасом
+		if err := p.expandAlias(len(cmd.Args) == 0); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case p.tok.kind == tokWord && len(cmd.Args) == 0 && isAssignment(p.tok.val):
+			// NAME=value до имени команды - присваивание, после - обычный аргумент
+			cmd.Assigns = append(cmd.Assigns, Word{Raw: p.tok.val, Pos: p.tok.pos})
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+
+		case p.tok.kind == tokWord:
+			first := len(cmd.Assigns) == 0 && len(cmd.Args) == 0 && len(cmd.Redirects) == 0
+			cmd.Args = append(cmd.Args, Word{Raw: p.tok.val, Pos: p.tok.pos})
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if first && p.isOp("(") {
+				return p.parseFuncDef(cmd.Args[0])
+			}
+
+		case p.isRedirectOp() || p.tok.kind == tokIONumber:
+			redirect, err := p.parseRedirect()
+			if err != nil {
+				return nil, err
+			}
+			cmd.Redirects = append(cmd.Redirects, redirect)
+
+		default:
+			if len(cmd.Assigns) == 0 && len(cmd.Args) == 0 && len(cmd.Redirects) == 0 {
+				return nil, p.unexpected()
+			}
+			return cmd, nil
+		}
+	}
+}
+
+// parseRedirect разбирает перенаправление: номер дескриптора, оператор и слово после него
+func (p *Parser) parseRedirect() (*IORedirect, error) {
+	fd := -1
+	if p.tok.kind == tokIONumber {
+		n, err := strconv.Atoi(p.tok.val)
+		if err != nil || n > maxRedirectFd {
+			return nil, p.lex.errorf(p.tok.pos, "слишком большой номер дескриптора %s", p.tok.val)
+		}
+		fd = n
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if !p.isRedirectOp() || p.isOp("&>") || p.isOp("&>>") {
+			return nil, p.unexpected()
+		}
+	}
+
+	op := p.tok.val
+	if fd < 0 {
+		fd = 1
+		if strings.HasPrefix(op, "<") {
+			fd = 0
+		}
+	}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokWord {
+		return nil, p.unexpected()
+	}
+	redirect := &IORedirect{Fd: fd, Op: op, Target: Word{Raw: p.tok.val, Pos: p.tok.pos}}
+
+	if op == "<<" || op == "<<-" {
+		// Тело here-document будет прочитано после перевода строки
+		delim, quoted := unquoteDelim(p.tok.val)
+		redirect.HereDoc = &HereDoc{Delim: delim, Quoted: quoted, StripTabs: op == "<<-"}
+		p.heredocs = append(p.heredocs, redirect)
+	}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	return redirect, nil
+}
+
+// maxRedirectFd - наибольший номер дескриптора, который можно перенаправить
+const maxRedirectFd = 9
+
+// unquoteDelim снимает кавычки и экранирование с ограничителя here-document.
+// Если они были, тело here-document не раскрывается
+func unquoteDelim(raw string) (string, bool) {
+	if !strings.ContainsAny(raw, `'"\`) {
+		return raw, false
+	}
+	var delim strings.Builder
+	for i := 0; i < len(raw); i++ {
+		switch c := raw[i]; c {
+		case '\'', '"':
+		case '\\':
+			if i+1 < len(raw) {
+				i++
+				delim.WriteByte(raw[i])
+			}
+		default:
+			delim.WriteByte(c)
+		}
+	}
+	return delim.String(), true
+}
+
+// parseIf разбирает if ... then ... [elif ... then ...] [else ...] fi
+func (p *Parser) parseIf() (Command, error) {
+	clause := &IfClause{}
+
+	for p.isWord("if") || p.isWord("elif") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		cond, err := p.parseCompoundList()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("then"); err != nil {
+			return nil, err
+		}
+		body, err := p.parseCompoundList()
+		if err != nil {
+			return nil, err
+		}
+		clause.Conds = append(clause.Conds, cond)
+		clause.Bodies = append(clause.Bodies, body)
+	}
+
+	if p.isWord("else") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		body, err := p.parseCompoundList()
+		if err != nil {
+			return nil, err
+		}
+		clause.Else = body
+	}
+
+	if err := p.expect("fi"); err != nil {
+		return nil, err
+	}
+	return clause, nil
+}
+
+// parseWhile разбирает while/until ... do ... done
+func (p *Parser) parseWhile() (Command, error) {
+	clause := &WhileClause{Until: p.isWord("until")}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	cond, err := p.parseCompoundList()
+	if err != nil {
+		return nil, err
+	}
+	clause.Cond = cond
+
+	if clause.Body, err = p.parseDoGroup(); err != nil {
+		return nil, err
+	}
+	return clause, nil
+}
+
+// parseDoGroup разбирает тело цикла do ... done
+func (p *Parser) parseDoGroup() (*List, error) {
+	if err := p.expect("do"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseCompoundList()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("done"); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// parseFor разбирает for NAME [in WORDS]; do ... done
+func (p *Parser) parseFor() (Command, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokWord || !isName(p.tok.val) {
+		if p.tok.kind == tokWord {
+			return nil, p.lex.errorf(p.tok.pos, "неверное имя переменной цикла %q", p.tok.val)
+		}
+		return nil, p.unexpected()
+	}
+	clause := &ForClause{Var: p.tok.val}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	// for NAME; do - то же, что for NAME in "$@"; do
+	if p.isOp(";") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.skipNewlines(); err != nil {
+		return nil, err
+	}
+
+	if p.isWord("in") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		clause.Words = []Word{}
+		for p.tok.kind == tokWord {
+			clause.Words = append(clause.Words, Word{Raw: p.tok.val, Pos: p.tok.pos})
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+		if !p.isOp(";") && p.tok.kind != tokNewline {
+			return nil, p.unexpected()
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if err := p.skipNewlines(); err != nil {
+			return nil, err
+		}
+	} else {
+		clause.Words = []Word{{Raw: `"$@"`, Pos: p.tok.pos}}
+	}
+
+	body, err := p.parseDoGroup()
+	if err != nil {
+		return nil, err
+	}
+	clause.Body = body
+	return clause, nil
+}
+
+// parseCase разбирает case WORD in PATTERN) LIST ;; ... esac
+func (p *Parser) parseCase() (Command, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokWord {
+		return nil, p.unexpected()
+	}
+	clause := &CaseClause{Word: Word{Raw: p.tok.val, Pos: p.tok.pos}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if err := p.skipNewlines(); err != nil {
+		return nil, err
+	}
+	if err := p.expect("in"); err != nil {
+		return nil, err
+	}
+
+	for {
+		if err := p.skipNewlines(); err != nil {
+			return nil, err
+		}
+		if p.isWord("esac") {
+			break
+		}
+
+		item, err := p.parseCaseItem()
+		if err != nil {
+			return nil, err
+		}
+		clause.Items = append(clause.Items, item)
+
+		// ;; перед esac у последней ветки можно опустить
+		if !p.isOp(";;") {
+			break
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expect("esac"); err != nil {
+		return nil, err
+	}
+	return clause, nil
+}
+
+// parseCaseItem разбирает ветку case: [(] PATTERN [| PATTERN]... ) LIST
+func (p *Parser) parseCaseItem() (*CaseItem, error) {
+	if p.isOp("(") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	item := &CaseItem{}
+	for {
+		if p.tok.kind != tokWord {
+			return nil, p.unexpected()
+		}
+		item.Patterns = append(item.Patterns, Word{Raw: p.tok.val, Pos: p.tok.pos})
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if !p.isOp("|") {
+			break
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !p.isOp(")") {
+		return nil, p.unexpected()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	// Тело ветки может быть пустым: "*) ;;"
+	body, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	item.Body = body
+	return item, nil
+}
+
+// parseFuncDef разбирает определение функции; имя уже прочитано, текущая лексема - (
+func (p *Parser) parseFuncDef(name Word) (Command, error) {
+	if !isName(name.Raw) {
+		return nil, p.lex.errorf(name.Pos, "неверное имя функции %q", name.Raw)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if !p.isOp(")") {
+		return nil, p.unexpected()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if err := p.skipNewlines(); err != nil {
+		return nil, err
+	}
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	body, err := p.parseCompoundList()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return &FuncDef{Name: name.Raw, Body: body}, nil
+}
@@ -0,0 +1,148 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestLexer тестирует разбиение строки на лексемы
+func TestLexer(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "words and operators",
+			input: "ls -la|grep go&&echo ok",
+			want:  []string{"ls", "-la", "|", "grep", "go", "&&", "echo", "ok"},
+		},
+		{
+			name:  "quotes keep operators",
+			input: `echo "a|b" 'c && d' e\>f`,
+			want:  []string{"echo", `"a|b"`, "'c && d'", `e\>f`},
+		},
+		{
+			name:  "redirects",
+			input: "sort<in>>out",
+			want:  []string{"sort", "<", "in", ">>", "out"},
+		},
+		{
+			name:  "descriptor numbers",
+			input: "cmd 2>err 2>&1 &>all 12 <<<x",
+			want:  []string{"cmd", "2", ">", "err", "2", ">&", "1", "&>", "all", "12", "<<<", "x"},
+		},
+		{
+			name:  "comment",
+			input: "echo hi # comment | not a pipe",
+			want:  []string{"echo", "hi"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			lex := NewLexer(tt.input)
+			var got []string
+			for {
+				tok, err := lex.Next()
+				if err != nil {
+					t.Fatalf("Next: unexpected error: %v", err)
+				}
+				if tok.kind == tokEOF {
+					break
+				}
+				got = append(got, tok.val)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lexer: expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestParseSyntaxErrors тестирует позиции в сообщениях о синтаксических ошибках
+func TestParseSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		pos        Pos
+		incomplete bool
+	}{
+		{name: "leading operator", input: "&& ls", pos: Pos{1, 1}},
+		{name: "double pipe operator", input: "ls | | wc", pos: Pos{1, 6}},
+		{name: "missing file", input: "echo a > ", pos: Pos{1, 10}, incomplete: true},
+		{name: "trailing and", input: "true &&", pos: Pos{1, 8}, incomplete: true},
+		{name: "unclosed quote", input: "echo 'abc", pos: Pos{1, 6}, incomplete: true},
+		{name: "second line", input: "echo a\n; ls", pos: Pos{2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := Parse(tt.input)
+
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse(%q): expected SyntaxError, got %v", tt.input, err)
+			}
+			if syntaxErr.Pos != tt.pos {
+				t.Errorf("Parse(%q): expected position %v, got %v", tt.input, tt.pos, syntaxErr.Pos)
+			}
+			if syntaxErr.Incomplete != tt.incomplete {
+				t.Errorf("Parse(%q): expected incomplete=%v, got %v", tt.input, tt.incomplete, syntaxErr.Incomplete)
+			}
+		})
+	}
+}
+
+// TestExpandWord тестирует снятие кавычек, экранирование и разбиение подстановок на поля
+func TestExpandWord(t *testing.T) {
+	os.Setenv("EXPAND_TEST", "one two")
+	defer os.Unsetenv("EXPAND_TEST")
+
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{raw: `'a b'`, want: []string{"a b"}},
+		{raw: `"a|b"`, want: []string{"a|b"}},
+		{raw: `a\ b`, want: []string{"a b"}},
+		{raw: `""`, want: []string{""}},
+		{raw: `$EXPAND_TEST`, want: []string{"one", "two"}},
+		{raw: `"$EXPAND_TEST"`, want: []string{"one two"}},
+		{raw: `'$EXPAND_TEST'`, want: []string{"$EXPAND_TEST"}},
+		{raw: `"\$EXPAND_TEST"`, want: []string{"$EXPAND_TEST"}},
+		{raw: `x${EXPAND_TEST}y`, want: []string{"xone", "twoy"}},
+		{raw: `$EXPAND_TEST_UNSET`, want: nil},
+	}
+
+	shell := NewShell(nil, nil, nil)
+	for _, tt := range tests {
+		got := shell.expandWord(Word{Raw: tt.raw})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandWord(%s): expected %q, got %q", tt.raw, tt.want, got)
+		}
+	}
+}
+
+// TestQuotedOperators тестирует выполнение команд с операторами внутри кавычек
+func TestQuotedOperators(t *testing.T) {
+	t.Parallel()
+	input := bufio.NewReader(strings.NewReader("echo \"a|b\" 'c && d' \"x > y\"\nexit\n"))
+	output := &bytes.Buffer{}
+
+	shell := NewShell(input, output, os.Stderr)
+	shell.Run()
+
+	result := output.String()
+	if !strings.Contains(result, "a|b c && d x > y") {
+		t.Errorf("quoted operators: expected 'a|b c && d x > y' in output, got %q", result)
+	}
+}
@@ -0,0 +1,158 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Приглашения по умолчанию, если переменные PS1 и PS2 не заданы
+const (
+	defaultPS1 = `[\H] \w \$ `
+	defaultPS2 = "> "
+)
+
+// currentUser возвращает текущего пользователя. Поиск в /etc/passwd выполняется
+// один раз: приглашение формируется перед каждой командой
+var currentUser = sync.OnceValues(user.Current)
+
+// getPrompt возвращает основное приглашение ($PS1)
+func (s *Shell) getPrompt() string {
+	return s.prompt("PS1", defaultPS1)
+}
+
+// prompt раскрывает приглашение из переменной name (или value по умолчанию, если она не задана)
+func (s *Shell) prompt(name, value string) string {
+	if ps, ok := s.lookupVar(name); ok {
+		value = ps
+	}
+	return s.expandPrompt(value)
+}
+
+// expandPrompt раскрывает escape-последовательности приглашения, как bash:
+//
+//	\u  имя пользователя         \h  имя хоста до первой точки   \H  полное имя хоста
+//	\w  текущая директория (~)   \W  последний элемент \w        \$  # для root, иначе $
+//	\t  время ЧЧ:ММ:СС           \T  то же в 12-часовом формате  \@  время ЧЧ:ММ am/pm
+//	\A  время ЧЧ:ММ              \d  дата "Tue May 26"           \s  имя shell'а
+//	\j  количество заданий       \!  номер команды в истории     \?  код завершения ($?)
+//	\n  перевод строки           \e  ESC                         \a  BEL
+//	\nnn  символ с восьмеричным кодом   \\  обратная косая черта
+//
+// \[ и \] (границы непечатаемых символов в bash) удаляются. После этого раскрываются
+// подстановки $VAR, $(...) и `...`
+func (s *Shell) expandPrompt(ps string) string {
+	var b strings.Builder
+	now := time.Now()
+
+	for i := 0; i < len(ps); i++ {
+		c := ps[i]
+		if c == '$' || c == '`' {
+			var value string
+			var next int
+			var ok bool
+			if c == '$' {
+				value, next, ok = s.substitute(ps, i)
+			} else {
+				value, next, ok = s.backquoteSubst(ps, i)
+			}
+			if ok && next > i+1 {
+				b.WriteString(value)
+				i = next - 1
+				continue
+			}
+		}
+		if c != '\\' || i+1 >= len(ps) {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch c := ps[i]; c {
+		case 'u':
+			if u, err := currentUser(); err == nil {
+				b.WriteString(u.Username)
+			}
+		case 'h':
+			host, _, _ := strings.Cut(getHostname(), ".")
+			b.WriteString(host)
+		case 'H':
+			b.WriteString(getHostname())
+		case 'w':
+			b.WriteString(s.promptDir())
+		case 'W':
+			dir := s.promptDir()
+			if dir != "~" && dir != "/" {
+				dir = filepath.Base(dir)
+			}
+			b.WriteString(dir)
+		case '$':
+			if os.Geteuid() == 0 {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('$')
+			}
+		case 't':
+			b.WriteString(now.Format("15:04:05"))
+		case 'T':
+			b.WriteString(now.Format("03:04:05"))
+		case '@':
+			b.WriteString(now.Format("03:04 PM"))
+		case 'A':
+			b.WriteString(now.Format("15:04"))
+		case 'd':
+			b.WriteString(now.Format("Mon Jan 02"))
+		case 's':
+			b.WriteString(filepath.Base(s.name))
+		case 'j':
+			b.WriteString(strconv.Itoa(len(s.jobs)))
+		case '!':
+			n := 1
+			if s.history != nil {
+				n = s.history.Len() + 1
+			}
+			b.WriteString(strconv.Itoa(n))
+		case '?':
+			b.WriteString(strconv.Itoa(s.status))
+		case 'n':
+			b.WriteByte('\n')
+		case 'e':
+			b.WriteByte(0x1b)
+		case 'a':
+			b.WriteByte('\a')
+		case '\\':
+			b.WriteByte('\\')
+		case '[', ']':
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			end := i
+			for end < len(ps) && end-i < 3 && ps[end] >= '0' && ps[end] <= '7' {
+				end++
+			}
+			n, _ := strconv.ParseUint(ps[i:end], 8, 8)
+			b.WriteByte(byte(n))
+			i = end - 1
+		default:
+			fmt.Fprintf(&b, "\\%c", c)
+		}
+	}
+	return b.String()
+}
+
+// promptDir возвращает текущую директорию, в которой домашняя директория заменена на ~
+func (s *Shell) promptDir() string {
+	return s.tildePath(s.cwd)
+}
+
+// tildePath заменяет домашнюю директорию в начале пути на ~
+func (s *Shell) tildePath(dir string) string {
+	home := strings.TrimSuffix(s.homeDir(), "/")
+	if home != "" && (dir == home || strings.HasPrefix(dir, home+"/")) {
+		return "~" + dir[len(home):]
+	}
+	return dir
+}
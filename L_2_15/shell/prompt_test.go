@@ -0,0 +1,74 @@
+package shell
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+// TestExpandPrompt тестирует escape-последовательности и подстановки в приглашении
+func TestExpandPrompt(t *testing.T) {
+	t.Parallel()
+	shell := NewShell(nil, nil, nil)
+	shell.setVar("HOME", "/home/user")
+	shell.setVar("NAME", "value")
+	shell.status = 3
+
+	user, err := currentUser()
+	if err != nil {
+		t.Fatalf("currentUser: %v", err)
+	}
+	sign := "$"
+	if os.Geteuid() == 0 {
+		sign = "#"
+	}
+
+	tests := []struct {
+		cwd  string
+		ps   string
+		want string
+	}{
+		{"/home/user/src/app", `\w`, "~/src/app"},
+		{"/home/user", `\w \W`, "~ ~"},
+		{"/home/username", `\w`, "/home/username"},
+		{"/", `\W`, "/"},
+		{"/tmp/dir", `\W`, "dir"},
+		{"/tmp", `\u\$`, user.Username + sign},
+		{"/tmp", `[\?] `, "[3] "},
+		{"/tmp", `\[\e[1m\]x\[\e[0m\]`, "\x1b[1mx\x1b[0m"},
+		{"/tmp", `a\nb\\c\101`, "a\nb\\cA"},
+		{"/tmp", `$NAME $(echo sub) \$NAME`, "value sub " + sign + "NAME"},
+		{"/tmp", `\j \q $`, "0 \\q $"},
+	}
+	for _, tt := range tests {
+		shell.cwd = tt.cwd
+		if got := shell.expandPrompt(tt.ps); got != tt.want {
+			t.Errorf("expandPrompt(%q) in %s: expected %q, got %q", tt.ps, tt.cwd, tt.want, got)
+		}
+	}
+
+	if got := shell.expandPrompt(`\t`); !regexp.MustCompile(`^\d\d:\d\d:\d\d$`).MatchString(got) {
+		t.Errorf(`expandPrompt(\t): unexpected time %q`, got)
+	}
+	if got := shell.expandPrompt(`\h`); got == "" || regexp.MustCompile(`\.`).MatchString(got) {
+		t.Errorf(`expandPrompt(\h): unexpected host %q`, got)
+	}
+	if got := shell.expandPrompt(`\!`); got != strconv.Itoa(1) {
+		t.Errorf(`expandPrompt(\!): expected 1 without history, got %q`, got)
+	}
+}
+
+// TestPromptVars тестирует приглашения из переменных PS1 и PS2
+func TestPromptVars(t *testing.T) {
+	t.Parallel()
+	shell := NewShell(nil, nil, nil)
+	if got := shell.prompt("PS2", defaultPS2); got != "> " {
+		t.Errorf("default PS2: expected %q, got %q", "> ", got)
+	}
+
+	shell.setVar("PS1", "ps1> ")
+	if got := shell.getPrompt(); got != "ps1> " {
+		t.Errorf("PS1: expected %q, got %q", "ps1> ", got)
+	}
+}
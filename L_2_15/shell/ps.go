@@ -0,0 +1,397 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// procDir - файловая система /proc, из которой ps читает сведения о процессах
+const procDir = "/proc"
+
+// clockTicks - число тиков часов в секунду (USER_HZ), в которых /proc отдает время процессов.
+// В Linux оно равно 100 на всех распространенных архитектурах
+const clockTicks = 100
+
+// procInfo - сведения о процессе из /proc/[pid]/stat, status и cmdline
+type procInfo struct {
+	pid, ppid    int
+	uid          int
+	user         string
+	state        string // Состояние с дополнительными признаками, как в колонке STAT
+	cpu          float64
+	rss, vsz     int64 // КиБ
+	comm, cmdArg string
+}
+
+// psColumn - колонка вывода ps
+type psColumn struct {
+	header string
+	right  bool // Выравнивание по правому краю (числа)
+	value  func(p *procInfo) string
+}
+
+// psColumns - колонки, которые можно выбрать через ps -o
+var psColumns = map[string]psColumn{
+	"pid":  {"PID", true, func(p *procInfo) string { return strconv.Itoa(p.pid) }},
+	"ppid": {"PPID", true, func(p *procInfo) string { return strconv.Itoa(p.ppid) }},
+	"uid":  {"UID", true, func(p *procInfo) string { return strconv.Itoa(p.uid) }},
+	"user": {"USER", false, func(p *procInfo) string { return p.user }},
+	"stat": {"STAT", false, func(p *procInfo) string { return p.state }},
+	"%cpu": {"%CPU", true, func(p *procInfo) string { return strconv.FormatFloat(p.cpu, 'f', 1, 64) }},
+	"rss":  {"RSS", true, func(p *procInfo) string { return strconv.FormatInt(p.rss, 10) }},
+	"vsz":  {"VSZ", true, func(p *procInfo) string { return strconv.FormatInt(p.vsz, 10) }},
+	"comm": {"COMMAND", false, func(p *procInfo) string { return p.comm }},
+	"cmd":  {"CMD", false, func(p *procInfo) string { return p.cmdArg }},
+}
+
+// psAliases - другие имена колонок, принятые в procps
+var psAliases = map[string]string{
+	"pcpu": "%cpu", "args": "cmd", "command": "cmd", "ucomm": "comm", "euid": "uid", "euser": "user", "rssize": "rss",
+}
+
+// psField - выбранная колонка и ее заголовок (ps -o pid=ID меняет заголовок)
+type psField struct {
+	name, header string
+}
+
+// psDefaultColumns - колонки ps без -o
+var psDefaultColumns = []string{"pid", "ppid", "user", "stat", "%cpu", "rss", "cmd"}
+
+// builtinPs выводит список процессов, читая /proc:
+//
+//	ps              процессы текущего пользователя
+//	ps -e, ps -A    все процессы (ps aux и ps ax - то же самое)
+//	ps -u user,...  процессы пользователей (имя или UID)
+//	ps -p pid,...   процессы с указанными PID
+//	ps -o col,...   выбор колонок: pid, ppid, uid, user, stat, %cpu, rss, vsz, comm, cmd;
+//	                col=Заголовок меняет заголовок, а если все заголовки пусты (-o pid=), строка заголовков не выводится
+//
+// Условия -u и -p объединяются: выводится процесс, подходящий хотя бы под одно
+func (s *Shell) builtinPs(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	all := false
+	var users []string
+	var pids []int
+	var columns []psField
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		// BSD-синтаксис без дефиса: ps aux, ps ax
+		if !strings.HasPrefix(arg, "-") {
+			if strings.Trim(arg, "aux") != "" {
+				fmt.Fprintf(stderr, "ps: неизвестный параметр %s\n", arg)
+				return 1
+			}
+			all = all || strings.ContainsAny(arg, "ax")
+			continue
+		}
+
+		for j := 1; j < len(arg); j++ {
+			flag := arg[j]
+			switch flag {
+			case 'e', 'A':
+				all = true
+				continue
+			case 'f':
+				continue // Полный формат: колонка CMD и так выводится по умолчанию
+			case 'o', 'u', 'p':
+			default:
+				fmt.Fprintf(stderr, "ps: неизвестный параметр -%c\n", flag)
+				return 1
+			}
+
+			// Значение параметра: остаток аргумента (-ouser) или следующий аргумент
+			value := arg[j+1:]
+			if value == "" {
+				if i+1 >= len(args) {
+					fmt.Fprintf(stderr, "ps: -%c: требуется аргумент\n", flag)
+					return 1
+				}
+				i++
+				value = args[i]
+			}
+			list := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+
+			switch flag {
+			case 'o':
+				for _, item := range list {
+					name, header, renamed := strings.Cut(item, "=")
+					name = strings.ToLower(name)
+					if alias, ok := psAliases[name]; ok {
+						name = alias
+					}
+					column, ok := psColumns[name]
+					if !ok {
+						fmt.Fprintf(stderr, "ps: неизвестная колонка %s\n", name)
+						return 1
+					}
+					if !renamed {
+						header = column.header
+					}
+					columns = append(columns, psField{name, header})
+				}
+			case 'u':
+				users = append(users, list...)
+			case 'p':
+				for _, item := range list {
+					pid, err := strconv.Atoi(item)
+					if err != nil || pid <= 0 {
+						fmt.Fprintf(stderr, "ps: неверный PID %s\n", item)
+						return 1
+					}
+					pids = append(pids, pid)
+				}
+			}
+			break
+		}
+	}
+
+	if columns == nil {
+		for _, name := range psDefaultColumns {
+			columns = append(columns, psField{name, psColumns[name].header})
+		}
+	}
+
+	// Без -e, -u и -p выводятся процессы текущего пользователя
+	uids := make(map[int]bool)
+	for _, name := range users {
+		uid, err := lookupUid(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "ps: %v\n", err)
+			return 1
+		}
+		uids[uid] = true
+	}
+	if !all && len(users) == 0 && len(pids) == 0 {
+		uids[os.Geteuid()] = true
+	}
+
+	procs, err := readProcs()
+	if err != nil {
+		fmt.Fprintf(stderr, "ps: %v\n", err)
+		return 1
+	}
+
+	var selected []*procInfo
+	for _, p := range procs {
+		if all || uids[p.uid] || slices.Contains(pids, p.pid) {
+			selected = append(selected, p)
+		}
+	}
+
+	writePsTable(stdout, columns, selected)
+	if len(pids) > 0 && len(selected) == 0 {
+		return 1 // Как в procps: ни одного процесса из -p не найдено
+	}
+	return 0
+}
+
+// lookupUid переводит имя пользователя или UID в UID
+func lookupUid(name string) (int, error) {
+	if uid, err := strconv.Atoi(name); err == nil {
+		return uid, nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, fmt.Errorf("пользователь %s не найден", name)
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+// readProcs читает сведения обо всех процессах из /proc, упорядоченные по PID.
+// Процессы, завершившиеся во время чтения, пропускаются
+func readProcs() ([]*procInfo, error) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать %s: %w", procDir, err)
+	}
+
+	uptime := readUptime()
+	names := make(map[int]string) // UID -> имя пользователя, чтобы не искать его для каждого процесса
+	var procs []*procInfo
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		p, err := readProc(pid, uptime)
+		if err != nil {
+			continue
+		}
+
+		name, ok := names[p.uid]
+		if !ok {
+			name = strconv.Itoa(p.uid)
+			if u, err := user.LookupId(name); err == nil {
+				name = u.Username
+			}
+			names[p.uid] = name
+		}
+		p.user = name
+		procs = append(procs, p)
+	}
+
+	slices.SortFunc(procs, func(a, b *procInfo) int { return a.pid - b.pid })
+	return procs, nil
+}
+
+// readUptime возвращает время работы системы в секундах из /proc/uptime
+func readUptime() float64 {
+	data, err := os.ReadFile(filepath.Join(procDir, "uptime"))
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	uptime, _ := strconv.ParseFloat(fields[0], 64)
+	return uptime
+}
+
+// readProc читает сведения о процессе pid
+func readProc(pid int, uptime float64) (*procInfo, error) {
+	dir := filepath.Join(procDir, strconv.Itoa(pid))
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	p, err := parseProcStat(string(stat), uptime)
+	if err != nil {
+		return nil, err
+	}
+
+	// Владелец процесса - реальный UID из строки "Uid:" файла status
+	status, err := os.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return nil, err
+	}
+	for line := range strings.SplitSeq(string(status), "\n") {
+		if uid, ok := strings.CutPrefix(line, "Uid:"); ok {
+			if fields := strings.Fields(uid); len(fields) > 0 {
+				p.uid, _ = strconv.Atoi(fields[0])
+			}
+			break
+		}
+	}
+
+	// Аргументы разделены нулевыми байтами; у потоков ядра cmdline пуст, и выводится [comm]
+	cmdline, _ := os.ReadFile(filepath.Join(dir, "cmdline"))
+	p.cmdArg = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	if p.cmdArg == "" {
+		p.cmdArg = "[" + p.comm + "]"
+	}
+	return p, nil
+}
+
+// parseProcStat разбирает /proc/[pid]/stat. Имя команды стоит в скобках и может содержать
+// пробелы и скобки, поэтому поля считаются от последней закрывающей скобки
+func parseProcStat(stat string, uptime float64) (*procInfo, error) {
+	open, end := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+	if open < 0 || end < open {
+		return nil, fmt.Errorf("неверный формат stat")
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(stat[:open]))
+	if err != nil {
+		return nil, fmt.Errorf("неверный формат stat")
+	}
+
+	// fields[0] - состояние (поле 3 в proc(5)), fields[i] - поле i+3
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("неверный формат stat")
+	}
+	num := func(i int) int64 {
+		n, _ := strconv.ParseInt(fields[i], 10, 64)
+		return n
+	}
+
+	p := &procInfo{
+		pid:  pid,
+		ppid: int(num(1)),
+		comm: stat[open+1 : end],
+		vsz:  num(20) / 1024,
+		rss:  num(21) * int64(os.Getpagesize()) / 1024,
+	}
+
+	// Дополнительные признаки состояния, как в procps
+	state := fields[0]
+	switch nice := num(16); {
+	case nice < 0:
+		state += "<"
+	case nice > 0:
+		state += "N"
+	}
+	if num(3) == int64(pid) {
+		state += "s" // Лидер сеанса
+	}
+	if num(17) > 1 {
+		state += "l" // Несколько потоков
+	}
+	if tpgid := num(5); tpgid > 0 && tpgid == num(2) {
+		state += "+" // Группа процессов на переднем плане терминала
+	}
+	p.state = state
+
+	// %CPU - процессорное время (utime + stime) к времени жизни процесса
+	cpuTime := float64(num(11)+num(12)) / clockTicks
+	if elapsed := uptime - float64(num(19))/clockTicks; elapsed > 0 {
+		p.cpu = min(cpuTime/elapsed*100, 999.9)
+	}
+	return p, nil
+}
+
+// writePsTable выводит процессы таблицей: ширина колонки - по самому длинному значению,
+// числа выравниваются вправо, последняя колонка не дополняется пробелами
+func writePsTable(w io.Writer, columns []psField, procs []*procInfo) {
+	rows := make([][]string, 0, len(procs)+1)
+	header := make([]string, len(columns))
+	for i, field := range columns {
+		header[i] = field.header
+	}
+	if strings.Join(header, "") != "" {
+		rows = append(rows, header)
+	}
+	for _, p := range procs {
+		row := make([]string, len(columns))
+		for i, field := range columns {
+			row[i] = psColumns[field.name].value(p)
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(columns))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range rows {
+		for i, cell := range row {
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+			switch {
+			case psColumns[columns[i].name].right:
+				b.WriteString(pad + cell)
+			case i == len(row)-1:
+				b.WriteString(cell)
+			default:
+				b.WriteString(cell + pad)
+			}
+			if i < len(row)-1 {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	io.WriteString(w, b.String())
+}
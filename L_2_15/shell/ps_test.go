@@ -0,0 +1,65 @@
+package shell
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestParseProcStat тестирует разбор /proc/[pid]/stat, в том числе имени команды с пробелами и скобками
+func TestParseProcStat(t *testing.T) {
+	t.Parallel()
+	stat := "4242 (my (odd) cmd) S 1 4242 4242 34816 4242 4194304 80 0 0 0 150 50 0 0 20 -5 3 0 1000 8192000 250 18446744073709551615 0 0"
+
+	p, err := parseProcStat(stat, 30)
+	if err != nil {
+		t.Fatalf("parseProcStat: unexpected error %v", err)
+	}
+	if p.pid != 4242 || p.ppid != 1 || p.comm != "my (odd) cmd" {
+		t.Errorf("parseProcStat: got pid=%d ppid=%d comm=%q", p.pid, p.ppid, p.comm)
+	}
+	if p.state != "S<sl+" {
+		t.Errorf("parseProcStat: expected state %q, got %q", "S<sl+", p.state)
+	}
+	if want := int64(250 * os.Getpagesize() / 1024); p.rss != want || p.vsz != 8000 {
+		t.Errorf("parseProcStat: got rss=%d vsz=%d, expected rss=%d vsz=8000", p.rss, p.vsz, want)
+	}
+	// 2 секунды процессорного времени за 20 секунд жизни процесса
+	if p.cpu != 10 {
+		t.Errorf("parseProcStat: expected 10%% CPU, got %v", p.cpu)
+	}
+
+	if _, err := parseProcStat("garbage", 0); err == nil {
+		t.Error("parseProcStat: expected error for malformed stat")
+	}
+}
+
+// TestBuiltinPs тестирует выбор процессов и колонок встроенной командой ps
+func TestBuiltinPs(t *testing.T) {
+	t.Parallel()
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("/proc is not available")
+	}
+	pid := strconv.Itoa(os.Getpid())
+
+	result, _ := runScript(t, "ps -o pid=,comm= -p "+pid+"\nps -p "+pid+" | head -1\nps -e | wc -l | tr -d ' ' | grep -qv '^[01]$' && echo many\n")
+	lines := strings.Split(strings.TrimSpace(result), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("ps: expected 3 lines, got %q", result)
+	}
+	if fields := strings.Fields(lines[0]); len(fields) != 2 || fields[0] != pid {
+		t.Errorf("ps -o pid=,comm=: expected own pid without header, got %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "PID PPID USER STAT %CPU RSS CMD" {
+		t.Errorf("ps: unexpected header %q", lines[1])
+	}
+	if lines[2] != "many" {
+		t.Errorf("ps -e: expected several processes, got %q", lines[2])
+	}
+
+	output, shell := runScript(t, "ps -o nosuch\n")
+	if output != "" || shell.status != 1 {
+		t.Errorf("ps -o nosuch: expected status 1 and no output, got %d %q", shell.status, output)
+	}
+}
@@ -0,0 +1,223 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// Redirect - перенаправление после раскрытия слов
+type Redirect struct {
+	Fd     int    // Перенаправляемый дескриптор
+	Op     string // Оператор: <, >, >>, &>, &>>, <&, >&, << (here-document и here-string)
+	Target string // Имя файла или номер дескриптора для <& и >& ("-" - закрыть дескриптор)
+	Data   string // Содержимое here-document или here-string
+}
+
+// resolveRedirects раскрывает имена файлов и тела here-documents в перенаправлениях команды.
+// Перенаправления применяются по порядку, поэтому при повторах действует последнее
+func (s *Shell) resolveRedirects(redirects []*IORedirect) ([]Redirect, error) {
+	result := make([]Redirect, 0, len(redirects))
+
+	for _, r := range redirects {
+		redirect := Redirect{Fd: r.Fd, Op: r.Op}
+
+		switch {
+		case r.HereDoc != nil:
+			redirect.Op = "<<"
+			redirect.Data = r.HereDoc.Body
+			if !r.HereDoc.Quoted {
+				redirect.Data = s.expandHeredoc(r.HereDoc.Body)
+			}
+
+		case r.Op == "<<<":
+			redirect.Op = "<<"
+			redirect.Data = s.expandString(r.Target.Raw) + "\n"
+
+		default:
+			fields := s.expandWord(r.Target)
+			if len(fields) != 1 {
+				return nil, fmt.Errorf("%s: неоднозначное перенаправление", r.Target.Raw)
+			}
+			redirect.Target = fields[0]
+
+			// >&file без номера дескриптора - то же, что &>file
+			if r.Op == ">&" && r.Fd == 1 && redirect.Target != "-" && !isDigits(redirect.Target) {
+				redirect.Op = "&>"
+			}
+		}
+
+		result = append(result, redirect)
+	}
+
+	return result, nil
+}
+
+// applyRedirects применяет редиректы к команде
+// Возвращает функцию очистки для закрытия открытых файлов
+func applyRedirects(cmd *exec.Cmd, redirects []Redirect) (func(), error) {
+	var opened []*os.File
+	cleanup := func() {
+		for _, file := range opened {
+			file.Close()
+		}
+	}
+
+	// Таблица дескрипторов: 0 - io.Reader, 1 и 2 - io.Writer, 3 и далее - *os.File
+	fds := map[int]any{0: cmd.Stdin, 1: cmd.Stdout, 2: cmd.Stderr}
+	for i, file := range cmd.ExtraFiles {
+		fds[i+3] = file
+	}
+
+	for _, r := range redirects {
+		switch r.Op {
+		case "<&", ">&":
+			if r.Target == "-" {
+				delete(fds, r.Fd)
+				continue
+			}
+			n, err := strconv.Atoi(r.Target)
+			if err != nil {
+				return cleanup, fmt.Errorf("%s: неверный дескриптор", r.Target)
+			}
+			target, ok := fds[n]
+			if !ok {
+				return cleanup, fmt.Errorf("%d: неверный дескриптор", n)
+			}
+			fds[r.Fd] = target
+
+		case "<<":
+			file, err := dataFile(r.Data)
+			if err != nil {
+				return cleanup, fmt.Errorf("не удалось создать here-document: %v", err)
+			}
+			opened = append(opened, file)
+			fds[r.Fd] = file
+
+		default:
+			file, err := openRedirectFile(r, cmd.Dir)
+			if err != nil {
+				return cleanup, err
+			}
+			opened = append(opened, file)
+			fds[r.Fd] = file
+			if r.Op == "&>" || r.Op == "&>>" {
+				// &> перенаправляет и вывод, и ошибки
+				fds[2] = file
+			}
+		}
+	}
+
+	// Закрытый дескриптор (>&-) остается пустым: exec.Cmd подключит к нему /dev/null
+	cmd.Stdin, _ = fds[0].(io.Reader)
+	cmd.Stdout, _ = fds[1].(io.Writer)
+	cmd.Stderr, _ = fds[2].(io.Writer)
+
+	// Дескрипторы 3 и выше передаются через ExtraFiles и должны быть файлами
+	cmd.ExtraFiles = nil
+	for fd := 3; fd <= maxRedirectFd; fd++ {
+		value, ok := fds[fd]
+		if !ok || value == nil {
+			continue
+		}
+		file, isFile := value.(*os.File)
+		if !isFile {
+			return cleanup, fmt.Errorf("%d: дескриптор можно связать только с файлом", fd)
+		}
+		for len(cmd.ExtraFiles) < fd-3 {
+			cmd.ExtraFiles = append(cmd.ExtraFiles, nil)
+		}
+		cmd.ExtraFiles = append(cmd.ExtraFiles, file)
+	}
+
+	return cleanup, nil
+}
+
+// openRedirectFile открывает файл для перенаправления ввода или вывода.
+// Относительное имя файла считается от директории dir
+func openRedirectFile(r Redirect, dir string) (*os.File, error) {
+	path := r.Target
+	if !filepath.IsAbs(path) && dir != "" {
+		path = filepath.Join(dir, path)
+	}
+
+	switch r.Op {
+	case "<":
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось открыть файл для чтения '%s': %v", r.Target, err)
+		}
+		return file, nil
+
+	case ">>", "&>>":
+		// Открываем файл в режиме добавления
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось открыть файл для записи '%s': %v", r.Target, err)
+		}
+		return file, nil
+
+	default:
+		// Открываем файл в режиме перезаписи
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось открыть файл для записи '%s': %v", r.Target, err)
+		}
+		return file, nil
+	}
+}
+
+// dataFile возвращает временный файл с содержимым here-document, открытый на чтение с начала.
+// Файл удаляется сразу после создания и существует, пока открыт его дескриптор
+func dataFile(data string) (*os.File, error) {
+	file, err := os.CreateTemp("", "shell-heredoc-*")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(file.Name())
+
+	if _, err := file.WriteString(data); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// withRedirects выполняет встроенную команду или функцию с перенаправленными
+// вводом, выводом и ошибками shell'а; после выполнения они восстанавливаются
+func (s *Shell) withRedirects(redirects []Redirect, fn func() int) int {
+	if len(redirects) == 0 {
+		return fn()
+	}
+
+	cmd := &exec.Cmd{Dir: s.cwd, Stdin: s.stdin, Stdout: s.writer, Stderr: s.errWriter}
+	cleanup, err := applyRedirects(cmd, redirects)
+	defer cleanup()
+	if err != nil {
+		fmt.Fprintf(s.errWriter, "ошибка: %v\n", err)
+		return 1
+	}
+
+	savedIn, savedOut, savedErr := s.stdin, s.writer, s.errWriter
+	s.stdin, s.writer, s.errWriter = cmd.Stdin, orDiscard(cmd.Stdout), orDiscard(cmd.Stderr)
+	defer func() {
+		s.stdin, s.writer, s.errWriter = savedIn, savedOut, savedErr
+	}()
+
+	return fn()
+}
+
+// orDiscard заменяет отсутствующий (закрытый) вывод на io.Discard
+func orDiscard(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+	return w
+}
@@ -0,0 +1,91 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestStderrRedirects тестирует перенаправление дескриптора 2
+func TestStderrRedirects(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	errFile := filepath.Join(tmpDir, "err.log")
+	allFile := filepath.Join(tmpDir, "all.log")
+
+	script := "sh -c 'echo out; echo err >&2' 2>" + errFile + "\n" +
+		"sh -c 'echo out; echo err >&2' &>" + allFile + "\n" +
+		"sh -c 'echo joined >&2' 2>&1 | tr a-z A-Z\n" +
+		"sh -c 'echo hidden >&2; echo visible' 2>/dev/null | cat\n"
+	result, _ := runScript(t, script)
+
+	if result != "out\nJOINED\nvisible\n" {
+		t.Errorf("stderr redirects: unexpected output %q", result)
+	}
+	if content, _ := os.ReadFile(errFile); string(content) != "err\n" {
+		t.Errorf("2>file: expected only stderr in file, got %q", content)
+	}
+	if content, _ := os.ReadFile(allFile); string(content) != "out\nerr\n" {
+		t.Errorf("&>file: expected stdout and stderr in file, got %q", content)
+	}
+}
+
+// TestHereDocs тестирует here-documents и here-strings
+func TestHereDocs(t *testing.T) {
+	t.Parallel()
+	script := "name=World\n" +
+		"cat <<EOF\nHello, $name!\n\"quoted\" \\$name\nEOF\n" +
+		"cat <<'EOF'\nLiteral $name\nEOF\n" +
+		"cat <<-END | tr a-z A-Z\n\ttabbed\n\tEND\n" +
+		"cat <<< \"here $name\"\n" +
+		"cat <<A <<B\nfirst\nA\nsecond\nB\n"
+	result, _ := runScript(t, script)
+
+	want := "Hello, World!\n\"quoted\" $name\nLiteral $name\nTABBED\nhere World\nsecond\n"
+	if result != want {
+		t.Errorf("here-documents: expected %q, got %q", want, result)
+	}
+}
+
+// TestHereDocIncomplete тестирует, что here-document без ограничителя требует продолжения ввода
+func TestHereDocIncomplete(t *testing.T) {
+	t.Parallel()
+	for _, input := range []string{"cat <<EOF", "cat <<EOF\nline\n", "cat <<EOF\nEO"} {
+		if _, err := Parse(input); !isIncomplete(err) {
+			t.Errorf("Parse(%q): expected incomplete input, got %v", input, err)
+		}
+	}
+
+	list, err := Parse("cat <<EOF; echo next\nbody\nEOF")
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if len(list.Items) != 2 {
+		t.Errorf("Parse: expected 2 commands, got %d", len(list.Items))
+	}
+	doc := list.Items[0].Pipelines[0].Commands[0].(*SimpleCommand).Redirects[0].HereDoc
+	if doc.Body != "body\n" {
+		t.Errorf("Parse: expected here-document body %q, got %q", "body\n", doc.Body)
+	}
+}
+
+// TestBuiltinRedirect тестирует перенаправление вывода встроенных команд и функций
+func TestBuiltinRedirect(t *testing.T) {
+	t.Parallel()
+	tmpDir := t.TempDir()
+	pwdFile := filepath.Join(tmpDir, "pwd.txt")
+	funcFile := filepath.Join(tmpDir, "func.txt")
+
+	result, shell := runScript(t, "pwd > "+pwdFile+"\nf() { echo in-func; }\nf >> "+funcFile+"\necho after\n")
+
+	if result != "after\n" {
+		t.Errorf("builtin redirect: expected output only after redirects, got %q", result)
+	}
+	if content, _ := os.ReadFile(pwdFile); strings.TrimSpace(string(content)) != shell.cwd {
+		t.Errorf("pwd > file: expected %q, got %q", shell.cwd, content)
+	}
+	if content, _ := os.ReadFile(funcFile); string(content) != "in-func\n" {
+		t.Errorf("f >> file: expected function output, got %q", content)
+	}
+}
@@ -0,0 +1,1048 @@
+package shell
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// Файлы, выполняемые при запуске интерактивного shell'а
+const (
+	systemRcFile = "/etc/myshellrc"
+	userRcFile   = ".myshellrc" // В домашней директории
+)
+
+// Main запускает shell как программу с аргументами командной строки args (без имени программы)
+// и возвращает код завершения: код последней выполненной команды или аргумент exit.
+//
+//	shell -c 'команды' [имя [аргументы...]]
+//	shell script.sh [аргументы...]
+//	shell                          - команды из stdin, в терминале - интерактивно
+func Main(args []string) int {
+	var shell *Shell
+
+	switch {
+	case len(args) > 0 && args[0] == "-c":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "shell: -c: требуется аргумент")
+			return 2
+		}
+		shell = NewShell(nil, os.Stdout, os.Stderr)
+		if len(args) > 2 {
+			shell.name = args[2]
+			shell.params = args[3:]
+		}
+		stop := shell.trapSignals()
+		shell.executeCommand(args[1])
+		stop()
+
+	case len(args) > 0:
+		file, err := os.Open(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "shell: %v\n", err)
+			return 127
+		}
+		shell = NewShell(bufio.NewReader(file), os.Stdout, os.Stderr)
+		shell.name = args[0]
+		shell.params = args[1:]
+		err = shell.Run()
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ошибка shell: %v\n", err)
+			return 1
+		}
+
+	default:
+		shell = NewShell(bufio.NewReader(os.Stdin), os.Stdout, os.Stderr)
+		if isTerminal(int(os.Stdin.Fd())) {
+			shell.interactive = true
+			shell.enableJobControl(int(os.Stdin.Fd()))
+			shell.history = loadHistory(shell.historyPath())
+			if os.Getenv("TERM") != "dumb" {
+				// Редактор строки; без терминала ввод читается построчно через reader
+				shell.editor = newLineEditor(int(os.Stdin.Fd()), os.Stdin, os.Stdout, shell.history)
+				shell.editor.complete = shell.complete
+			}
+			stop := shell.trapSignals()
+			shell.loadStartupFiles()
+			stop()
+		}
+		if shell.exit {
+			break
+		}
+		if err := shell.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "ошибка shell: %v\n", err)
+			return 1
+		}
+	}
+
+	return shell.status
+}
+
+// Shell представляет интерпретатор команд
+type Shell struct {
+	reader    *bufio.Reader
+	writer    io.Writer
+	stdin     io.Reader // Ввод для запускаемых команд
+	errWriter io.Writer // Вывод ошибок запускаемых команд
+	cwd       string    // Текущая директория
+	exit      bool      // Флаг для выхода
+
+	interactive bool                 // Интерактивный режим: выводить приглашение
+	name        string               // Имя shell'а или скрипта ($0)
+	params      []string             // Позиционные параметры ($1, $2, ...)
+	status      int                  // Код завершения последней команды ($?)
+	vars        map[string]*variable // Переменные shell'а (экспортированные и локальные)
+	lastBgPid   int                  // PID последнего фонового процесса ($!)
+	lineNo      int                  // Количество уже прочитанных строк ввода (для сообщений об ошибках)
+	inputName   string               // Имя читаемого файла (rc-файл) для сообщений об ошибках
+	abortInput  bool                 // Синтаксическая ошибка: прекратить чтение текущего ввода
+	sourceDepth int                  // Глубина вложенности выполняемых файлов (source): в них действует return
+	expandErr   error                // Ошибка раскрытия слов текущей команды
+	substStatus int                  // Код завершения последней подстановки команды
+	pipeStatus  []int                // Коды завершения команд последнего конвейера ($PIPESTATUS)
+	options     map[string]bool      // Параметры, включенные через set -o (errexit, pipefail, xtrace)
+	noErrexit   int                  // Больше нуля - set -e не действует (условие if, левая часть && и ||)
+
+	funcs     map[string]*FuncDef    // Определенные функции
+	aliases   map[string]string      // Алиасы (alias)
+	hash      map[string]hashEntry   // Найденные в $PATH команды (hash)
+	dirStack  []string               // Стек каталогов pushd/popd без текущей директории
+	locals    []map[string]*variable // Стек вызовов функций: прежние значения переменных, объявленных через local
+	loopDepth int                    // Глубина вложенности выполняемых циклов
+	breakN    int                    // Сколько циклов осталось прервать после break N
+	continueN int                    // continue N: сколько циклов прервать, чтобы продолжить внешний
+	returning bool                   // Выполнен return: выйти из функции
+
+	jobs       []*Job // Фоновые и остановленные задания
+	jobControl bool   // Управление заданиями включено (интерактивный режим в терминале)
+	terminal   int    // Дескриптор управляющего терминала
+	shellPgid  int    // Группа процессов самого shell'а
+
+	builtins   map[string]Builtin           // Встроенные команды (RegisterBuiltin)
+	foreground atomic.Pointer[signalTarget] // Процессы задания переднего плана для пересылки сигналов
+	sigint     *atomic.Bool                 // Получен Ctrl+C: выполнение командной строки прерывается (общий с копиями shell'а)
+
+	editor  *lineEditor // Редактор строки (nil - ввод читается из reader)
+	history *History    // История команд (nil - история выключена)
+
+	mu  sync.Mutex      // Сериализует Run и Exec: одновременно shell выполняет одну команду
+	ctx context.Context // Контекст выполняемого Exec (nil - выполнение без отмены)
+}
+
+// NewShell создает новый экземпляр shell'а, который читает команды из reader.
+// В writer выводятся результаты команд, в errWriter - сообщения shell'а об ошибках
+// и заданиях и поток ошибок запускаемых команд. errWriter == nil - сообщения отбрасываются
+func NewShell(reader *bufio.Reader, writer, errWriter io.Writer) *Shell {
+	cwd, _ := os.Getwd()
+
+	// В не-файловый writer (например, bytes.Buffer) одновременно пишут shell
+	// и горутины, копирующие вывод фоновых заданий. Если вывод и ошибки идут
+	// в один writer, запись в него сериализуется общей блокировкой
+	sameWriter := errWriter == writer
+	if _, ok := writer.(*os.File); !ok && writer != nil {
+		writer = &syncWriter{w: writer}
+	}
+	switch {
+	case sameWriter:
+		errWriter = writer
+	case errWriter == nil:
+		errWriter = io.Discard
+	default:
+		if _, ok := errWriter.(*os.File); !ok {
+			errWriter = &syncWriter{w: errWriter}
+		}
+	}
+
+	return &Shell{
+		reader:    reader,
+		writer:    writer,
+		stdin:     os.Stdin,
+		errWriter: errWriter,
+		cwd:       cwd,
+		name:      filepath.Base(os.Args[0]),
+		vars:      importEnviron(os.Environ()),
+		funcs:     make(map[string]*FuncDef),
+		builtins:  defaultBuiltins(),
+		options:   make(map[string]bool),
+		aliases:   make(map[string]string),
+		hash:      make(map[string]hashEntry),
+		sigint:    new(atomic.Bool),
+	}
+}
+
+// syncWriter сериализует запись в writer из нескольких горутин
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// Run читает и выполняет команды до конца ввода или команды exit.
+// В интерактивном режиме перед каждой командой выводится приглашение
+func (s *Shell) Run() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.trapSignals()()
+	return s.readCommands()
+}
+
+// readCommands читает и выполняет команды из текущего ввода до его конца, команды exit
+// или синтаксической ошибки в неинтерактивном режиме
+func (s *Shell) readCommands() error {
+	for {
+		prompt := ""
+		if s.interactive {
+			// Сообщаем о завершившихся фоновых заданиях
+			s.notifyJobs()
+			prompt = s.getPrompt() // приглашение с текущей директорией
+		}
+
+		// Чтение строки команды
+		line, err := s.readLine(prompt)
+		if errors.Is(err, errInterrupted) {
+			s.status = 130
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == io.EOF && line == "" {
+			// Ctrl+D - выход
+			if s.interactive {
+				fmt.Fprintln(s.writer)
+			}
+			return nil
+		}
+
+		// Незаконченная команда (незакрытая кавычка, if без fi, && или \ в конце строки)
+		// продолжается на следующих строках
+		line, ok := s.expandHistoryLine(line)
+		src, lines := line, 1
+		list, parseErr := s.parse(src)
+		for ok && isIncomplete(parseErr) && err == nil {
+			line, err = s.readLine(s.prompt("PS2", defaultPS2))
+			if errors.Is(err, errInterrupted) {
+				ok = false
+				s.status = 130
+				break
+			}
+			if err != nil && err != io.EOF {
+				return err
+			}
+			line, ok = s.expandHistoryLine(line)
+			src += line
+			lines++
+			list, parseErr = s.parse(src)
+		}
+
+		// Пропускаем пустые строки и команды, прерванные Ctrl+C или ошибкой в ссылке на историю
+		if ok && strings.TrimSpace(src) != "" {
+			if s.history != nil {
+				s.history.Add(strings.TrimRight(src, "\n"))
+			}
+			// Выполняем команду; Ctrl+C, нажатый до нее, прерывает только предыдущую строку
+			if s.interactive {
+				s.sigint.Store(false)
+			}
+			s.executeParsed(list, parseErr)
+		}
+		s.lineNo += lines
+
+		// Проверяем флаг выхода; последняя строка без перевода строки завершает ввод
+		if s.exit || s.abortInput || s.returning || err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// runFile выполняет команды из файла в самом shell'е: переменные, функции и алиасы,
+// определенные в файле, остаются в shell'е. Синтаксическая ошибка прекращает чтение
+// файла, но не завершает shell; exit в файле завершает shell
+func (s *Shell) runFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader, editor, history := s.reader, s.editor, s.history
+	interactive, lineNo, inputName := s.interactive, s.lineNo, s.inputName
+	defer func() {
+		s.reader, s.editor, s.history = reader, editor, history
+		s.interactive, s.lineNo, s.inputName = interactive, lineNo, inputName
+		s.abortInput = false
+	}()
+
+	s.reader, s.editor, s.history = bufio.NewReader(file), nil, nil
+	s.interactive, s.lineNo, s.inputName = false, 0, path
+	s.sourceDepth++
+	defer func() {
+		s.sourceDepth--
+		s.returning = false
+	}()
+	return s.readCommands()
+}
+
+// loadStartupFiles выполняет /etc/myshellrc и ~/.myshellrc при запуске интерактивного shell'а.
+// Отсутствующие файлы пропускаются
+func (s *Shell) loadStartupFiles() {
+	for _, path := range []string{systemRcFile, filepath.Join(s.homeDir(), userRcFile)} {
+		err := s.runFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(s.errWriter, "%s: %v\n", s.name, err)
+		}
+		if s.exit {
+			return
+		}
+	}
+}
+
+// readLine выводит приглашение и читает строку ввода: в терминале - редактором строки,
+// иначе - построчно из reader
+func (s *Shell) readLine(prompt string) (string, error) {
+	if s.editor != nil {
+		return s.editor.ReadLine(prompt)
+	}
+	if s.interactive {
+		fmt.Fprint(s.writer, prompt)
+	}
+	return s.reader.ReadString('\n')
+}
+
+// expandHistoryLine раскрывает в строке ссылки на историю (!!, !n) и, как bash, выводит
+// получившуюся команду. Если ссылка не найдена, возвращает false: команда не выполняется
+func (s *Shell) expandHistoryLine(line string) (string, bool) {
+	if s.history == nil {
+		return line, true
+	}
+	expanded, changed, err := s.history.expandHistory(line)
+	if err != nil {
+		fmt.Fprintln(s.errWriter, err)
+		s.status = 1
+		return "", false
+	}
+	if changed {
+		fmt.Fprint(s.writer, expanded)
+	}
+	return expanded, true
+}
+
+// isIncomplete сообщает, что ввод оборвался посередине команды
+func isIncomplete(err error) bool {
+	var syntaxErr *SyntaxError
+	return errors.As(err, &syntaxErr) && syntaxErr.Incomplete
+}
+
+// executeCommand разбирает и выполняет командную строку
+// Код завершения сохраняется в s.status ($?)
+func (s *Shell) executeCommand(line string) {
+	s.executeParsed(s.parse(line))
+}
+
+// parse разбирает src с учетом алиасов shell'а
+func (s *Shell) parse(src string) (*List, error) {
+	return ParseAliases(src, s.aliases)
+}
+
+// executeParsed выполняет разобранную программу или сообщает о синтаксической ошибке
+func (s *Shell) executeParsed(list *List, err error) {
+	if err != nil {
+		var syntaxErr *SyntaxError
+		if errors.As(err, &syntaxErr) {
+			// Позиция считается от начала всего ввода, а не текущей строки
+			syntaxErr.Pos.Line += s.lineNo
+		}
+
+		if s.interactive {
+			fmt.Fprintln(s.errWriter, err)
+		} else {
+			// Синтаксическая ошибка в скрипте прерывает его выполнение
+			name := s.name
+			if s.inputName != "" {
+				name = s.inputName
+			}
+			fmt.Fprintf(s.errWriter, "%s: %v\n", name, err)
+			s.abortInput = true
+		}
+		s.status = 2
+		return
+	}
+
+	if len(list.Items) > 0 {
+		s.status = s.executeList(list)
+	}
+
+	// Ctrl+C прерывает командную строку (цикл, список); скрипт он завершает с кодом 130,
+	// а выполняемый source-файл - прекращает читать
+	if s.sigint.Load() {
+		s.status = 130
+		if s.sourceDepth > 0 {
+			s.abortInput = true
+		} else if !s.interactive {
+			s.exit = true
+		}
+	}
+}
+
+// executeList выполняет команды списка по очереди и возвращает код завершения последней
+func (s *Shell) executeList(list *List) int {
+	var exitCode int
+	for _, item := range list.Items {
+		if s.aborted() {
+			break
+		}
+		if item.Background {
+			exitCode = s.executeBackground(item)
+		} else {
+			exitCode = s.executeAndOr(item)
+		}
+		s.status = exitCode
+		if !s.interactive {
+			// Интерактивный shell собирает фоновые задания перед приглашением, а скрипт - здесь
+			s.reapJobs()
+		}
+		if s.interrupted() {
+			break
+		}
+	}
+	return exitCode
+}
+
+// executeBackground запускает цепочку в фоне и сразу возвращает управление
+func (s *Shell) executeBackground(andOr *AndOr) int {
+	if len(andOr.Pipelines) > 1 {
+		fmt.Fprintln(s.errWriter, "ошибка: в фоне можно запустить только конвейер без && и ||")
+		return 1
+	}
+
+	stages, err := s.expandPipeline(andOr.Pipelines[0])
+	if err != nil {
+		fmt.Fprintf(s.errWriter, "ошибка: %v\n", err)
+		return 1
+	}
+	s.trace(stages)
+
+	job, err := s.startJob(stages, andOr.Text, false)
+	if err != nil {
+		fmt.Fprintf(s.errWriter, "ошибка: %v\n", err)
+		return 1
+	}
+
+	s.addJob(job)
+	pid := 0
+	for _, p := range job.procs {
+		if !p.internal {
+			pid = p.pid // $! - PID последнего процесса конвейера
+		}
+	}
+	if pid == 0 {
+		// Конвейер только из встроенных команд и функций выполняется в горутинах, PID у него нет
+		if s.interactive {
+			fmt.Fprintf(s.errWriter, "[%d]\n", job.ID)
+		}
+		return 0
+	}
+	s.lastBgPid = pid
+	if s.interactive {
+		fmt.Fprintf(s.errWriter, "[%d] %d\n", job.ID, pid)
+	}
+	return 0
+}
+
+// executeAndOr выполняет цепочку конвейеров с учетом && и ||.
+// При set -e неуспешный последний конвейер цепочки завершает shell; неуспех
+// конвейеров перед && и || и конвейеров с ! проверяется самой цепочкой, поэтому shell не завершает
+func (s *Shell) executeAndOr(andOr *AndOr) int {
+	last := len(andOr.Pipelines) - 1
+	run := func(i int) int {
+		pipeline := andOr.Pipelines[i]
+		if i == last && !pipeline.Negated {
+			return s.executePipeline(pipeline)
+		}
+		exitCode := s.withoutErrexit(func() int { return s.executePipeline(pipeline) })
+		if pipeline.Negated {
+			return negateStatus(exitCode)
+		}
+		return exitCode
+	}
+
+	lastExitCode := run(0)
+	ran := 0
+	for i, op := range andOr.Ops {
+		if s.interrupted() {
+			break
+		}
+		if op == "&&" && lastExitCode != 0 {
+			continue // &&: выполнять только если предыдущая команда успешна
+		}
+		if op == "||" && lastExitCode == 0 {
+			continue // ||: выполнять только если предыдущая команда неуспешна
+		}
+
+		s.status = lastExitCode
+		lastExitCode = run(i + 1)
+		ran = i + 1
+	}
+
+	if ran == last && lastExitCode != 0 && !andOr.Pipelines[last].Negated &&
+		s.options["errexit"] && s.noErrexit == 0 && !s.interrupted() {
+		s.exit = true
+	}
+	return lastExitCode
+}
+
+// negateStatus инвертирует код завершения конвейера с !: успех становится 1, неуспех - 0
+func negateStatus(exitCode int) int {
+	if exitCode == 0 {
+		return 1
+	}
+	return 0
+}
+
+// withoutErrexit выполняет fn, в которой неуспешные команды не завершают shell при set -e
+func (s *Shell) withoutErrexit(fn func() int) int {
+	s.noErrexit++
+	defer func() { s.noErrexit-- }()
+	return fn()
+}
+
+// stage - команда конвейера после раскрытия слов и имен файлов
+type stage struct {
+	assigns   []string // Присваивания NAME=value перед командой
+	args      []string
+	redirects []Redirect
+	noFunc    bool    // command имя: функции с этим именем не вызываются
+	compound  Command // Составная команда ({ ...; }, ( ... ), if, while, ...) вместо простой
+}
+
+// expandPipeline раскрывает слова и редиректы всех команд конвейера
+func (s *Shell) expandPipeline(pipeline *Pipeline) ([]stage, error) {
+	s.expandErr = nil
+	s.substStatus = 0
+
+	stages := make([]stage, 0, len(pipeline.Commands))
+	for _, node := range pipeline.Commands {
+		simple, ok := node.(*SimpleCommand)
+		if !ok {
+			// Составная команда раскрывает свои слова сама, при выполнении
+			stages = append(stages, stage{compound: node})
+			continue
+		}
+		redirects, err := s.resolveRedirects(simple.Redirects)
+		if err != nil {
+			return nil, err
+		}
+		var assigns []string
+		for _, w := range simple.Assigns {
+			name, value, _ := strings.Cut(w.Raw, "=")
+			assigns = append(assigns, name+"="+s.expandString(value))
+		}
+
+		stages = append(stages, stage{assigns: assigns, args: s.expandWords(simple.Args), redirects: redirects})
+	}
+	if s.expandErr != nil {
+		return nil, s.expandErr
+	}
+	return stages, nil
+}
+
+// executePipeline выполняет конвейер команд на переднем плане и запоминает
+// коды завершения его команд в $PIPESTATUS
+func (s *Shell) executePipeline(pipeline *Pipeline) int {
+	if len(pipeline.Commands) == 1 {
+		if _, simple := pipeline.Commands[0].(*SimpleCommand); !simple {
+			// $PIPESTATUS составной команды - коды последнего конвейера внутри нее
+			return s.executeCompound(pipeline.Commands[0])
+		}
+	}
+
+	exitCode, statuses := s.runPipeline(pipeline)
+	if statuses == nil {
+		statuses = []int{exitCode}
+	}
+	s.pipeStatus = statuses
+	return exitCode
+}
+
+// runPipeline выполняет конвейер простых команд. Возвращает код завершения конвейера
+// и коды его команд (nil, если команда выполнена самим shell'ом без запуска задания)
+func (s *Shell) runPipeline(pipeline *Pipeline) (int, []int) {
+	stages, err := s.expandPipeline(pipeline)
+	if err != nil {
+		fmt.Fprintf(s.errWriter, "ошибка: %v\n", err)
+		return 1, nil
+	}
+	s.trace(stages)
+
+	if len(stages) == 1 {
+		// Одна команда без конвейера
+		args := stages[0].args
+		if len(args) == 0 {
+			// Без команды присваивания меняют переменные самого shell'а
+			for _, assign := range stages[0].assigns {
+				name, value, _ := strings.Cut(assign, "=")
+				s.setVar(name, value)
+			}
+
+			// Команда из одних редиректов ("> file") только создает или открывает файлы
+			cleanup, err := applyRedirects(&exec.Cmd{Dir: s.cwd}, stages[0].redirects)
+			cleanup()
+			if err != nil {
+				fmt.Fprintf(s.errWriter, "ошибка: %v\n", err)
+				return 1, nil
+			}
+			// Код завершения x=$(cmd) - код подстановки
+			return s.substStatus, nil
+		}
+
+		// Функции и встроенные команды выполняются самим shell'ом;
+		// присваивания и редиректы действуют только на время их выполнения
+		if fn := s.funcs[args[0]]; fn != nil {
+			return s.withAssigns(stages[0].assigns, func() int {
+				return s.withRedirects(stages[0].redirects, func() int {
+					return s.callFunction(fn, args)
+				})
+			}), nil
+		}
+		if s.builtins[args[0]] != nil {
+			return s.withAssigns(stages[0].assigns, func() int {
+				return s.withRedirects(stages[0].redirects, func() int {
+					exitCode, _ := s.executeBuiltin(args)
+					return exitCode
+				})
+			}), nil
+		}
+	}
+
+	job, err := s.startJob(stages, pipeline.Text, true)
+	if err != nil {
+		fmt.Fprintf(s.errWriter, "ошибка: %v\n", err)
+		return 1, nil
+	}
+
+	exitCode := s.waitForeground(job)
+	return exitCode, job.statuses()
+}
+
+// trace выводит команды конвейера в поток ошибок при set -x: после раскрытия слов,
+// с префиксом $PS4 (по умолчанию "+ ")
+func (s *Shell) trace(stages []stage) {
+	if !s.options["xtrace"] {
+		return
+	}
+	prefix, ok := s.lookupVar("PS4")
+	if !ok {
+		prefix = "+ "
+	}
+
+	// Как в bash, каждое присваивание выводится отдельной строкой перед командой
+	var b strings.Builder
+	for _, st := range stages {
+		for _, assign := range st.assigns {
+			name, value, _ := strings.Cut(assign, "=")
+			b.WriteString(prefix + name + "=")
+			if value != "" {
+				b.WriteString(shellQuote(value))
+			}
+			b.WriteByte('\n')
+		}
+		if len(st.args) == 0 {
+			continue
+		}
+		b.WriteString(prefix)
+		for i, arg := range st.args {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(shellQuote(arg))
+		}
+		b.WriteByte('\n')
+	}
+	io.WriteString(s.errWriter, b.String())
+}
+
+// startJob запускает команды конвейера, соединяя их трубами.
+// Внешние команды запускаются процессами; встроенные команды и функции выполняются
+// горутинами в копии shell'а, соединенными с соседями через io.Pipe (или через os.Pipe,
+// если сосед - процесс). При управлении заданиями все процессы помещаются в одну группу,
+// а задание переднего плана (foreground) получает терминал
+func (s *Shell) startJob(stages []stage, text string, foreground bool) (*Job, error) {
+	job := &Job{Command: text}
+
+	// Концы труб закрываются в родительском процессе после запуска, иначе читатели не получат EOF.
+	// Концы, доставшиеся встроенным командам, закрывают их горутины (owned)
+	var pipes []io.Closer
+	owned := make([][]io.Closer, len(stages))
+	closePipes := func() {
+		for _, p := range pipes {
+			p.Close()
+		}
+		pipes = nil
+	}
+	defer closePipes()
+
+	fail := func(err error) (*Job, error) {
+		closePipes()
+		for _, closers := range owned {
+			for _, c := range closers {
+				c.Close()
+			}
+		}
+		job.abort()
+		return nil, err
+	}
+
+	cmds := make([]*exec.Cmd, len(stages))
+	missing := make([]error, len(stages))
+	for i, st := range stages {
+		internal := s.isInternal(st)
+		var path string
+		if !internal {
+			// Ненайденная команда не запускается: ее стадия выполняется горутиной,
+			// которая только сообщает об ошибке, а остальные команды конвейера работают
+			path, missing[i] = s.lookPath(st.args[0])
+			internal = missing[i] != nil
+		}
+		job.procs = append(job.procs, &process{internal: internal})
+
+		var cmd *exec.Cmd
+		if internal {
+			// exec.Cmd встроенной команды только хранит ее ввод и вывод для applyRedirects
+			cmd = &exec.Cmd{Dir: s.cwd}
+		} else {
+			cmd = s.newCommand(path, st.args)
+			// Присваивания перед командой попадают только в окружение дочернего процесса
+			cmd.Env = s.environ(st.assigns)
+			cmd.Dir = s.cwd
+		}
+		cmd.Stderr = s.errWriter
+		cmds[i] = cmd
+
+		// Соединяем команды: stdin текущей команды - это stdout предыдущей
+		if i == 0 {
+			continue
+		}
+		if job.procs[i-1].internal && internal {
+			r, w := io.Pipe()
+			cmds[i-1].Stdout = w
+			cmd.Stdin = r
+			owned[i-1] = append(owned[i-1], w)
+			owned[i] = append(owned[i], r)
+			continue
+		}
+		r, w, err := os.Pipe()
+		if err != nil {
+			return fail(err)
+		}
+		cmds[i-1].Stdout = w
+		cmd.Stdin = r
+		if job.procs[i-1].internal {
+			owned[i-1] = append(owned[i-1], w)
+		} else {
+			pipes = append(pipes, w)
+		}
+		if internal {
+			owned[i] = append(owned[i], r)
+		} else {
+			pipes = append(pipes, r)
+		}
+	}
+
+	// Первая команда читает ввод shell'а, последняя выводит в консоль.
+	// Фоновое задание без управления заданиями не должно забирать ввод у shell'а
+	if foreground || s.jobControl {
+		cmds[0].Stdin = s.stdin
+	}
+	cmds[len(cmds)-1].Stdout = s.writer
+
+	for i, cmd := range cmds {
+		// Редиректы каждой команды применяются поверх труб
+		cleanup, err := applyRedirects(cmd, stages[i].redirects)
+		job.cleanup = append(job.cleanup, cleanup)
+		if err != nil {
+			return fail(err)
+		}
+		if job.procs[i].internal {
+			continue
+		}
+
+		in, closer, err := job.pipeInput(cmd.Stdin)
+		if err != nil {
+			return fail(err)
+		}
+		cmd.Stdin = in
+		if closer != nil {
+			pipes = append(pipes, closer)
+		}
+
+		// Если вывод и ошибки направлены в один не-файловый writer (2>&1),
+		// они пишутся в одну трубу, чтобы сохранить порядок строк
+		sameOutput := cmd.Stderr != nil && cmd.Stderr == cmd.Stdout
+		out, closer, err := job.pipeOutput(cmd.Stdout)
+		if err != nil {
+			return fail(err)
+		}
+		cmd.Stdout = out
+		if closer != nil {
+			pipes = append(pipes, closer)
+		}
+
+		if sameOutput {
+			cmd.Stderr = out
+			continue
+		}
+		errOut, closer, err := job.pipeOutput(cmd.Stderr)
+		if err != nil {
+			return fail(err)
+		}
+		cmd.Stderr = errOut
+		if closer != nil {
+			pipes = append(pipes, closer)
+		}
+	}
+
+	// Запускаем все внешние команды
+	for i, cmd := range cmds {
+		if job.procs[i].internal {
+			continue
+		}
+		if s.jobControl {
+			cmd.SysProcAttr = &syscall.SysProcAttr{
+				Setpgid:    true,
+				Pgid:       job.Pgid,
+				Foreground: foreground && job.Pgid == 0,
+				Ctty:       s.terminal,
+			}
+		} else if s.ctx != nil {
+			// В Exec задание тоже получает свою группу процессов: при отмене контекста
+			// убивается вся группа, вместе с процессами, которые запустили сами команды
+			cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pgid: job.Pgid}
+		}
+
+		if err := cmd.Start(); err != nil {
+			s.grabTerminal()
+			return fail(err)
+		}
+
+		if (s.jobControl || s.ctx != nil) && job.Pgid == 0 {
+			job.Pgid = cmd.Process.Pid
+		}
+		job.procs[i].cmd = cmd
+		job.procs[i].pid = cmd.Process.Pid
+	}
+
+	// Затем встроенные команды и функции: каждая - в своей копии shell'а,
+	// поэтому они не меняют сам shell и не мешают друг другу
+	for i, st := range stages {
+		p := job.procs[i]
+		if !p.internal {
+			continue
+		}
+		sub := s.subshell()
+		cmd, closers, missing := cmds[i], owned[i], missing[i]
+		p.finished = make(chan struct{})
+		go func() {
+			defer close(p.finished)
+			if missing != nil {
+				message, code := commandError(st.args[0], missing)
+				fmt.Fprintln(orDiscard(cmd.Stderr), message)
+				p.code = code
+			} else {
+				p.code = sub.runInternal(st, cmd)
+			}
+			for _, c := range closers {
+				c.Close()
+			}
+		}()
+	}
+
+	return job, nil
+}
+
+// isInternal сообщает, выполняет ли команду сам shell: встроенная команда, функция,
+// составная команда или одни присваивания без команды
+func (s *Shell) isInternal(st stage) bool {
+	args := st.args
+	return st.compound != nil || len(args) == 0 || s.builtins[args[0]] != nil || !st.noFunc && s.funcs[args[0]] != nil
+}
+
+// runInternal выполняет встроенную команду или функцию - стадию конвейера -
+// с вводом и выводом из cmd и возвращает код завершения
+func (s *Shell) runInternal(st stage, cmd *exec.Cmd) int {
+	s.stdin = cmd.Stdin
+	if s.stdin == nil {
+		s.stdin = strings.NewReader("") // Ввод закрыт (<&-)
+	}
+	s.writer, s.errWriter = orDiscard(cmd.Stdout), orDiscard(cmd.Stderr)
+
+	if st.compound != nil {
+		return s.executeCompound(st.compound)
+	}
+	if len(st.args) == 0 {
+		for _, assign := range st.assigns {
+			name, value, _ := strings.Cut(assign, "=")
+			s.setVar(name, value)
+		}
+		return 0
+	}
+
+	return s.withAssigns(st.assigns, func() int {
+		if fn := s.funcs[st.args[0]]; fn != nil && !st.noFunc {
+			return s.callFunction(fn, st.args)
+		}
+		exitCode, _ := s.executeBuiltin(st.args)
+		return exitCode
+	})
+}
+
+// newCommand создает exec.Cmd для внешней команды с исполняемым файлом path (см. lookPath)
+func (s *Shell) newCommand(path string, args []string) *exec.Cmd {
+	return &exec.Cmd{Path: path, Args: args}
+}
+
+// executeBuiltin выполняет встроенную команду с вводом, выводом и ошибками shell'а.
+// Возвращает код завершения и false, если команда не встроенная
+func (s *Shell) executeBuiltin(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	builtin, ok := s.builtins[args[0]]
+	if !ok {
+		return 0, false
+	}
+	return builtin.Run(s, args, s.stdin, s.writer, s.errWriter), true
+}
+
+// builtinExit завершает shell с кодом из аргумента или кодом последней команды
+func (s *Shell) builtinExit(args []string, _ io.Reader, _, stderr io.Writer) int {
+	args = args[1:]
+	s.exit = true
+	if len(args) == 0 {
+		return s.status
+	}
+
+	code, err := strconv.Atoi(args[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "exit: %s: требуется числовой аргумент\n", args[0])
+		return 2
+	}
+	return code & 0xff
+}
+
+// builtinCd меняет текущую директорию. cd - возвращает в предыдущую ($OLDPWD) и выводит ее
+func (s *Shell) builtinCd(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	args = args[1:]
+	if len(args) == 0 {
+		// cd без аргументов - переход в домашнюю директорию
+		args = []string{s.homeDir()}
+	}
+
+	dir, back := args[0], args[0] == "-"
+	if back {
+		oldpwd, ok := s.lookupVar("OLDPWD")
+		if !ok || oldpwd == "" {
+			fmt.Fprintln(stderr, "cd: OLDPWD не задан")
+			return 1
+		}
+		dir = oldpwd
+	}
+
+	if err := s.changeDir(dir); err != nil {
+		fmt.Fprintf(stderr, "cd: %v\n", err)
+		return 1
+	}
+	if back {
+		fmt.Fprintln(stdout, s.cwd)
+	}
+	return 0
+}
+
+// changeDir делает dir текущей директорией shell'а и обновляет $PWD и $OLDPWD.
+// Относительный путь считается от текущей директории shell'а.
+// Директория процесса не меняется: команды запускаются с cmd.Dir = s.cwd
+func (s *Shell) changeDir(dir string) error {
+	path := s.resolvePath(dir)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s: не директория", dir)
+	}
+
+	s.setVar("OLDPWD", s.cwd)
+	s.cwd = path
+	s.setVar("PWD", path)
+	return nil
+}
+
+// homeDir возвращает домашнюю директорию: значение $HOME или домашнюю директорию пользователя
+func (s *Shell) homeDir() string {
+	if home, ok := s.lookupVar("HOME"); ok && home != "" {
+		return home
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+// trapSignals перехватывает Ctrl+C (SIGINT) и Ctrl+\ (SIGQUIT): shell от них не завершается,
+// а пересылает их заданию переднего плана. Возвращает функцию, снимающую перехват
+func (s *Shell) trapSignals() func() {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGQUIT)
+	// Вывод запоминаем при запуске: s.writer подменяется перенаправлениями
+	// во время выполнения команд, и читать его из горутины нельзя
+	go s.handleSignals(sigChan, s.writer, s.interactive)
+	return func() {
+		signal.Stop(sigChan)
+		close(sigChan)
+	}
+}
+
+// handleSignals пересылает сигналы заданию переднего плана. Без управления заданиями
+// процессы задания в одной группе с shell'ом и получают Ctrl+C с терминала сами,
+// но сигнал, посланный только shell'у (kill -INT), иначе до них не дойдет.
+// Ctrl+C, полученный самим shell'ом, прерывает выполняемую командную строку
+func (s *Shell) handleSignals(sigChan <-chan os.Signal, out io.Writer, interactive bool) {
+	for sig := range sigChan {
+		if sig == syscall.SIGINT {
+			// Ctrl+C, полученный самим shell'ом, прерывает выполняемую командную строку
+			s.sigint.Store(true)
+		}
+		if target := s.foreground.Load(); target != nil {
+			target.signal(sig.(syscall.Signal))
+			continue
+		}
+		if sig == syscall.SIGINT && interactive {
+			// Ctrl+C без выполняемой команды - просто переходим на новую строку
+			fmt.Fprintln(out)
+		}
+	}
+}
+
+// getHostname возвращает имя хоста
+func getHostname() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "localhost"
+	}
+	return hostname
+}
@@ -1,11 +1,13 @@
-package main
+package shell
 
 import (
 	"bufio"
 	"bytes"
 	"os"
+	"os/exec"
 	"os/user"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -40,7 +42,7 @@ func TestBuiltinEcho(t *testing.T) {
 			input := bufio.NewReader(strings.NewReader(tt.input + "exit\n"))
 			output := &bytes.Buffer{}
 
-			shell := NewShell(input, output)
+			shell := NewShell(input, output, os.Stderr)
 			shell.Run()
 
 			result := output.String()
@@ -57,7 +59,7 @@ func TestBuiltinPwd(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("pwd\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	cwd, _ := os.Getwd()
 
 	shell.Run()
@@ -78,7 +80,7 @@ func TestBuiltinCd(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("cd " + tmpDir + "\npwd\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	result := output.String()
@@ -93,7 +95,7 @@ func TestBuiltinCdHome(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("cd\npwd\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	home, _ := os.UserHomeDir()
@@ -109,7 +111,7 @@ func TestBuiltinCdTilde(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("cd ~\npwd\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	home, _ := os.UserHomeDir()
@@ -119,8 +121,8 @@ func TestBuiltinCdTilde(t *testing.T) {
 	}
 }
 
-// TestParseConditionals тестирует парсинг условных операторов
-func TestParseConditionals(t *testing.T) {
+// TestParseAndOr тестирует разбор условных операторов
+func TestParseAndOr(t *testing.T) {
 	tests := []struct {
 		name    string
 		input   string
@@ -148,24 +150,34 @@ func TestParseConditionals(t *testing.T) {
 			numOps:  1,
 			firstOp: "||",
 		},
+		{
+			name:    "operator inside quotes",
+			input:   "grep '&&' file || echo \"a||b\"",
+			numCmds: 2,
+			numOps:  1,
+			firstOp: "||",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			shell := NewShell(nil, nil)
-			cmds, ops := shell.parseConditionals(tt.input)
+			list, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse: unexpected error: %v", err)
+			}
+			andOr := list.Items[0]
 
-			if len(cmds) != tt.numCmds {
-				t.Errorf("parseConditionals: expected %d commands, got %d", tt.numCmds, len(cmds))
+			if len(andOr.Pipelines) != tt.numCmds {
+				t.Errorf("Parse: expected %d commands, got %d", tt.numCmds, len(andOr.Pipelines))
 			}
 
-			if len(ops) != tt.numOps {
-				t.Errorf("parseConditionals: expected %d operators, got %d", tt.numOps, len(ops))
+			if len(andOr.Ops) != tt.numOps {
+				t.Errorf("Parse: expected %d operators, got %d", tt.numOps, len(andOr.Ops))
 			}
 
-			if tt.numOps > 0 && ops[0] != tt.firstOp {
-				t.Errorf("parseConditionals: expected first operator %q, got %q", tt.firstOp, ops[0])
+			if tt.numOps > 0 && andOr.Ops[0] != tt.firstOp {
+				t.Errorf("Parse: expected first operator %q, got %q", tt.firstOp, andOr.Ops[0])
 			}
 		})
 	}
@@ -178,7 +190,7 @@ func TestExternalCommand(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("echo test\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	result := output.String()
@@ -190,7 +202,7 @@ func TestExternalCommand(t *testing.T) {
 // TestGetPrompt тестирует формирование приглашения
 func TestGetPrompt(t *testing.T) {
 	t.Parallel()
-	shell := NewShell(nil, nil)
+	shell := NewShell(nil, nil, nil)
 	prompt := shell.getPrompt()
 
 	// Проверяем что в приглашении есть необходимые элементы
@@ -213,7 +225,7 @@ func TestExecuteBuiltin(t *testing.T) {
 		{
 			name:      "echo",
 			command:   []string{"echo", "test"},
-			isBuiltin: false, // echo теперь работает как внешняя команда (может быть в конвейере)
+			isBuiltin: true,
 		},
 		{
 			name:      "pwd",
@@ -233,7 +245,7 @@ func TestExecuteBuiltin(t *testing.T) {
 		{
 			name:      "ps",
 			command:   []string{"ps"},
-			isBuiltin: false, // ps теперь работает как внешняя команда (может быть в конвейере)
+			isBuiltin: true,
 		},
 		{
 			name:      "unknown",
@@ -246,9 +258,9 @@ func TestExecuteBuiltin(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 			output := &bytes.Buffer{}
-			shell := NewShell(nil, output)
+			shell := NewShell(nil, output, os.Stderr)
 
-			result := shell.executeBuiltin(tt.command)
+			_, result := shell.executeBuiltin(tt.command)
 			if result != tt.isBuiltin {
 				t.Errorf("executeBuiltin: expected %v, got %v", tt.isBuiltin, result)
 			}
@@ -271,7 +283,7 @@ func TestShellNewShell(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader(""))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 
 	if shell == nil {
 		t.Error("NewShell: expected non-nil shell")
@@ -317,7 +329,7 @@ func TestCdWithRelativePath(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("cd " + tmpDir + "\ncd subdir\npwd\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	result := output.String()
@@ -332,7 +344,7 @@ func TestEchoPipeline(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("echo hello world | cat\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	result := output.String()
@@ -353,7 +365,7 @@ func TestPsPipeline(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("ps | head -1\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	result := output.String()
@@ -369,7 +381,7 @@ func TestComplexPipeline(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("echo -e 'line1\\nline2\\nline3' | wc -l\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	result := output.String()
@@ -389,7 +401,7 @@ func TestEnvVarExpansion(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("echo $TEST_VAR\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	result := output.String()
@@ -407,7 +419,7 @@ func TestEnvVarExpansionBraces(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("echo ${TEST_BRACES}\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	result := output.String()
@@ -425,7 +437,7 @@ func TestEnvVarInPipeline(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("echo $PIPE_TEST | cat\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	result := output.String()
@@ -447,7 +459,7 @@ func TestEnvVarInCd(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("cd $TEST_DIR\npwd\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	result := output.String()
@@ -465,7 +477,7 @@ func TestOutputRedirect(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("echo hello world > " + outputFile + "\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	// Проверяем, что файл создан и содержит правильный текст
@@ -494,7 +506,7 @@ func TestInputRedirect(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("cat < " + inputFile + "\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	result := output.String()
@@ -519,7 +531,7 @@ func TestAppendRedirect(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("echo second line >> " + outputFile + "\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	// Проверяем содержимое файла
@@ -546,7 +558,7 @@ func TestPipeRedirect(t *testing.T) {
 	input := bufio.NewReader(strings.NewReader("echo line1 | cat > " + outputFile + "\nexit\n"))
 	output := &bytes.Buffer{}
 
-	shell := NewShell(input, output)
+	shell := NewShell(input, output, os.Stderr)
 	shell.Run()
 
 	content, err := os.ReadFile(outputFile)
@@ -559,59 +571,246 @@ func TestPipeRedirect(t *testing.T) {
 	}
 }
 
-// TestParseRedirects тестирует парсинг редиректов
+// TestParseRedirects тестирует разбор редиректов
 func TestParseRedirects(t *testing.T) {
 	tests := []struct {
-		name           string
-		input          string
-		expectedCmd    string
-		expectedInput  string
-		expectedOutput string
-		expectedAppend bool
+		name        string
+		input       string
+		expectedCmd string
+		expected    []Redirect
 	}{
 		{
-			name:           "output redirect",
-			input:          "echo hello > output.txt",
-			expectedCmd:    "echo hello",
-			expectedOutput: "output.txt",
+			name:        "output redirect",
+			input:       "echo hello > output.txt",
+			expectedCmd: "echo hello",
+			expected:    []Redirect{{Fd: 1, Op: ">", Target: "output.txt"}},
+		},
+		{
+			name:        "input redirect",
+			input:       "cat < input.txt",
+			expectedCmd: "cat",
+			expected:    []Redirect{{Fd: 0, Op: "<", Target: "input.txt"}},
+		},
+		{
+			name:        "append redirect",
+			input:       "echo line >> output.txt",
+			expectedCmd: "echo line",
+			expected:    []Redirect{{Fd: 1, Op: ">>", Target: "output.txt"}},
+		},
+		{
+			name:        "stderr redirect",
+			input:       "make 2>err.log 2>&1",
+			expectedCmd: "make",
+			expected:    []Redirect{{Fd: 2, Op: ">", Target: "err.log"}, {Fd: 2, Op: ">&", Target: "1"}},
 		},
 		{
-			name:          "input redirect",
-			input:         "cat < input.txt",
-			expectedCmd:   "cat",
-			expectedInput: "input.txt",
+			name:        "output and errors",
+			input:       "make &> all.log",
+			expectedCmd: "make",
+			expected:    []Redirect{{Fd: 1, Op: "&>", Target: "all.log"}},
 		},
 		{
-			name:           "append redirect",
-			input:          "echo line >> output.txt",
-			expectedCmd:    "echo line",
-			expectedOutput: "output.txt",
-			expectedAppend: true,
+			name:        "here-string",
+			input:       "cat <<< 'a b'",
+			expectedCmd: "cat",
+			expected:    []Redirect{{Fd: 0, Op: "<<", Data: "a b\n"}},
+		},
+		{
+			name:        "number is an argument",
+			input:       "echo 2 > out",
+			expectedCmd: "echo 2",
+			expected:    []Redirect{{Fd: 1, Op: ">", Target: "out"}},
 		},
 		{
 			name:        "no redirect",
 			input:       "ls -la",
 			expectedCmd: "ls -la",
+			expected:    []Redirect{},
+		},
+		{
+			name:        "redirect inside quotes",
+			input:       "echo \"x > y\"",
+			expectedCmd: "echo x > y",
+			expected:    []Redirect{},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			cmd, redirect := parseRedirects(tt.input)
-
-			if cmd != tt.expectedCmd {
-				t.Errorf("parseRedirects: expected cmd %q, got %q", tt.expectedCmd, cmd)
+			list, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse: unexpected error: %v", err)
 			}
-			if redirect.InputFile != tt.expectedInput {
-				t.Errorf("parseRedirects: expected input %q, got %q", tt.expectedInput, redirect.InputFile)
+			simple := list.Items[0].Pipelines[0].Commands[0].(*SimpleCommand)
+
+			shell := NewShell(nil, nil, nil)
+			cmd := strings.Join(shell.expandWords(simple.Args), " ")
+			redirects, err := shell.resolveRedirects(simple.Redirects)
+			if err != nil {
+				t.Fatalf("resolveRedirects: unexpected error: %v", err)
 			}
-			if redirect.OutputFile != tt.expectedOutput {
-				t.Errorf("parseRedirects: expected output %q, got %q", tt.expectedOutput, redirect.OutputFile)
+
+			if cmd != tt.expectedCmd {
+				t.Errorf("parse redirects: expected cmd %q, got %q", tt.expectedCmd, cmd)
 			}
-			if redirect.Append != tt.expectedAppend {
-				t.Errorf("parseRedirects: expected append %v, got %v", tt.expectedAppend, redirect.Append)
+			if !reflect.DeepEqual(redirects, tt.expected) {
+				t.Errorf("parse redirects: expected %+v, got %+v", tt.expected, redirects)
 			}
 		})
 	}
 }
+
+// TestExitStatusVar тестирует подстановку $? и код завершения shell'а
+func TestExitStatusVar(t *testing.T) {
+	t.Parallel()
+	input := bufio.NewReader(strings.NewReader("false\necho status=$?\nfalse || echo or=$?\nsh -c 'exit 7'\n"))
+	output := &bytes.Buffer{}
+
+	shell := NewShell(input, output, os.Stderr)
+	shell.Run()
+
+	result := output.String()
+	if !strings.Contains(result, "status=1") || !strings.Contains(result, "or=1") {
+		t.Errorf("$?: expected status=1 and or=1, got %q", result)
+	}
+	if shell.status != 7 {
+		t.Errorf("$?: expected shell status 7, got %d", shell.status)
+	}
+}
+
+// TestPositionalParams тестирует позиционные параметры скрипта
+func TestPositionalParams(t *testing.T) {
+	t.Parallel()
+	script := "#!/usr/bin/env myshell\necho \"$0 $# $1 $2 $3\"\nprintf '[%s]' \"$@\"\necho\nexit 5\necho not reached\n"
+	output := &bytes.Buffer{}
+
+	shell := NewShell(bufio.NewReader(strings.NewReader(script)), output, os.Stderr)
+	shell.name = "script.sh"
+	shell.params = []string{"a", "b c"}
+	shell.Run()
+
+	result := output.String()
+	if !strings.Contains(result, "script.sh 2 a b c \n") {
+		t.Errorf("positional params: expected 'script.sh 2 a b c ', got %q", result)
+	}
+	if !strings.Contains(result, "[a][b c]") {
+		t.Errorf("positional params: expected \"$@\" to keep arguments, got %q", result)
+	}
+	if strings.Contains(result, "not reached") {
+		t.Errorf("exit: expected script to stop, got %q", result)
+	}
+	if shell.status != 5 {
+		t.Errorf("exit: expected status 5, got %d", shell.status)
+	}
+}
+
+// TestScriptSyntaxError тестирует, что синтаксическая ошибка прерывает скрипт и сообщает номер строки
+func TestScriptSyntaxError(t *testing.T) {
+	t.Parallel()
+	script := "echo one\necho two | | wc\necho three\n"
+	output, errOutput := &bytes.Buffer{}, &bytes.Buffer{}
+
+	shell := NewShell(bufio.NewReader(strings.NewReader(script)), output, errOutput)
+	shell.name = "script.sh"
+	shell.Run()
+
+	result := output.String()
+	if !strings.Contains(errOutput.String(), "script.sh: синтаксическая ошибка (2:12)") {
+		t.Errorf("syntax error: expected position 2:12, got %q", errOutput.String())
+	}
+	if result != "one\n" {
+		t.Errorf("syntax error: expected script to stop, got %q", result)
+	}
+	if shell.status != 2 {
+		t.Errorf("syntax error: expected status 2, got %d", shell.status)
+	}
+}
+
+// TestStderrSeparation тестирует, что сообщения shell'а и stderr дочерних
+// процессов пишутся в errWriter, а в writer попадает только обычный вывод
+func TestStderrSeparation(t *testing.T) {
+	t.Parallel()
+	script := "echo out\nsh -c 'echo err >&2'\nnosuchcmd\necho $((1 / 0))\n{ echo group; echo hidden >&2; } 2>/dev/null\necho bad >&2\n"
+
+	result, errOut, _ := runScriptStderr(t, script)
+	if result != "out\ngroup\n" {
+		t.Errorf("stderr separation: unexpected output %q", result)
+	}
+	want := "err\nnosuchcmd: команда не найдена\nошибка: 1 / 0: деление на ноль\nbad\n"
+	if errOut != want {
+		t.Errorf("stderr separation: expected errors %q, got %q", want, errOut)
+	}
+}
+
+// TestRunFile тестирует выполнение rc-файла: определения остаются в shell'е,
+// синтаксическая ошибка прекращает чтение файла, но не завершает shell
+func TestRunFile(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	rc := filepath.Join(dir, ".myshellrc")
+	os.WriteFile(rc, []byte("alias hi='echo hello'\nPS1='rc> '\ngreet() { echo greet $1; }\necho | |\necho after\n"), 0644)
+
+	output, errOutput := &bytes.Buffer{}, &bytes.Buffer{}
+	shell := NewShell(bufio.NewReader(strings.NewReader("hi\ngreet x\necho $PS1\n")), output, errOutput)
+	shell.setVar("HOME", dir)
+	shell.loadStartupFiles()
+	if shell.exit {
+		t.Fatal("rc syntax error: shell should not exit")
+	}
+	shell.Run()
+
+	result := output.String()
+	if !strings.Contains(errOutput.String(), rc+": синтаксическая ошибка (4:") {
+		t.Errorf("rc syntax error: expected message with file name and line, got %q", errOutput.String())
+	}
+	if strings.Contains(result, "after") {
+		t.Errorf("rc syntax error: expected rest of file to be skipped, got %q", result)
+	}
+	if !strings.HasSuffix(result, "hello\ngreet x\nrc>\n") {
+		t.Errorf("rc definitions: unexpected output %q", result)
+	}
+
+	if err := shell.runFile(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("runFile: expected not-exist error, got %v", err)
+	}
+}
+
+// TestPipelineStartFailure тестирует, что при ошибке запуска команды конвейера
+// уже запущенные команды убиваются и дожидаются (не остаются работать или зомби)
+func TestPipelineStartFailure(t *testing.T) {
+	t.Parallel()
+	sleepPath, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not found")
+	}
+	// Ссылка с особым именем, чтобы найти процесс среди процессов других тестов
+	dir := t.TempDir()
+	sleeper := filepath.Join(dir, "startfail")
+	if err := os.Symlink(sleepPath, sleeper); err != nil {
+		t.Fatal(err)
+	}
+	// Исполняемый файл неизвестного формата: находится в $PATH, но не запускается
+	broken := filepath.Join(dir, "broken")
+	if err := os.WriteFile(broken, []byte{0, 1, 2, 3}, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	result, errOut, shell := runScriptStderr(t, sleeper+" 5 | "+broken+" | cat\necho after\n")
+	if !strings.Contains(errOut, "ошибка: ") || result != "after\n" {
+		t.Errorf("start failure: unexpected output %q, errors %q", result, errOut)
+	}
+	if len(shell.jobs) != 0 {
+		t.Errorf("start failure: expected no jobs left, got %d", len(shell.jobs))
+	}
+
+	procs, err := readProcs()
+	if err != nil {
+		t.Skip("/proc is not available")
+	}
+	for _, p := range procs {
+		if p.ppid == os.Getpid() && p.comm == "startfail" {
+			t.Errorf("start failure: started stage left behind (pid %d, state %s)", p.pid, p.state)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// subshell создает копию shell'а для подстановки команды: переменные, функции и параметры
+// копируются, поэтому изменения внутри подстановки не видны снаружи.
+// Управление заданиями в копии выключено
+func (s *Shell) subshell() *Shell {
+	vars := make(map[string]*variable, len(s.vars))
+	for name, v := range s.vars {
+		copied := *v
+		vars[name] = &copied
+	}
+
+	return &Shell{
+		writer:    s.writer,
+		stdin:     s.stdin,
+		errWriter: s.errWriter,
+		cwd:       s.cwd,
+		name:      s.name,
+		params:    slices.Clone(s.params),
+		status:    s.status,
+		vars:      vars,
+		lastBgPid: s.lastBgPid,
+		funcs:     maps.Clone(s.funcs),
+		aliases:   maps.Clone(s.aliases),
+		hash:      maps.Clone(s.hash),
+		dirStack:  slices.Clone(s.dirStack),
+		builtins:  s.cloneBuiltins(),
+		history:   s.history,
+		options:   maps.Clone(s.options),
+		noErrexit: s.noErrexit,
+		ctx:       s.ctx,
+		sigint:    s.sigint,
+	}
+}
+
+// commandSubst выполняет $(...) или `...` в копии shell'а и возвращает ее вывод
+// без завершающих переводов строки. Код завершения сохраняется для $? присваивания x=$(...)
+func (s *Shell) commandSubst(src string) string {
+	list, err := s.parse(src)
+	if err != nil {
+		fmt.Fprintln(s.errWriter, err)
+		s.substStatus = 2
+		return ""
+	}
+
+	var buf bytes.Buffer
+	sub := s.subshell()
+	sub.writer = &syncWriter{w: &buf}
+	sub.executeParsed(list, nil)
+
+	// Вывод фоновых заданий подстановки тоже попадает в результат
+	for _, job := range sub.jobs {
+		job.copiers.Wait()
+	}
+
+	s.substStatus = sub.status
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// expandFailed запоминает первую ошибку раскрытия слов (например, деление на ноль в $((...)));
+// команда с такой ошибкой не выполняется
+func (s *Shell) expandFailed(err error) {
+	if s.expandErr == nil {
+		s.expandErr = err
+	}
+}
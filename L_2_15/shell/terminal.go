@@ -0,0 +1,96 @@
+package shell
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// Операции rt_sigprocmask (в пакете syscall для Linux не объявлены)
+const (
+	sigBlock   = 0
+	sigSetmask = 2
+)
+
+// isTerminal сообщает, связан ли дескриптор с терминалом
+func isTerminal(fd int) bool {
+	_, err := tcgetpgrp(fd)
+	return err == nil
+}
+
+// tcgetpgrp возвращает группу процессов, которая владеет терминалом
+func tcgetpgrp(fd int) (int, error) {
+	var pgid int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgid)))
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(pgid), nil
+}
+
+// tcsetpgrp передает терминал группе процессов pgid.
+// Когда shell не владеет терминалом, ядро отвечает на эту операцию сигналом SIGTTOU.
+// signal.Ignore здесь не подходит: игнорирование наследуется дочерними процессами
+// через exec. Поэтому SIGTTOU блокируется только на время вызова и только в текущем потоке
+func tcsetpgrp(fd, pgid int) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	set := uint64(1) << (syscall.SIGTTOU - 1)
+	var old uint64
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_RT_SIGPROCMASK, sigBlock,
+		uintptr(unsafe.Pointer(&set)), uintptr(unsafe.Pointer(&old)), unsafe.Sizeof(set), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer syscall.RawSyscall6(syscall.SYS_RT_SIGPROCMASK, sigSetmask,
+		uintptr(unsafe.Pointer(&old)), 0, unsafe.Sizeof(old), 0, 0)
+
+	pgrp := int32(pgid)
+	_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TIOCSPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// getTermios возвращает текущие настройки терминала
+func getTermios(fd int) (*syscall.Termios, error) {
+	termios := &syscall.Termios{}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TCGETS), uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return nil, errno
+	}
+	return termios, nil
+}
+
+// setTermios применяет настройки терминала
+func setTermios(fd int, termios *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TCSETS), uintptr(unsafe.Pointer(termios)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// rawTermios возвращает копию настроек для посимвольного ввода без эха:
+// редактор строки сам выводит символы и обрабатывает Ctrl+C, Ctrl+Z и Enter.
+// Обработка вывода (OPOST) остается включенной, поэтому \n переводит строку как обычно
+func rawTermios(termios *syscall.Termios) *syscall.Termios {
+	raw := *termios
+	raw.Iflag &^= syscall.ICRNL | syscall.INLCR | syscall.IGNCR | syscall.IXON | syscall.ISTRIP
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	return &raw
+}
+
+// terminalWidth возвращает ширину терминала в символах (80, если ее не удалось узнать)
+func terminalWidth(fd int) int {
+	var ws struct{ row, col, xpixel, ypixel uint16 }
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 || ws.col == 0 {
+		return 80
+	}
+	return int(ws.col)
+}
@@ -0,0 +1,20 @@
+# Кавычки, переменные и параметры
+echo plain   words   here
+echo "double  quoted" 'single  $quoted' mixed"$HOME_UNSET"end
+x=value
+echo $x "${x}s" '$x' "\$x"
+empty=
+echo [${empty:-default}] [${empty-unset}] [${unset_var-unset}] [${x:+alt}]
+echo ${#x}
+: ${assigned:=new}
+echo $assigned
+set -- one "two words" three
+echo $# $1 $3
+for arg in "$@"; do echo "<$arg>"; done
+for arg in $*; do echo "[$arg]"; done
+true; echo $?
+false; echo $?
+echo $(echo nested $(echo deeper))
+echo `echo backquoted`
+echo $((2 + 3 * 4)) $(( (1 + 2) * 3 )) $((7 / 2)) $((7 % 3))
+i=1; i=$((i + 1)); echo $i
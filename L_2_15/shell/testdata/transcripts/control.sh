@@ -0,0 +1,53 @@
+# Условия, циклы, case и функции
+n=3
+if [ $n -gt 5 ]; then
+	echo big
+elif [ $n -gt 2 ]; then
+	echo medium
+else
+	echo small
+fi
+
+i=0
+while [ $i -lt 3 ]; do
+	echo while $i
+	i=$((i + 1))
+done
+
+until [ $i -eq 0 ]; do
+	i=$((i - 1))
+	if [ $i -eq 1 ]; then continue; fi
+	echo until $i
+done
+
+for word in alpha beta gamma; do
+	case $word in
+	a*) echo "$word starts with a" ;;
+	b?ta | zeta) echo "$word matches" ;;
+	*) echo "$word is other" ;;
+	esac
+done
+
+for outer in 1 2 3; do
+	for inner in 1 2 3; do
+		[ $inner -eq 2 ] && continue
+		[ $outer -eq 3 ] && break 2
+		echo $outer$inner
+	done
+done
+
+greet() {
+	echo "hello $1 ($# args)"
+	return 4
+}
+greet world extra
+echo status $?
+
+count() {
+	if [ $1 -le 0 ]; then
+		return 0
+	fi
+	echo $1
+	count $(($1 - 1))
+}
+count 3
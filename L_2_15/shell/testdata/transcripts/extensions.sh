@@ -0,0 +1,17 @@
+# posix: no
+# Возможности, которых нет в POSIX sh
+echo -e 'tab\there'
+echo a{b,c}d
+alias say='echo said'
+say hello
+type say cd
+false | true; echo ${PIPESTATUS[@]}
+set -o pipefail
+false | true; echo pipefail $?
+set +o pipefail
+cd /
+pushd /tmp > /dev/null; dirs
+popd > /dev/null; pwd
+cat <<< "here string"
+nosuch-command-xyz
+echo status $?
@@ -0,0 +1,15 @@
+# Группы и подоболочки
+x=1
+(x=2; echo inner $x)
+echo outer $x
+{ x=3; echo group $x; }
+echo after $x
+(exit 7); echo subshell status $?
+{ echo a; echo b; } | wc -l
+{ echo first; echo second; } > group.txt
+cat group.txt
+(cd /; pwd)
+for i in 1 2; do echo line $i; done | tail -1
+if true; then echo x; echo y; fi > if.txt
+wc -l < if.txt
+echo $(x=9; echo $x) $x
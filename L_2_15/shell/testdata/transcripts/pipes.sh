@@ -0,0 +1,22 @@
+# Конвейеры, редиректы и условное выполнение
+printf 'b\na\nc\n' | sort | head -2
+echo one > out.txt
+echo two >> out.txt
+cat < out.txt
+wc -l < out.txt
+ls nonexistent-file 2> err.txt; echo ls status $?
+[ -s err.txt ] && echo stderr captured
+cat <<EOF2
+heredoc $HOME_UNSET line
+	tab kept
+EOF2
+cat <<-'EOF2'
+	literal $x
+	EOF2
+true && echo and-true
+false && echo and-false
+false || echo or-false
+true || echo or-true
+false || false && echo never || echo chain
+echo err 1>&2 2>/dev/null
+{ echo to-stderr 1>&2; } 2>&1 | tr a-z A-Z
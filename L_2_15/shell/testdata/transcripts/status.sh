@@ -0,0 +1,11 @@
+# Коды завершения и set -e
+nosuch-command-xyz 2>/dev/null
+echo not found $?
+sh -c 'exit 5'
+echo exit status $?
+set -e
+false || echo handled
+if false; then :; fi
+echo still running
+sh -c 'exit 3'
+echo not reached
@@ -0,0 +1,204 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Транскрипты - скрипты testdata/transcripts/NAME.sh с ожидаемым результатом:
+//
+//	NAME.stdout  вывод (обязателен)
+//	NAME.stderr  поток ошибок (нет файла - поток ошибок пуст)
+//	NAME.status  код завершения (нет файла - 0)
+//
+// Каждый скрипт выполняется в своей временной директории. Скрипты без строки
+// "# posix: no" выполняются еще и в /bin/sh: его вывод и код завершения должны совпасть
+// с нашими. go test -run TestTranscripts -update перезаписывает ожидаемые файлы
+var updateTranscripts = flag.Bool("update", false, "перезаписать ожидаемый вывод транскриптов в testdata/transcripts")
+
+// transcriptDir - директория со скриптами транскриптов
+const transcriptDir = "testdata/transcripts"
+
+// transcript - результат выполнения скрипта
+type transcript struct {
+	stdout string
+	stderr string
+	status int
+}
+
+// TestTranscripts выполняет скрипты транскриптов и сравнивает результат с ожидаемым и с /bin/sh
+func TestTranscripts(t *testing.T) {
+	scripts, err := filepath.Glob(filepath.Join(transcriptDir, "*.sh"))
+	if err != nil || len(scripts) == 0 {
+		t.Fatalf("no transcripts in %s: %v", transcriptDir, err)
+	}
+
+	for _, script := range scripts {
+		name := strings.TrimSuffix(filepath.Base(script), ".sh")
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			src, err := os.ReadFile(script)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := runShellTranscript(script, src, t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			base := strings.TrimSuffix(script, ".sh")
+
+			if *updateTranscripts {
+				writeTranscript(t, base, got)
+			} else {
+				want, err := readTranscript(base)
+				if err != nil {
+					t.Fatalf("%v (запустите go test -run TestTranscripts -update)", err)
+				}
+				compareTranscripts(t, "expected", want, got, true)
+			}
+
+			if bytes.Contains(src, []byte("# posix: no")) {
+				return
+			}
+			if _, err := os.Stat("/bin/sh"); err != nil {
+				t.Skip("/bin/sh not found")
+			}
+			sh, err := runShTranscript(script, t.TempDir())
+			if err != nil {
+				t.Fatalf("/bin/sh: %v", err)
+			}
+			compareTranscripts(t, "/bin/sh", sh, got, false)
+		})
+	}
+}
+
+// runShellTranscript выполняет скрипт в нашем shell'е в директории dir.
+// Ввод команд - /dev/null, как у /bin/sh в runShTranscript
+func runShellTranscript(script string, src []byte, dir string) (transcript, error) {
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		return transcript{}, err
+	}
+	defer devNull.Close()
+
+	var stdout, stderr bytes.Buffer
+	shell := NewShell(bufio.NewReader(bytes.NewReader(src)), &stdout, &stderr)
+	shell.stdin = devNull
+	shell.name = script
+	shell.cwd = dir
+	shell.setVar("PWD", dir)
+	shell.Run()
+	return transcript{stdout: stdout.String(), stderr: stderr.String(), status: shell.status}, nil
+}
+
+// runShTranscript выполняет скрипт в /bin/sh в директории dir
+func runShTranscript(script, dir string) (transcript, error) {
+	path, err := filepath.Abs(script)
+	if err != nil {
+		return transcript{}, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("/bin/sh", path)
+	cmd.Dir, cmd.Stdout, cmd.Stderr = dir, &stdout, &stderr
+
+	status := 0
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return transcript{}, err
+		}
+		status = exitErr.ExitCode()
+	}
+	return transcript{stdout: stdout.String(), stderr: stderr.String(), status: status}, nil
+}
+
+// readTranscript читает ожидаемый результат скрипта base.sh
+func readTranscript(base string) (transcript, error) {
+	var want transcript
+	stdout, err := os.ReadFile(base + ".stdout")
+	if err != nil {
+		return want, err
+	}
+	want.stdout = string(stdout)
+
+	if stderr, err := os.ReadFile(base + ".stderr"); err == nil {
+		want.stderr = string(stderr)
+	} else if !os.IsNotExist(err) {
+		return want, err
+	}
+
+	if status, err := os.ReadFile(base + ".status"); err == nil {
+		if want.status, err = strconv.Atoi(strings.TrimSpace(string(status))); err != nil {
+			return want, fmt.Errorf("%s.status: %v", base, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return want, err
+	}
+	return want, nil
+}
+
+// writeTranscript записывает результат скрипта base.sh как ожидаемый.
+// Пустой поток ошибок и нулевой код завершения не записываются
+func writeTranscript(t *testing.T, base string, got transcript) {
+	t.Helper()
+	write := func(path, content string, keep bool) {
+		if !keep {
+			os.Remove(path)
+			return
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(base+".stdout", got.stdout, true)
+	write(base+".stderr", got.stderr, got.stderr != "")
+	write(base+".status", fmt.Sprintln(got.status), got.status != 0)
+}
+
+// compareTranscripts сообщает о расхождениях результата got с want.
+// Поток ошибок сравнивается только с ожидаемыми файлами: сообщения /bin/sh отличаются от наших
+func compareTranscripts(t *testing.T, source string, want, got transcript, withStderr bool) {
+	t.Helper()
+	if diff := diffLines(want.stdout, got.stdout); diff != "" {
+		t.Errorf("stdout differs from %s:\n%s", source, diff)
+	}
+	if withStderr {
+		if diff := diffLines(want.stderr, got.stderr); diff != "" {
+			t.Errorf("stderr differs from %s:\n%s", source, diff)
+		}
+	}
+	if want.status != got.status {
+		t.Errorf("exit status differs from %s: want %d, got %d", source, want.status, got.status)
+	}
+}
+
+// diffLines возвращает построчное описание первого расхождения текстов ("" - тексты совпадают)
+func diffLines(want, got string) string {
+	if want == got {
+		return ""
+	}
+	wantLines := strings.SplitAfter(want, "\n")
+	gotLines := strings.SplitAfter(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("  line %d:\n    want %q\n    got  %q", i+1, w, g)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,421 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// variable - переменная shell'а. Экспортированные переменные
+// передаются дочерним процессам в окружении, локальные - нет
+type variable struct {
+	value    string
+	exported bool
+	noValue  bool // export NAME без значения: переменная экспортирована, но не задана
+}
+
+// importEnviron создает таблицу переменных из окружения env ("NAME=value"); все они экспортированы
+func importEnviron(env []string) map[string]*variable {
+	vars := make(map[string]*variable)
+	for _, kv := range env {
+		name, value, ok := strings.Cut(kv, "=")
+		if ok && isName(name) {
+			vars[name] = &variable{value: value, exported: true}
+		}
+	}
+	return vars
+}
+
+// isName сообщает, является ли строка допустимым именем переменной
+func isName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isNameChar(name[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// isAssignment сообщает, является ли слово присваиванием NAME=value
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	return ok && isName(name)
+}
+
+// lookupVar возвращает значение переменной и признак того, что она задана
+func (s *Shell) lookupVar(name string) (string, bool) {
+	if v, ok := s.vars[name]; ok && !v.noValue {
+		return v.value, true
+	}
+	return "", false
+}
+
+// setVar присваивает значение переменной, сохраняя признак экспорта
+func (s *Shell) setVar(name, value string) {
+	if name == "PATH" {
+		clear(s.hash) // Команды будут найдены заново в новых каталогах
+	}
+	if v, ok := s.vars[name]; ok {
+		v.value, v.noValue = value, false
+		return
+	}
+	s.vars[name] = &variable{value: value}
+}
+
+// environ возвращает окружение для дочернего процесса: экспортированные переменные
+// и присваивания вида NAME=value, заданные перед командой (они переопределяют переменные shell'а)
+func (s *Shell) environ(assigns []string) []string {
+	env := make([]string, 0, len(s.vars)+len(assigns))
+	for _, name := range s.sortedVarNames() {
+		if v := s.vars[name]; v.exported && !v.noValue {
+			env = append(env, name+"="+v.value)
+		}
+	}
+	return append(env, assigns...)
+}
+
+// sortedVarNames возвращает имена переменных в алфавитном порядке
+func (s *Shell) sortedVarNames() []string {
+	names := make([]string, 0, len(s.vars))
+	for name := range s.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withAssigns выполняет fn с временно установленными переменными (FOO=1 builtin)
+func (s *Shell) withAssigns(assigns []string, fn func() int) int {
+	saved := make(map[string]*variable, len(assigns))
+	for _, assign := range assigns {
+		name, value, _ := strings.Cut(assign, "=")
+		if _, done := saved[name]; !done {
+			saved[name] = s.vars[name]
+		}
+		s.vars[name] = &variable{value: value, exported: true}
+	}
+
+	defer func() {
+		for name, v := range saved {
+			if v == nil {
+				delete(s.vars, name)
+			} else {
+				s.vars[name] = v
+			}
+		}
+	}()
+
+	return fn()
+}
+
+// shellQuote заключает значение в кавычки, если без них shell разобрал бы его иначе
+func shellQuote(value string) string {
+	if value == "" {
+		return "''"
+	}
+	for _, c := range value {
+		if !(c == '_' || c == '-' || c == '.' || c == '/' || c == ':' || c == ',' || c == '+' || c == '@' || c == '%' || c == '=' ||
+			c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return singleQuote(value)
+		}
+	}
+	return value
+}
+
+// builtinExport помечает переменные как экспортируемые (и при необходимости присваивает значение).
+// Без аргументов выводит список экспортированных переменных
+func (s *Shell) builtinExport(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	args = args[1:]
+	if len(args) > 0 && args[0] == "-p" {
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		for _, name := range s.sortedVarNames() {
+			switch v := s.vars[name]; {
+			case v.exported && v.noValue:
+				fmt.Fprintf(stdout, "export %s\n", name)
+			case v.exported:
+				fmt.Fprintf(stdout, "export %s=%s\n", name, shellQuote(v.value))
+			}
+		}
+		return 0
+	}
+
+	exitCode := 0
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !isName(name) {
+			fmt.Fprintf(stderr, "export: '%s': неверный идентификатор\n", arg)
+			exitCode = 1
+			continue
+		}
+
+		v, ok := s.vars[name]
+		if !ok {
+			// export NAME без значения не задает переменную, а только помечает ее
+			v = &variable{noValue: true}
+			s.vars[name] = v
+		}
+		if hasValue {
+			v.value, v.noValue = value, false
+		}
+		v.exported = true
+	}
+	return exitCode
+}
+
+// builtinUnset удаляет переменные, а с -f - функции
+func (s *Shell) builtinUnset(args []string, _ io.Reader, _, stderr io.Writer) int {
+	args = args[1:]
+	funcs := false
+	if len(args) > 0 && (args[0] == "-v" || args[0] == "-f") {
+		funcs = args[0] == "-f"
+		args = args[1:]
+	}
+
+	exitCode := 0
+	for _, name := range args {
+		if !isName(name) {
+			fmt.Fprintf(stderr, "unset: '%s': неверный идентификатор\n", name)
+			exitCode = 1
+			continue
+		}
+		if funcs {
+			delete(s.funcs, name)
+			continue
+		}
+		delete(s.vars, name)
+		if name == "PATH" {
+			clear(s.hash)
+		}
+	}
+	return exitCode
+}
+
+// setOption - параметр shell'а для set -o: имя и однобуквенный флаг (0 - только длинное имя)
+type setOption struct {
+	name string
+	flag byte
+}
+
+// setOptions - параметры, которые можно включить через set
+var setOptions = []setOption{
+	{"errexit", 'e'}, // Завершать shell при неуспешной команде
+	{"nounset", 'u'}, // Считать ошибкой подстановку незаданной переменной
+	{"pipefail", 0},  // Код конвейера - код последней неуспешной команды
+	{"xtrace", 'x'},  // Выводить выполняемые команды в поток ошибок
+}
+
+// builtinSet без аргументов выводит все переменные shell'а, а set -- args заменяет позиционные параметры.
+// Флаги -e/+e, -x/+x и -o имя/+o имя включают и выключают параметры shell'а;
+// set -o выводит их состояние, set +o - в виде команд set
+func (s *Shell) builtinSet(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	args = args[1:]
+	if len(args) == 0 {
+		for _, name := range s.sortedVarNames() {
+			if v := s.vars[name]; !v.noValue {
+				fmt.Fprintf(stdout, "%s=%s\n", name, shellQuote(v.value))
+			}
+		}
+		return 0
+	}
+
+	for len(args) > 0 {
+		arg := args[0]
+		if arg == "--" {
+			s.params = append([]string(nil), args[1:]...)
+			return 0
+		}
+		if len(arg) < 2 || arg[0] != '-' && arg[0] != '+' {
+			break
+		}
+		enable := arg[0] == '-'
+		args = args[1:]
+
+		for _, flag := range []byte(arg[1:]) {
+			if flag != 'o' {
+				name := ""
+				for _, opt := range setOptions {
+					if opt.flag == flag {
+						name = opt.name
+					}
+				}
+				if name == "" {
+					fmt.Fprintf(stderr, "set: %c%c: неизвестный параметр\n", arg[0], flag)
+					return 2
+				}
+				s.options[name] = enable
+				continue
+			}
+
+			// -o без имени выводит состояние параметров
+			if len(args) == 0 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[0], "+") {
+				s.printOptions(stdout, enable)
+				continue
+			}
+			name := args[0]
+			args = args[1:]
+			if !slices.ContainsFunc(setOptions, func(opt setOption) bool { return opt.name == name }) {
+				fmt.Fprintf(stderr, "set: %s: неверное название параметра\n", name)
+				return 2
+			}
+			s.options[name] = enable
+		}
+	}
+
+	if len(args) > 0 {
+		s.params = append([]string(nil), args...)
+	}
+	return 0
+}
+
+// printOptions выводит состояние параметров shell'а: для set -o - таблицей,
+// для set +o - командами, которые восстанавливают текущие значения
+func (s *Shell) printOptions(w io.Writer, table bool) {
+	for _, opt := range setOptions {
+		on := s.options[opt.name]
+		switch {
+		case table && on:
+			fmt.Fprintf(w, "%-15s\ton\n", opt.name)
+		case table:
+			fmt.Fprintf(w, "%-15s\toff\n", opt.name)
+		case on:
+			fmt.Fprintf(w, "set -o %s\n", opt.name)
+		default:
+			fmt.Fprintf(w, "set +o %s\n", opt.name)
+		}
+	}
+}
+
+// builtinShift сдвигает позиционные параметры влево на N (по умолчанию на один).
+// Если параметров меньше N, они не меняются, а код завершения - 1
+func (s *Shell) builtinShift(args []string, _ io.Reader, _, stderr io.Writer) int {
+	args = args[1:]
+	n := 1
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n < 0 {
+			fmt.Fprintf(stderr, "shift: %s: требуется неотрицательное число\n", args[0])
+			return 1
+		}
+	}
+	if n > len(s.params) {
+		return 1
+	}
+	s.params = s.params[n:]
+	return 0
+}
+
+// builtinRead читает строку ввода и присваивает ее поля переменным по порядку: последней
+// переменной достается остаток строки, без переменных строка целиком попадает в REPLY.
+// Без -r обратная косая черта экранирует следующий символ, а перед переводом строки
+// продолжает ввод на следующей строке. Код завершения - 1, если ввод кончился до конца строки
+func (s *Shell) builtinRead(args []string, stdin io.Reader, _, stderr io.Writer) int {
+	args = args[1:]
+	raw := false
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") && args[0] != "-" {
+		arg := args[0]
+		args = args[1:]
+		if arg == "--" {
+			break
+		}
+		if arg != "-r" {
+			fmt.Fprintf(stderr, "read: %s: неизвестный параметр\n", arg)
+			return 2
+		}
+		raw = true
+	}
+	for _, name := range args {
+		if !isName(name) {
+			fmt.Fprintf(stderr, "read: '%s': неверный идентификатор\n", name)
+			return 1
+		}
+	}
+
+	line, complete := readLine(stdin, raw)
+	if len(args) == 0 {
+		s.setVar("REPLY", splitReadFields(line, 0, raw)[0])
+	} else {
+		for i, value := range splitReadFields(line, len(args), raw) {
+			s.setVar(args[i], value)
+		}
+	}
+
+	if !complete {
+		return 1
+	}
+	return 0
+}
+
+// readLine читает из r строку без перевода строки; без raw экранированный перевод строки
+// продолжает строку, остальные обратные косые черты остаются для splitReadFields.
+// Ввод читается по байту, чтобы не забрать у следующих команд то, что идет после строки.
+// Возвращает false, если ввод кончился раньше перевода строки
+func readLine(r io.Reader, raw bool) (string, bool) {
+	var line []byte
+	buf := make([]byte, 1)
+	escaped := false
+	for {
+		if n, err := r.Read(buf); n == 0 {
+			if err != nil {
+				return string(line), false
+			}
+			continue
+		}
+
+		c := buf[0]
+		switch {
+		case escaped:
+			escaped = false
+			if c != '\n' {
+				line = append(line, '\\', c)
+			}
+		case c == '\\' && !raw:
+			escaped = true
+		case c == '\n':
+			return string(line), true
+		default:
+			line = append(line, c)
+		}
+	}
+}
+
+// splitReadFields делит строку для read на n полей по пробелам и табуляциям: последнее поле -
+// остаток строки без пробелов по краям. При n = 0 строка не делится и не обрезается.
+// Без raw обратная косая черта убирается, а экранированный ею пробел не разделяет поля
+func splitReadFields(line string, n int, raw bool) []string {
+	fields := make([]string, max(n, 1))
+	var field []byte
+	i, keep := 0, 0 // keep - длина поля без пробелов в конце
+	for pos := 0; pos < len(line); pos++ {
+		c := line[pos]
+		switch {
+		case c == '\\' && !raw && pos+1 < len(line):
+			pos++
+			field = append(field, line[pos])
+			keep = len(field)
+		case (c == ' ' || c == '\t') && n > 0:
+			if len(field) > 0 && i < n-1 {
+				fields[i] = string(field)
+				i, field, keep = i+1, field[:0], 0
+			} else if len(field) > 0 {
+				field = append(field, c)
+			}
+		default:
+			field = append(field, c)
+			keep = len(field)
+		}
+	}
+	if n == 0 {
+		keep = len(field)
+	}
+	fields[i] = string(field[:keep])
+	return fields
+}
@@ -0,0 +1,230 @@
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// runScript выполняет строки скрипта в новом shell'е и возвращает вывод
+func runScript(t *testing.T, script string) (string, *Shell) {
+	t.Helper()
+	output := &bytes.Buffer{}
+	shell := NewShell(bufio.NewReader(strings.NewReader(script)), output, os.Stderr)
+	shell.Run()
+	return output.String(), shell
+}
+
+// runScriptStderr выполняет скрипт и возвращает вывод, поток ошибок и shell
+func runScriptStderr(t *testing.T, script string) (string, string, *Shell) {
+	t.Helper()
+	output, errOutput := &bytes.Buffer{}, &bytes.Buffer{}
+	shell := NewShell(bufio.NewReader(strings.NewReader(script)), output, errOutput)
+	shell.Run()
+	return output.String(), errOutput.String(), shell
+}
+
+// TestLocalAndExportedVars тестирует, что дочерним процессам передаются только экспортированные переменные
+func TestLocalAndExportedVars(t *testing.T) {
+	t.Parallel()
+	result, _ := runScript(t, "X=local\necho shell:$X\nsh -c 'echo child:$X'\nexport X\nsh -c 'echo exported:$X'\nunset X\necho unset:[$X]\n")
+
+	for _, want := range []string{"shell:local\n", "child:\n", "exported:local\n", "unset:[]\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("vars: expected %q in output, got %q", want, result)
+		}
+	}
+}
+
+// TestAssignmentPrefix тестирует, что FOO=1 cmd меняет только окружение дочернего процесса
+func TestAssignmentPrefix(t *testing.T) {
+	t.Parallel()
+	result, shell := runScript(t, "FOO=1 BAR='a b' sh -c 'echo child:$FOO:$BAR'\necho shell:[$FOO]\n")
+
+	if !strings.Contains(result, "child:1:a b\n") {
+		t.Errorf("assignment prefix: expected child to see FOO and BAR, got %q", result)
+	}
+	if !strings.Contains(result, "shell:[]\n") {
+		t.Errorf("assignment prefix: expected FOO to stay unset in shell, got %q", result)
+	}
+	if _, ok := shell.lookupVar("FOO"); ok {
+		t.Error("assignment prefix: expected FOO not to be a shell variable")
+	}
+}
+
+// TestExportBuiltin тестирует вывод export и set
+func TestExportBuiltin(t *testing.T) {
+	t.Parallel()
+	result, shell := runScript(t, "export EXPORT_TEST='a b' NOVALUE_TEST\nexport\nLOCAL_TEST=x\nset\necho [${NOVALUE_TEST-unset}]\n")
+
+	if !strings.Contains(result, "export EXPORT_TEST='a b'\n") {
+		t.Errorf("export: expected quoted variable in export list, got %q", result)
+	}
+	if strings.Contains(result, "export LOCAL_TEST") {
+		t.Errorf("export: local variable must not be exported, got %q", result)
+	}
+	if !strings.Contains(result, "LOCAL_TEST=x\n") {
+		t.Errorf("set: expected local variable in set output, got %q", result)
+	}
+	if env := strings.Join(shell.environ(nil), "\n"); !strings.Contains(env, "EXPORT_TEST=a b") {
+		t.Errorf("environ: expected EXPORT_TEST in child environment")
+	}
+
+	// export без значения помечает переменную, но не задает ее
+	if !strings.Contains(result, "export NOVALUE_TEST\n") || !strings.Contains(result, "[unset]\n") {
+		t.Errorf("export: expected NOVALUE_TEST to be exported without value, got %q", result)
+	}
+	if strings.Contains(result, "NOVALUE_TEST=") || strings.Contains(strings.Join(shell.environ(nil), "\n"), "NOVALUE_TEST") {
+		t.Errorf("export: variable without value must not be set, got %q", result)
+	}
+}
+
+// TestUnset тестирует удаление переменных и, с -f, функций
+func TestUnset(t *testing.T) {
+	t.Parallel()
+	result, errOutput, _ := runScriptStderr(t, "X=1; f() { echo f; }\nunset X\nunset -f f\necho [$X]\nf\n")
+	if result != "[]\n" || !strings.Contains(errOutput, "f: команда не найдена") {
+		t.Errorf("unset: unexpected output %q, errors %q", result, errOutput)
+	}
+}
+
+// TestReadBuiltin тестирует чтение строк командой read: деление на поля, -r и REPLY
+func TestReadBuiltin(t *testing.T) {
+	t.Parallel()
+	script := "while read a b; do echo \"[$a|$b]\"; done <<'EOF'\n  x  y z \nfoo\\ bar baz\nlast\nEOF\n" +
+		"read -r v <<< 'p\\q'; echo $v\n" +
+		"read <<< '  all  '; echo \"[$REPLY]\"\n" +
+		"printf 'tail' | { read t; echo $? $t; }\n"
+	result, _ := runScript(t, script)
+	want := "[x|y z]\n[foo bar|baz]\n[last|]\np\\q\n[  all  ]\n1 tail\n"
+	if result != want {
+		t.Errorf("read: expected %q, got %q", want, result)
+	}
+}
+
+// TestShift тестирует сдвиг позиционных параметров скрипта и функции
+func TestShift(t *testing.T) {
+	t.Parallel()
+	script := "f() { shift; echo $# $1; shift 5; echo $? $1; }\nf 1 2 3\n" +
+		"set -- a b c; shift 2; echo $@\nshift x; echo $?\n"
+	result, errOutput, _ := runScriptStderr(t, script)
+	if result != "2 2\n1 2\nc\n1\n" || !strings.Contains(errOutput, "shift: x: требуется неотрицательное число") {
+		t.Errorf("shift: unexpected output %q, errors %q", result, errOutput)
+	}
+}
+
+// TestParamExpansion тестирует формы подстановки ${...}
+func TestParamExpansion(t *testing.T) {
+	t.Parallel()
+	shell := NewShell(nil, nil, nil)
+	shell.setVar("SET", "value")
+	shell.setVar("EMPTY", "")
+	shell.setVar("RU", "привет")
+
+	tests := []struct {
+		raw  string
+		want []string
+	}{
+		{raw: "${UNSET:-default}", want: []string{"default"}},
+		{raw: "${EMPTY:-default}", want: []string{"default"}},
+		{raw: "${EMPTY-default}", want: nil},
+		{raw: "${SET:-default}", want: []string{"value"}},
+		{raw: "${UNSET:-$SET}", want: []string{"value"}},
+		{raw: `"${UNSET:-a b}"`, want: []string{"a b"}},
+		{raw: "${SET:+alt}", want: []string{"alt"}},
+		{raw: "${UNSET:+alt}", want: nil},
+		{raw: "${#SET}", want: []string{"5"}},
+		{raw: "${#RU}", want: []string{"6"}},
+		{raw: "${ASSIGNED:=new}", want: []string{"new"}},
+		{raw: "$$", want: []string{strconv.Itoa(os.Getpid())}},
+	}
+
+	for _, tt := range tests {
+		got := shell.expandWord(Word{Raw: tt.raw})
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandWord(%s): expected %q, got %q", tt.raw, tt.want, got)
+		}
+	}
+
+	if value, _ := shell.lookupVar("ASSIGNED"); value != "new" {
+		t.Errorf("${ASSIGNED:=new}: expected variable to be assigned, got %q", value)
+	}
+
+	// Неподдерживаемые формы - ошибка раскрытия, а не значение без изменений
+	for _, raw := range []string{"${SET#v}", "${SET%e}", "${SET/a/b}", "${SET:?msg}", "${SET:}"} {
+		shell.expandErr = nil
+		if got := shell.expandWord(Word{Raw: raw}); shell.expandErr == nil {
+			t.Errorf("expandWord(%s): expected bad substitution, got %q", raw, got)
+		}
+	}
+	result, errOutput, _ := runScriptStderr(t, "v=abc\necho ${v#a}\necho next\n")
+	if result != "next\n" || !strings.Contains(errOutput, "${v#a}: неверная подстановка") {
+		t.Errorf("bad substitution: unexpected output %q, errors %q", result, errOutput)
+	}
+}
+
+// TestPipeStatus тестирует код завершения конвейера, $PIPESTATUS и set -o pipefail
+func TestPipeStatus(t *testing.T) {
+	t.Parallel()
+	script := "false | true; echo $?\n" +
+		"true | false; echo $?\n" +
+		"sh -c 'exit 3' | sh -c 'exit 5' | true; echo ${PIPESTATUS[@]} ${#PIPESTATUS[@]} ${PIPESTATUS[1]} ${PIPESTATUS[-1]} $PIPESTATUS\n" +
+		"f() { false | true; }; f; echo ${PIPESTATUS[@]}\n" +
+		"if true; then false | true; fi; echo ${PIPESTATUS[@]}\n" +
+		"set -o pipefail\n" +
+		"sh -c 'exit 3' | sh -c 'exit 5' | true; echo $?\n" +
+		"true | true; echo $?\n" +
+		"set +o pipefail\n" +
+		"false | true; echo $?\n"
+	result, _ := runScript(t, script)
+
+	want := "0\n1\n3 5 0 3 5 0 3\n0\n1 0\n5\n0\n0\n"
+	if result != want {
+		t.Errorf("pipe status: expected %q, got %q", want, result)
+	}
+}
+
+// TestSetOptions тестирует set -e, set -x и вывод set -o
+func TestSetOptions(t *testing.T) {
+	t.Parallel()
+	script := "set -e\n" +
+		"false || echo or\n" +
+		"false && echo no\n" +
+		"if false; then :; fi\n" +
+		"while false; do :; done\n" +
+		"g() { false; echo in-g; }\n" +
+		"g || echo g-failed\n" +
+		"sh -c 'exit 4'\n" +
+		"echo not-reached\n"
+	result, shell := runScript(t, script)
+	if result != "or\nin-g\n" || shell.status != 4 {
+		t.Errorf("set -e: unexpected output %q, status %d", result, shell.status)
+	}
+
+	output := &bytes.Buffer{}
+	shell = NewShell(bufio.NewReader(strings.NewReader("set -x\nx= y='a b' echo \"1 2\" z=3 | cat\nset +x\necho quiet\nset -o pipefail\nset -o\nset +o\n")), output, output)
+	shell.Run()
+	for _, want := range []string{
+		"+ x=\n+ y='a b'\n+ echo '1 2' z=3\n+ cat\n",
+		"+ set +x\nquiet\n",
+		"errexit        \toff\nnounset        \toff\npipefail       \ton\nxtrace         \toff\n",
+		"set +o errexit\nset +o nounset\nset -o pipefail\nset +o xtrace\n",
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("set: expected %q in output, got %q", want, output.String())
+		}
+	}
+	if strings.Contains(output.String(), "+ echo quiet") {
+		t.Errorf("set +x: command traced after tracing was disabled")
+	}
+
+	// set -u: незаданная переменная - ошибка, которая завершает скрипт
+	result, errOutput, shell := runScriptStderr(t, "set -euo pipefail\necho ${X:-default} $# [$@]\necho $NOPE\necho not-reached\n")
+	if result != "default 0 []\n" || !strings.Contains(errOutput, "NOPE: переменная не задана") || !shell.exit || shell.status != 1 {
+		t.Errorf("set -u: unexpected output %q, errors %q, exit %v, status %d", result, errOutput, shell.exit, shell.status)
+	}
+}